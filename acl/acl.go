@@ -0,0 +1,161 @@
+package acl
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/ratelimit"
+	"strings"
+)
+
+// Access control for services that expose RCON to other users: API tokens
+// map to roles, and a role limits which servers and commands may be used and
+// how often.
+//
+// Example config:
+//
+//	roles:
+//	  moderator:
+//	    servers: [lobby, survival]
+//	    commands: [list, kick, "ban *", "say *"]
+//	    rate: 1
+//	    burst: 5
+//	tokens:
+//	  4f2c...: moderator
+
+var (
+	ErrorUnknownToken  = errors.New("acl: unknown token")
+	ErrorServerDenied  = errors.New("acl: server not allowed")
+	ErrorCommandDenied = errors.New("acl: command not allowed")
+	ErrorRateLimited   = errors.New("acl: rate limit exceeded")
+)
+
+type Role struct {
+	// Server names the role may use, "*" allows all
+	Servers []string `mapstructure:"servers" json:"servers" yaml:"servers"`
+	// Command patterns the role may run, see MatchCommand
+	Commands []string `mapstructure:"commands" json:"commands" yaml:"commands"`
+	// Commands per second per token, zero is unlimited
+	Rate  float64 `mapstructure:"rate" json:"rate" yaml:"rate"`
+	Burst int     `mapstructure:"burst" json:"burst" yaml:"burst"`
+}
+
+type Policy struct {
+	Roles  map[string]Role   `mapstructure:"roles" json:"roles" yaml:"roles"`
+	Tokens map[string]string `mapstructure:"tokens" json:"tokens" yaml:"tokens"` // token to role name
+}
+
+type Authorizer struct {
+	policy   Policy
+	limiters map[string]*ratelimit.Limiter // by role
+}
+
+func NewAuthorizer(policy Policy) (*Authorizer, error) {
+	for token, role := range policy.Tokens {
+		if token == "" {
+			return nil, errors.New("acl: empty token")
+		}
+		if _, ok := policy.Roles[role]; !ok {
+			return nil, fmt.Errorf("acl: token mapped to unknown role %q", role)
+		}
+	}
+
+	a := &Authorizer{
+		policy:   policy,
+		limiters: map[string]*ratelimit.Limiter{},
+	}
+	for name, role := range policy.Roles {
+		a.limiters[name] = ratelimit.NewLimiter(role.Rate, role.Burst)
+	}
+	return a, nil
+}
+
+// Check the token may run command on server, returning the token's role
+func (a *Authorizer) Authorize(token string, server string, command string) (string, error) {
+	name, ok := a.lookup(token)
+	if !ok {
+		return "", ErrorUnknownToken
+	}
+	role := a.policy.Roles[name]
+
+	if !matchAny(role.Servers, server, func(pattern, s string) bool {
+		return pattern == "*" || pattern == s
+	}) {
+		return name, ErrorServerDenied
+	}
+
+	if !matchAny(role.Commands, command, MatchCommand) {
+		return name, ErrorCommandDenied
+	}
+
+	if !a.limiters[name].Allow(token) {
+		return name, ErrorRateLimited
+	}
+
+	return name, nil
+}
+
+// Role of token, false if the token is unknown
+func (a *Authorizer) Role(token string) (string, bool) {
+	return a.lookup(token)
+}
+
+// Find the role for token without leaking timing information
+func (a *Authorizer) lookup(token string) (string, bool) {
+	role := ""
+	found := false
+	for t, r := range a.policy.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			role = r
+			found = true
+		}
+	}
+	return role, found
+}
+
+// A pattern without wildcards and spaces allows every use of that command
+// ("kick" allows "kick Steve"), otherwise it must match the whole command
+// line with "*" matching any text ("say *", "whitelist add *").
+func MatchCommand(pattern string, command string) bool {
+	command = strings.TrimPrefix(strings.TrimSpace(command), "/")
+	if pattern == "*" {
+		return true
+	}
+	if !strings.ContainsAny(pattern, "* ") {
+		name := command
+		if i := strings.IndexByte(command, ' '); i >= 0 {
+			name = command[:i]
+		}
+		return strings.EqualFold(pattern, name)
+	}
+	return glob(pattern, command)
+}
+
+func matchAny(patterns []string, s string, match func(pattern, s string) bool) bool {
+	for _, p := range patterns {
+		if match(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match s against pattern where "*" matches any sequence of characters
+func glob(pattern string, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
@@ -0,0 +1,145 @@
+package acl
+
+import (
+	"testing"
+)
+
+func TestMatchCommand(t *testing.T) {
+	tests := []struct {
+		pattern string
+		command string
+		want    bool
+	}{
+		{"*", "anything at all", true},
+		{"list", "list", true},
+		{"list", "list uuids", true},
+		{"list", "/list", true},
+		{"list", "  LIST ", true},
+		{"list", "listen", false},
+		{"kick", "ban Steve", false},
+		{"say *", "say hello there", true},
+		{"say *", "say", false},
+		{"say *", "tell Steve hi", false},
+		{"whitelist add *", "whitelist add Steve", true},
+		{"whitelist add *", "whitelist remove Steve", false},
+		{"whitelist list", "whitelist list", true},
+		{"whitelist list", "whitelist list extra", false},
+		{"time * day", "time set day", true},
+		{"time * day", "time set night", false},
+		{"*op*", "deop Steve", true},
+		{"*op*", "kick Steve", false},
+	}
+	for _, tt := range tests {
+		if got := MatchCommand(tt.pattern, tt.command); got != tt.want {
+			t.Errorf("MatchCommand(%q, %q) = %v", tt.pattern, tt.command, got)
+		}
+	}
+}
+
+func testPolicy() Policy {
+	return Policy{
+		Roles: map[string]Role{
+			"admin": {Servers: []string{"*"}, Commands: []string{"*"}},
+			"moderator": {
+				Servers:  []string{"lobby", "survival"},
+				Commands: []string{"list", "kick", "say *"},
+				Rate:     0.001,
+				Burst:    2,
+			},
+		},
+		Tokens: map[string]string{
+			"admin-token": "admin",
+			"mod-token":   "moderator",
+			"mod-token-2": "moderator",
+		},
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	a, err := NewAuthorizer(testPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		server  string
+		command string
+		role    string
+		err     error
+	}{
+		{"admin anywhere", "admin-token", "creative", "stop", "admin", nil},
+		{"moderator", "mod-token", "lobby", "kick Steve", "moderator", nil},
+		{"unknown token", "nope", "lobby", "list", "", ErrorUnknownToken},
+		{"token prefix", "mod-tok", "lobby", "list", "", ErrorUnknownToken},
+		{"empty token", "", "lobby", "list", "", ErrorUnknownToken},
+		{"server denied", "mod-token", "creative", "list", "moderator", ErrorServerDenied},
+		{"command denied", "mod-token", "lobby", "stop", "moderator", ErrorCommandDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := a.Authorize(tt.token, tt.server, tt.command)
+			if role != tt.role || err != tt.err {
+				t.Fatalf("got %q, %v, want %q, %v", role, err, tt.role, tt.err)
+			}
+		})
+	}
+}
+
+// Each token has its own bucket, and refused commands don't use it up
+func TestAuthorizeRateLimit(t *testing.T) {
+	a, err := NewAuthorizer(testPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := a.Authorize("mod-token", "lobby", "stop"); err != ErrorCommandDenied {
+			t.Fatalf("got %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := a.Authorize("mod-token", "lobby", "list"); err != nil {
+			t.Fatalf("command %d: %v", i, err)
+		}
+	}
+	if _, err := a.Authorize("mod-token", "lobby", "list"); err != ErrorRateLimited {
+		t.Fatalf("past the burst: %v", err)
+	}
+	if _, err := a.Authorize("mod-token-2", "lobby", "list"); err != nil {
+		t.Fatalf("other token of the role: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := a.Authorize("admin-token", "lobby", "list"); err != nil {
+			t.Fatalf("unlimited role: %v", err)
+		}
+	}
+}
+
+func TestNewAuthorizer(t *testing.T) {
+	policy := testPolicy()
+	policy.Tokens["stray"] = "owner"
+	if _, err := NewAuthorizer(policy); err == nil {
+		t.Error("token of an unknown role accepted")
+	}
+
+	policy = testPolicy()
+	policy.Tokens[""] = "admin"
+	if _, err := NewAuthorizer(policy); err == nil {
+		t.Error("empty token accepted")
+	}
+}
+
+func TestRole(t *testing.T) {
+	a, err := NewAuthorizer(testPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role, ok := a.Role("mod-token"); !ok || role != "moderator" {
+		t.Errorf("got %q, %v", role, ok)
+	}
+	if role, ok := a.Role("nope"); ok || role != "" {
+		t.Errorf("unknown token: %q, %v", role, ok)
+	}
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Audit trail of commands forwarded on behalf of clients, written as one
+// JSON object per line.
+
+type Record struct {
+	Time     time.Time     `json:"time"`
+	Client   string        `json:"client"`           // remote address or identity
+	Role     string        `json:"role,omitempty"`   // credential used by the client
+	Server   string        `json:"server,omitempty"` // upstream name
+	Command  string        `json:"command"`
+	Response string        `json:"response,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Denied   bool          `json:"denied,omitempty"` // rejected before forwarding
+	Duration time.Duration `json:"duration_ns,omitempty"`
+}
+
+type Logger struct {
+	w    io.Writer
+	enc  *json.Encoder
+	lock sync.Mutex
+}
+
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Append to the audit file at path, creating it if needed
+func Open(path string) (*Logger, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogger(file), nil
+}
+
+func (l *Logger) Log(r Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.enc.Encode(r)
+}
+
+func (l *Logger) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package bedrock fetches the status of Bedrock edition servers with the
+// RakNet unconnected ping, as the Bedrock server list does: edition, MOTD,
+// players and version.
+//
+//	s, err := bedrock.Ping(ctx, "localhost:19132")
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timeout of a ping whose context has no deadline
+const DefaultTimeout = 5 * time.Second
+
+// Default port of Bedrock servers
+const DefaultPort = 19132
+
+// Packet ids
+const (
+	idUnconnectedPing = 0x01
+	idUnconnectedPong = 0x1c
+)
+
+// Marks RakNet offline messages
+var magic = []byte{0x00, 0xff, 0xff, 0x00, 0xfe, 0xfe, 0xfe, 0xfe, 0xfd, 0xfd, 0xfd, 0xfd, 0x12, 0x34, 0x56, 0x78}
+
+var ErrorMalformed = errors.New("bedrock: malformed response")
+
+// Server status from the pong's "MCPE;MOTD;protocol;version;..." string,
+// which Raw holds as sent. Servers may leave out the fields after
+// MaxPlayers.
+type Status struct {
+	Edition    string // MCPE, or MCEE for Education Edition
+	MOTD       string
+	Protocol   int
+	Version    string
+	Players    int
+	MaxPlayers int
+	ServerId   string
+	LevelName  string
+	GameMode   string
+	PortV4     int
+	PortV6     int
+	Raw        string
+	Latency    time.Duration
+}
+
+// Ping the server at address, giving up when ctx is done
+func Ping(ctx context.Context, address string) (*Status, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultTimeout)
+	}
+	conn.SetDeadline(deadline)
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.SetDeadline(time.Now())
+			case <-stop:
+			}
+		}()
+	}
+
+	s, err := ping(conn)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	// The socket's deadline can pass a moment before ctx notices its own
+	if deadline, ok := ctx.Deadline(); err != nil && ok && !time.Now().Before(deadline) {
+		return nil, context.DeadlineExceeded
+	}
+	return s, err
+}
+
+func ping(conn net.Conn) (*Status, error) {
+	sent := time.Now()
+	req := make([]byte, 1+8, 1+8+16+8)
+	req[0] = idUnconnectedPing
+	binary.BigEndian.PutUint64(req[1:], uint64(sent.UnixNano()/int64(time.Millisecond)))
+	req = append(req, magic...)
+	var guid [8]byte
+	binary.BigEndian.PutUint64(guid[:], rand.Uint64())
+	req = append(req, guid[:]...)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		resp := buf[:n]
+		// A pong echoes the ping's time, skip those of earlier pings
+		if len(resp) < 1+8 || resp[0] != idUnconnectedPong || !bytes.Equal(resp[1:9], req[1:9]) {
+			continue
+		}
+		s, err := parsePong(resp)
+		if err != nil {
+			return nil, err
+		}
+		s.Latency = time.Since(sent)
+		return s, nil
+	}
+}
+
+// Pong: id, time, server GUID, magic, then the status as a string with a
+// 16 bit length
+func parsePong(resp []byte) (*Status, error) {
+	const header = 1 + 8 + 8 + 16
+	if len(resp) < header+2 || !bytes.Equal(resp[17:header], magic) {
+		return nil, ErrorMalformed
+	}
+	length := int(binary.BigEndian.Uint16(resp[header:]))
+	if len(resp) < header+2+length {
+		return nil, ErrorMalformed
+	}
+	raw := string(resp[header+2 : header+2+length])
+
+	fields := strings.Split(raw, ";")
+	if len(fields) < 6 {
+		return nil, ErrorMalformed
+	}
+	field := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	number := func(i int) int {
+		n, _ := strconv.Atoi(field(i))
+		return n
+	}
+	return &Status{
+		Edition:    field(0),
+		MOTD:       field(1),
+		Protocol:   number(2),
+		Version:    field(3),
+		Players:    number(4),
+		MaxPlayers: number(5),
+		ServerId:   field(6),
+		LevelName:  field(7),
+		GameMode:   field(8),
+		PortV4:     number(10),
+		PortV6:     number(11),
+		Raw:        raw,
+	}, nil
+}
@@ -0,0 +1,178 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Pong of a Bedrock Dedicated Server 1.20.41, as captured
+const capturedPong = "1c" +
+	"0000018b5c0c5e21" + // time of the ping
+	"b7ede8f1fd2bc931" + // server GUID
+	"00ffff00fefefefefdfdfdfd12345678" + // magic
+	"0061" + // length
+	"4d4350453b446564696361746564205365727665723b3632323b312e32302e34" +
+	"313b323b31303b31333235333836303839323332383933303836353b42656472" +
+	"6f636b206c6576656c3b537572766976616c3b313b31393133323b3139313333" +
+	"3b"
+
+func pong(time []byte, status string) []byte {
+	b := append([]byte{idUnconnectedPong}, time...)
+	b = append(b, 0xb7, 0xed, 0xe8, 0xf1, 0xfd, 0x2b, 0xc9, 0x31)
+	b = append(b, magic...)
+	b = append(b, byte(len(status)>>8), byte(len(status)))
+	return append(b, status...)
+}
+
+func TestParsePong(t *testing.T) {
+	data, err := hex.DecodeString(capturedPong)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := parsePong(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Status{
+		Edition:    "MCPE",
+		MOTD:       "Dedicated Server",
+		Protocol:   622,
+		Version:    "1.20.41",
+		Players:    2,
+		MaxPlayers: 10,
+		ServerId:   "13253860892328930865",
+		LevelName:  "Bedrock level",
+		GameMode:   "Survival",
+		PortV4:     19132,
+		PortV6:     19133,
+		Raw:        "MCPE;Dedicated Server;622;1.20.41;2;10;13253860892328930865;Bedrock level;Survival;1;19132;19133;",
+	}
+	if *s != want {
+		t.Errorf("got %+v\nwant %+v", *s, want)
+	}
+}
+
+// Servers may stop after the player counts
+func TestParsePongShortStatus(t *testing.T) {
+	s, err := parsePong(pong(make([]byte, 8), "MCEE;Classroom;390;1.14.31;0;40"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Edition != "MCEE" || s.MaxPlayers != 40 || s.ServerId != "" || s.PortV4 != 0 {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestParsePongMalformed(t *testing.T) {
+	good := pong(make([]byte, 8), "MCPE;motd;622;1.20.41;0;10")
+	badMagic := append([]byte(nil), good...)
+	badMagic[20] ^= 0xff
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"header only", good[:33]},
+		{"bad magic", badMagic},
+		{"length past the end", good[:len(good)-1]},
+		{"too few fields", pong(make([]byte, 8), "MCPE;motd;622;1.20.41;0")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s, err := parsePong(tt.data); err != ErrorMalformed {
+				t.Fatalf("got %+v, %v, want ErrorMalformed", s, err)
+			}
+		})
+	}
+}
+
+// Server on a local UDP socket answering each ping with pongs from reply
+func fakeServer(t *testing.T, reply func(ping []byte) [][]byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			ping := buf[:n]
+			if n != 1+8+16+8 || ping[0] != idUnconnectedPing || !bytes.Equal(ping[9:25], magic) {
+				continue
+			}
+			for _, p := range reply(ping) {
+				conn.WriteTo(p, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestPing(t *testing.T) {
+	address := fakeServer(t, func(ping []byte) [][]byte {
+		earlier := make([]byte, 8)
+		binary.BigEndian.PutUint64(earlier, binary.BigEndian.Uint64(ping[1:9])-1000)
+		return [][]byte{
+			{0x00}, // not a pong
+			pong(earlier, "MCPE;stale;622;1.20.41;0;10"),
+			pong(ping[1:9], "MCPE;Dedicated Server;622;1.20.41;2;10;1;Bedrock level;Survival;1;19132;19133;"),
+		}
+	})
+
+	s, err := Ping(context.Background(), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.MOTD != "Dedicated Server" || s.Players != 2 || s.LevelName != "Bedrock level" {
+		t.Errorf("got %+v", s)
+	}
+	if s.Latency <= 0 {
+		t.Errorf("latency %v", s.Latency)
+	}
+}
+
+func TestPingMalformed(t *testing.T) {
+	address := fakeServer(t, func(ping []byte) [][]byte {
+		return [][]byte{pong(ping[1:9], "not a status")}
+	})
+	if _, err := Ping(context.Background(), address); err != ErrorMalformed {
+		t.Fatalf("got %v, want ErrorMalformed", err)
+	}
+
+	// Too short to carry the ping's time, skipped until the deadline
+	address = fakeServer(t, func(ping []byte) [][]byte {
+		return [][]byte{append([]byte{idUnconnectedPong}, ping[1:5]...)}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Ping(ctx, address); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestPingContext(t *testing.T) {
+	address := fakeServer(t, func(ping []byte) [][]byte { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := Ping(ctx, address); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want Canceled", err)
+	}
+
+	if _, err := Ping(context.Background(), "no port"); err == nil || !strings.Contains(err.Error(), "port") {
+		t.Fatalf("address without a port: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package clock abstracts time so deadlines, retries, keepalives and request
+// IDs can be driven by a Mock in tests instead of real sleeps.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// The system clock
+var Real Clock = realClock{}
+
+// Or returns c, or Real when c is nil
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+// Manually advanced clock. Timers, tickers and sleeps fire only when Advance
+// or Set moves time past their deadline.
+type Mock struct {
+	now    time.Time
+	timers []*mockTimer
+	lock   sync.Mutex
+}
+
+func NewMock(start time.Time) *Mock {
+	return &Mock{now: start}
+}
+
+func (m *Mock) Now() time.Time {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.now
+}
+
+func (m *Mock) Since(t time.Time) time.Duration {
+	return m.Now().Sub(t)
+}
+
+func (m *Mock) After(d time.Duration) <-chan time.Time {
+	return m.NewTimer(d).C()
+}
+
+func (m *Mock) Sleep(d time.Duration) {
+	<-m.After(d)
+}
+
+func (m *Mock) NewTimer(d time.Duration) Timer {
+	return m.add(d, 0)
+}
+
+func (m *Mock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return mockTicker{m.add(d, d)}
+}
+
+// Move time forward by d, firing everything that falls due
+func (m *Mock) Advance(d time.Duration) {
+	m.Set(m.Now().Add(d))
+}
+
+// Move time to t, firing everything that falls due
+func (m *Mock) Set(t time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for {
+		// Fire in deadline order so tickers interleave correctly
+		sort.Slice(m.timers, func(i, j int) bool { return m.timers[i].when.Before(m.timers[j].when) })
+		if len(m.timers) == 0 || m.timers[0].when.After(t) {
+			break
+		}
+		timer := m.timers[0]
+		m.now = timer.when
+		select {
+		case timer.c <- timer.when:
+		default: // receiver is behind, drop the tick like time.Ticker
+		}
+		if timer.period > 0 {
+			timer.when = timer.when.Add(timer.period)
+		} else {
+			m.remove(timer)
+		}
+	}
+	m.now = t
+}
+
+// Number of timers and tickers waiting to fire
+func (m *Mock) Pending() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return len(m.timers)
+}
+
+func (m *Mock) add(d time.Duration, period time.Duration) *mockTimer {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t := &mockTimer{
+		mock:   m,
+		c:      make(chan time.Time, 1),
+		when:   m.now.Add(d),
+		period: period,
+	}
+	if d <= 0 && period == 0 {
+		t.c <- m.now
+		return t
+	}
+	m.timers = append(m.timers, t)
+	return t
+}
+
+func (m *Mock) remove(t *mockTimer) bool {
+	for i, other := range m.timers {
+		if other == t {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type mockTicker struct{ t *mockTimer }
+
+func (t mockTicker) C() <-chan time.Time { return t.t.c }
+func (t mockTicker) Stop()               { t.t.Stop() }
+
+type mockTimer struct {
+	mock   *Mock
+	c      chan time.Time
+	when   time.Time
+	period time.Duration
+}
+
+func (t *mockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTimer) Stop() bool {
+	t.mock.lock.Lock()
+	defer t.mock.lock.Unlock()
+	return t.mock.remove(t)
+}
+
+func (t *mockTimer) Reset(d time.Duration) bool {
+	t.mock.lock.Lock()
+	defer t.mock.lock.Unlock()
+
+	active := t.mock.remove(t)
+	t.when = t.mock.now.Add(d)
+	t.mock.timers = append(t.mock.timers, t)
+	return active
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// bansCmd represents the bans command. Without a known subcommand the
+// arguments are sent to the server's banlist command.
+var bansCmd = &cobra.Command{
+	Use:   "bans [sync <file> | banlist arguments ...]",
+	Short: "Manage the server ban list",
+	Args:  cobra.ArbitraryArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		server, err := serverAddress("")
+		if err != nil {
+			return err
+		}
+		if err := cli.Execute(server, os.Stdout, append([]string{"banlist"}, args...)...); err != nil {
+			os.Exit(cli.ExitFailure)
+		}
+		return nil
+	},
+}
+
+// bansSyncCmd represents the bans sync command
+var bansSyncCmd = &cobra.Command{
+	Use:   "sync <file>",
+	Short: "Make the server ban list match a local file",
+	Long: `Diff a local list of banned players (banned-players.json, or one name
+	or UUID per line) against the server's and apply only the necessary
+	ban/pardon commands. UUIDs are resolved via the Mojang API. For example:
+
+	rcon bans sync banned-players.json
+	rcon bans sync griefers.txt --reason "Griefing" --dry-run
+
+`,
+	Args: cobra.ExactArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		uuids, _ := cmd.Flags().GetBool("uuids")
+		reason, _ := cmd.Flags().GetString("reason")
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		names, err := cli.ReadNames(file)
+		if err != nil {
+			return err
+		}
+
+		server, err := serverAddress("")
+		if err != nil {
+			return err
+		}
+		resolver, done := syncResolver(names, uuids)
+		defer done()
+		if !cli.BanSync(server, os.Stdout, names, reason, resolver, dryRun) {
+			return errors.New("ban list sync incomplete")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bansCmd)
+	bansCmd.AddCommand(bansSyncCmd)
+
+	bansSyncCmd.Flags().Bool("dry-run", false, "show the changes without applying them")
+	bansSyncCmd.Flags().Bool("uuids", false, "report players with their UUIDs, looked up via the Mojang API")
+	bansSyncCmd.Flags().String("reason", "", "reason given to newly banned players")
+}
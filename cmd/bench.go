@@ -0,0 +1,63 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure RCON throughput and latency",
+	Long: `Repeatedly send a command over concurrent connections and report
+	throughput, latency distribution and errors by class.
+	For example:
+
+	rcon bench --concurrency 8 --duration 30s --command list
+
+`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		duration, _ := cmd.Flags().GetDuration("duration")
+		command, _ := cmd.Flags().GetString("command")
+
+		server, err := serverAddress("")
+		if err != nil {
+			return err
+		}
+
+		cli.Bench(server, os.Stdout, cli.BenchOptions{
+			Concurrency: concurrency,
+			Duration:    duration,
+			Command:     command,
+		})
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().Int("concurrency", 1, "number of concurrent connections")
+	benchCmd.Flags().Duration("duration", 10*time.Second, "how long to run the benchmark")
+	benchCmd.Flags().String("command", "list", "command to send")
+}
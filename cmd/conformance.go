@@ -0,0 +1,68 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/conformance"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// conformanceCmd represents the conformance command
+var conformanceCmd = &cobra.Command{
+	Use:   "conformance",
+	Short: "Check a server's RCON protocol compatibility",
+	Long: `Run a battery of protocol checks (authentication, payload limits,
+	fragmentation, invalid types, coalesced packets) against the server
+	and print a report.
+	For example:
+
+	rcon conformance -H example.com --large-command "help"
+
+`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		large, _ := cmd.Flags().GetString("large-command")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		server, err := serverAddress("")
+		if err != nil {
+			return err
+		}
+
+		report := conformance.Run(conformance.Config{
+			Address:      server.HostUri,
+			Password:     server.Password,
+			Timeout:      timeout,
+			LargeCommand: large,
+		})
+		report.Write(os.Stdout)
+
+		if !report.Passed() {
+			return errors.New("conformance checks failed")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(conformanceCmd)
+
+	conformanceCmd.Flags().String("large-command", "help", "command whose response spans several packets")
+	conformanceCmd.Flags().Duration("timeout", 0, "timeout per check (default 10s)")
+}
@@ -0,0 +1,54 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+// dashboardCmd represents the dashboard command
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Watch the server live and send commands",
+	Long: `Show the players online and the latency of polling them, refreshed
+	every --interval, with an input line for sending commands and their
+	output below. Up and down recall earlier commands, Ctrl-C quits.
+	For example:
+
+	rcon dashboard --server lobby
+	rcon dashboard --interval 5s
+
+`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interval, _ := cmd.Flags().GetDuration("interval")
+
+		server, err := serverAddress("")
+		if err != nil {
+			return err
+		}
+		return cli.Dashboard(server, interval)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dashboardCmd)
+
+	dashboardCmd.Flags().Duration("interval", 2*time.Second, "time between refreshes of the players")
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// functionRunCmd represents the function-run command, named apart from the
+// server's function command which is sent as any other
+var functionRunCmd = &cobra.Command{
+	Use:   "function-run <file>",
+	Short: "Run a local .mcfunction file",
+	Long: `Run each command of a local .mcfunction file over one connection,
+	reporting the commands that fail. Comments and blank lines are skipped.
+	For example:
+
+	rcon function-run setup.mcfunction
+	rcon function-run arena/reset.mcfunction --stop-on-error
+
+`,
+	Args: cobra.ExactArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stopOnError, _ := cmd.Flags().GetBool("stop-on-error")
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		commands, err := mcapi.ReadFunction(file)
+		if err != nil {
+			return err
+		}
+
+		server, err := serverAddress("")
+		if err != nil {
+			return err
+		}
+		if !cli.RunFunction(server, os.Stdout, commands, stopOnError) {
+			return errors.New("function had failures")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(functionRunCmd)
+
+	functionRunCmd.Flags().Bool("stop-on-error", false, "stop at the first failing command")
+}
@@ -0,0 +1,83 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/StarForger/neb-mc-rcon/internal/keyring"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// loginCmd represents the login command
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store the server's password in the keyring",
+	Long: `Ask for the password of the server profile picked with --server, or of
+	the global settings without one, check it logs in and store it in the
+	operating system's keyring. Commands use it when no password or password
+	file is configured.
+	For example:
+
+	rcon login --server lobby
+
+`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profile := viper.GetString("server")
+		server, err := serverAddress(profile)
+		if err != nil {
+			return err
+		}
+
+		password, err := cli.PromptPassword("Password for " + keyringAccount(profile) + ": ")
+		if err != nil {
+			return err
+		}
+		if password == "" {
+			return errors.New("empty password")
+		}
+		server.Password = password
+		if err := cli.CheckLogin(server); err != nil {
+			return err
+		}
+		return keyring.Set(keyringAccount(profile), password)
+	},
+}
+
+// logoutCmd represents the logout command
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the server's password from the keyring",
+	Args:  cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		account := keyringAccount(viper.GetString("server"))
+		err := keyring.Delete(account)
+		if err == keyring.ErrorNotFound {
+			return fmt.Errorf("no password stored for %q", account)
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/StarForger/neb-mc-rcon/internal/keyring"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"net"
+	"strings"
+)
+
+// serverAddress resolves the RCON address, password and protocol to use. An empty
+// profile means the one selected with --server or RCON_SERVER, if any. With
+// no profile the global host/port/password settings apply, otherwise the
+// named entry under "servers" in the config file, falling back to the global
+// settings for any missing key. A password file takes the place of the password, and without either the
+// password stored in the keyring by "rcon login" is used, if any.
+func serverAddress(profile string) (cli.Upstream, error) {
+	if profile == "" {
+		profile = viper.GetString("server")
+	}
+
+	host := viper.GetString("host")
+	port := viper.GetString("port")
+	password := viper.GetString("password")
+	passwordFile := viper.GetString("password-file")
+	protocol := viper.GetString("protocol")
+
+	if profile != "" {
+		server := viper.Sub("servers." + profile)
+		if server == nil {
+			return cli.Upstream{}, fmt.Errorf("unknown server profile %q", profile)
+		}
+		if server.IsSet("host") {
+			host = server.GetString("host")
+		}
+		if server.IsSet("port") {
+			port = server.GetString("port")
+		}
+		if server.IsSet("password") {
+			password = server.GetString("password")
+			passwordFile = ""
+		}
+		if server.IsSet("password-file") {
+			passwordFile = server.GetString("password-file")
+		}
+		if server.IsSet("protocol") {
+			protocol = server.GetString("protocol")
+		}
+	}
+	if err := cli.CheckProtocol(protocol); err != nil {
+		return cli.Upstream{}, err
+	}
+
+	if passwordFile != "" {
+		data, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return cli.Upstream{}, err
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	}
+	if password == "" {
+		// Errors leave the password to be asked for
+		password, _ = keyring.Get(keyringAccount(profile))
+	}
+
+	return cli.Upstream{HostUri: joinAddress(host, port), Password: password, Protocol: protocol}, nil
+}
+
+// serverUpstreams resolves every server profile in the config file, for
+// commands forwarding to them. A non-empty def must be one of them.
+func serverUpstreams(def string) (map[string]cli.Upstream, error) {
+	upstreams := map[string]cli.Upstream{}
+	for name := range viper.GetStringMap("servers") {
+		u, err := serverAddress(name)
+		if err != nil {
+			return nil, err
+		}
+		upstreams[name] = u
+	}
+	if len(upstreams) == 0 {
+		return nil, fmt.Errorf("no servers in the config file")
+	}
+	if _, ok := upstreams[def]; def != "" && !ok {
+		return nil, fmt.Errorf("unknown server profile %q", def)
+	}
+	return upstreams, nil
+}
+
+// namedUpstreams resolves the named server profiles, for commands run on
+// several servers.
+func namedUpstreams(names []string) (map[string]cli.Upstream, error) {
+	upstreams := map[string]cli.Upstream{}
+	for _, name := range names {
+		u, err := serverAddress(name)
+		if err != nil {
+			return nil, err
+		}
+		upstreams[name] = u
+	}
+	return upstreams, nil
+}
+
+// keyringAccount names the keyring entry of a profile, "default" for the
+// global settings.
+func keyringAccount(profile string) string {
+	if profile == "" {
+		return "default"
+	}
+	return profile
+}
+
+// joinAddress builds the address to dial. A unix:///path host is a Unix
+// domain socket, used as is without the port.
+func joinAddress(host string, port string) string {
+	if strings.HasPrefix(host, "unix://") {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/StarForger/neb-mc-rcon/ratelimit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// proxyCmd represents the proxy command
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Forward RCON clients to servers with limited access",
+	Long: `Listen for RCON clients and forward their commands to the servers in
+	the config file, so access can be handed out without the real passwords.
+	Clients log in with a token from the proxy section, whose role limits the
+	servers and commands they may use. A command starting with @name goes to
+	that server, others to --default. Every command is appended to the
+	--audit file, which is required. A server's limits cap each token's
+	commands per second and sessions using it.
+	For example:
+
+	rcon proxy --listen :25576 --default lobby --audit audit.jsonl \
+	    --tls-cert proxy.pem --tls-key proxy.key
+
+	Without a certificate the proxy only listens on loopback addresses,
+	unless --insecure.
+
+	with a config file such as:
+
+	servers:
+	  lobby:
+	    host: lobby.internal
+	    password: ***
+	    limits:
+	      rate: 2
+	      burst: 10
+	      max-sessions: 3
+	proxy:
+	  roles:
+	    moderator:
+	      servers: [lobby]
+	      commands: [list, kick, "say *"]
+	  tokens:
+	    4f2c...: moderator
+
+`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := cli.ProxyOptions{}
+		opts.Listen, _ = cmd.Flags().GetString("listen")
+		opts.Default, _ = cmd.Flags().GetString("default")
+		opts.AuditPath, _ = cmd.Flags().GetString("audit")
+		opts.Insecure, _ = cmd.Flags().GetBool("insecure")
+		opts.TLS = tlsFlags(cmd)
+		if err := viper.UnmarshalKey("proxy", &opts.Policy); err != nil {
+			return fmt.Errorf("invalid proxy config: %w", err)
+		}
+
+		opts.MaxSessions, _ = cmd.Flags().GetInt("max-sessions")
+
+		upstreams, err := serverUpstreams(opts.Default)
+		if err != nil {
+			return err
+		}
+		opts.Limits = map[string]ratelimit.Config{}
+		for name := range upstreams {
+			var limits ratelimit.Config
+			if err := viper.UnmarshalKey("servers."+name+".limits", &limits); err != nil {
+				return fmt.Errorf("invalid limits of server %q: %w", name, err)
+			}
+			opts.Limits[name] = limits
+		}
+		return cli.Proxy(upstreams, opts)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(proxyCmd)
+
+	proxyCmd.Flags().String("listen", "127.0.0.1:25576", "address to listen on")
+	proxyCmd.Flags().String("default", "", "server profile for commands without an @name prefix")
+	proxyCmd.Flags().String("audit", "", "append a JSON line per command to this file (required)")
+	proxyCmd.Flags().Int("max-sessions", 0, "concurrent sessions per token, 0 for no limit")
+	proxyCmd.Flags().Bool("insecure", false, "allow plain RCON on a non-loopback address")
+	addTLSFlags(proxyCmd)
+}
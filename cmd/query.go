@@ -0,0 +1,68 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/spf13/cobra"
+	"net"
+	"os"
+	"strconv"
+)
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Show the server's Query stat",
+	Long: `Show the server's MOTD, map and player count over the UDP Query
+	protocol, which needs enable-query but no password, and with --full its
+	version, plugins and player names.
+	For example:
+
+	rcon query
+	rcon query --full --query-port 25565
+
+`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		full, _ := cmd.Flags().GetBool("full")
+		target, _ := cmd.Flags().GetString("target")
+		port, _ := cmd.Flags().GetInt("query-port")
+
+		server, err := serverAddress(target)
+		if err != nil {
+			return err
+		}
+		host, _, err := net.SplitHostPort(server.HostUri)
+		if err != nil {
+			return err
+		}
+		if !cli.Query(net.JoinHostPort(host, strconv.Itoa(port)), os.Stdout, full) {
+			return errors.New("query unavailable")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	queryCmd.Flags().Bool("full", false, "include version, plugins and player names")
+	queryCmd.Flags().Int("query-port", 25565, "server's query.port")
+	queryCmd.Flags().String("target", "", "server profile from the config file")
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay <file>",
+	Short: "Re-run a recorded session",
+	Long: `Re-run the commands of a session recorded with --record, keeping
+	their relative timing. Sessions appended to the same file are replayed
+	one after the other, or only the one picked with --session.
+	For example:
+
+	rcon --record session.rcon
+	rcon replay session.rcon --speed 2x --target staging
+	rcon replay session.rcon --session 9f86d081884c7d65
+
+`,
+	Args: cobra.ExactArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		speedFlag, _ := cmd.Flags().GetString("speed")
+		target, _ := cmd.Flags().GetString("target")
+		session, _ := cmd.Flags().GetString("session")
+
+		speed, err := strconv.ParseFloat(strings.TrimSuffix(speedFlag, "x"), 64)
+		if err != nil || speed <= 0 {
+			return fmt.Errorf("invalid speed %q", speedFlag)
+		}
+
+		server, err := serverAddress(target)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		entries, err := cli.ReadRecording(file)
+		if err != nil {
+			return err
+		}
+		if session != "" {
+			entries = cli.SessionEntries(entries, session)
+			if len(entries) == 0 {
+				return fmt.Errorf("no session %q in %s", session, args[0])
+			}
+		}
+
+		cli.Replay(server, os.Stdout, entries, speed)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().String("speed", "1x", "playback speed multiplier (e.g. 2x)")
+	replayCmd.Flags().String("target", "", "server profile to replay against (default is the configured server)")
+	replayCmd.Flags().String("session", "", "replay only this session of a file several were recorded to")
+}
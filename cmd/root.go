@@ -18,12 +18,14 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"github.com/StarForger/neb-mc-rcon/cli"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
 	"github.com/spf13/cobra"	
 	"github.com/spf13/viper"
-	"net"
 	homedir "github.com/mitchellh/go-homedir"
 	"log"
+	"strings"
+	"time"
 )
 
 var ( 
@@ -35,17 +37,46 @@ var rootCmd = &cobra.Command{
 	Use:   "neb-mc-rcon [flags] [command ...]",
 	Short: "CLI for RCON server interaction",
 	Long: `CLI for interacting with RCON game servers.
-	With no arguments, the CLI will run an interactive session 
-	If arguments are included, they are sent as commands to the server.
+	With no arguments, the CLI will run an interactive session, or run the
+	commands piped to it as a script like --file does.
+	In the session, :help lists the commands for the client itself.
+	If arguments are included, they are sent as a command to the server,
+	several separated by --.
+	The exit status is 1 when a command fails or its response reports a
+	failure, and 2 when connecting or logging in fails.
 	For example:
 
 	rcon -H example.com 
 	rcon -H minecraft.com stop
 	RCON_PORT=25575 rcon list
+	rcon --server lobby list
+	RCON_SERVER=lobby rcon
+	rcon "say hi" -- save-all -- stop
+	rcon -c "say hi" -c save-all
+	rcon --file backup.txt --stop-on-error
+	echo save-all | rcon
+	rcon -o json list
+	rcon --all "say Restarting in 5 minutes"
+	rcon --servers lobby,survival save-all
+	rcon --wait --wait-timeout 120s whitelist reload
 
 `,
-	
-	Run: func(cmd *cobra.Command, args []string) { 
+	// Arguments that are not subcommands are sent to the server
+	Args: cobra.ArbitraryArgs,
+
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cli.SetColor(!viper.GetBool("no-color"))
+		cli.SetVerbose(viper.GetBool("verbose"), viper.GetBool("debug-wire"))
+		if err := cli.SetResponseCheck(viper.GetString("fail-regex"), viper.GetString("success-regex")); err != nil {
+			return err
+		}
+		if viper.GetBool("wait") {
+			cli.SetWait(viper.GetDuration("wait-timeout"))
+		}
+		return cli.SetOutput(viper.GetString("output"))
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
 		ver := viper.GetBool("version")
 
 		if ver {
@@ -53,20 +84,118 @@ var rootCmd = &cobra.Command{
 			return
 		}
 
-		host := viper.GetString("host")
-		port := viper.GetString("port")
-		pwd := viper.GetString("password")
+		commands, _ := cmd.Flags().GetStringArray("cmd")
+		commands = append(commands, splitCommands(args, cmd.ArgsLenAtDash())...)
+		stopOnError, _ := cmd.Flags().GetBool("stop-on-error")
+
+		all, _ := cmd.Flags().GetBool("all")
+		servers, _ := cmd.Flags().GetStringSlice("servers")
+		if all || len(servers) > 0 {
+			fanOut(cmd, commands, all, servers, stopOnError)
+			return
+		}
+
+		server, err := serverAddress("")
+		if err != nil {
+			log.Fatal(err)
+		}
 
-		uri := net.JoinHostPort(host, port)
+		file, _ := cmd.Flags().GetString("file")
+		if file == "" && len(commands) == 0 && !cli.IsTerminal(os.Stdin) {
+			// Piped commands run as a script
+			file = "-"
+		}
 
-		if len(args) == 0 {
-			cli.Run(uri, pwd, os.Stdin, os.Stdout)
+		if file != "" {
+			commands, err := readScript(file)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !cli.RunFunction(server, os.Stdout, commands, stopOnError) {
+				os.Exit(cli.ExitFailure)
+			}
+		} else if len(commands) == 0 {
+			var rec *cli.Recorder
+			if path, _ := cmd.Flags().GetString("record"); path != "" {
+				var err error
+				rec, err = cli.NewRecorder(path, server.HostUri)
+				if err != nil {
+					log.Fatal("Failed to open recording: ", err)
+				}
+				defer rec.Close()
+			}
+			cli.Run(server, os.Stdin, os.Stdout, cli.SessionOptions{Record: rec, Resolve: serverAddress})
 		} else {
-			cli.Execute(uri, pwd, os.Stdout, args...)
+			if err := cli.ExecuteAll(server, os.Stdout, commands, stopOnError); err != nil {
+				os.Exit(cli.ExitFailure)
+			}
 		}
 	},
 }
 
+// fanOut runs commands on all server profiles or the named ones, exiting
+// non-zero when any of them failed.
+func fanOut(cmd *cobra.Command, commands []string, all bool, servers []string, stopOnError bool) {
+	if len(commands) == 0 {
+		log.Fatal("no command to run on several servers")
+	}
+
+	var upstreams map[string]cli.Upstream
+	var err error
+	if all {
+		upstreams, err = serverUpstreams("")
+	} else {
+		upstreams, err = namedUpstreams(servers)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	if err := cli.FanOut(upstreams, os.Stdout, commands, parallel, stopOnError); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(cli.ExitFailure)
+	}
+}
+
+// splitCommands groups arguments into commands separated by "--". Cobra
+// drops the first "--" and dash is where it was, -1 for none.
+func splitCommands(args []string, dash int) []string {
+	var commands, words []string
+	flush := func() {
+		if len(words) > 0 {
+			commands = append(commands, strings.Join(words, " "))
+		}
+		words = nil
+	}
+
+	for i, arg := range args {
+		if i == dash || arg == "--" {
+			flush()
+		}
+		if arg != "--" {
+			words = append(words, arg)
+		}
+	}
+	flush()
+	return commands
+}
+
+// readScript reads the commands of a script, one per line with blank lines
+// and # comments skipped, from a file or, for "-", from stdin.
+func readScript(path string) ([]mcapi.FunctionCommand, error) {
+	if path == "-" {
+		return mcapi.ReadFunction(os.Stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return mcapi.ReadFunction(file)
+}
+
 func Execute() {
 	cobra.CheckErr(rootCmd.Execute())
 }
@@ -75,10 +204,28 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.rcon.yml)")
-	rootCmd.PersistentFlags().StringP("host", "H", "localhost", "RCON server's hostname")
-	rootCmd.PersistentFlags().String("password", "", "RCON server's password")
+	rootCmd.PersistentFlags().StringP("host", "H", "localhost", "RCON server's hostname, or unix:///path for a socket")
+	rootCmd.PersistentFlags().String("password", "", "RCON server's password, visible to other users, prefer --password-file or rcon login")
+	rootCmd.PersistentFlags().String("password-file", "", "file holding the RCON server's password")
 	rootCmd.PersistentFlags().Int("port", 25575, "RCON port")
+	rootCmd.PersistentFlags().StringP("server", "s", "", "server profile from the config file's servers section")
+	rootCmd.PersistentFlags().String("protocol", "rcon", "protocol to speak, rcon or webrcon for Rust servers")
+	rootCmd.PersistentFlags().StringP("output", "o", "text", "output format of commands sent: text, raw, json or yaml")
+	rootCmd.PersistentFlags().String("fail-regex", "", "responses matching it fail the command (default unknown command, incorrect argument or player not found)")
+	rootCmd.PersistentFlags().String("success-regex", "", "responses not matching it fail the command")
+	rootCmd.PersistentFlags().Bool("wait", false, "keep retrying to connect until the server is up, for startup scripts")
+	rootCmd.PersistentFlags().Duration("wait-timeout", 120*time.Second, "how long --wait retries")
+	rootCmd.PersistentFlags().Bool("no-color", false, "print responses without colors, also set by NO_COLOR")
+	rootCmd.PersistentFlags().Bool("verbose", false, "log connections, commands and their timing to stderr")
+	rootCmd.PersistentFlags().Bool("debug-wire", false, "log every packet as a hex dump too, implies --verbose")
 	rootCmd.PersistentFlags().BoolP("version", "v", false, "version number")
+	rootCmd.Flags().StringArrayP("cmd", "c", nil, "command to send, repeat for several over one connection")
+	rootCmd.Flags().Bool("all", false, "run the command on every server profile at once")
+	rootCmd.Flags().StringSlice("servers", nil, "run the command on these server profiles at once")
+	rootCmd.Flags().Int("parallel", 8, "servers run on at the same time with --all or --servers")
+	rootCmd.Flags().StringP("file", "f", "", "run the commands of a script file, - for stdin")
+	rootCmd.Flags().Bool("stop-on-error", false, "stop a script or several commands at the first failing one")
+	rootCmd.Flags().String("record", "", "append the interactive session's commands and responses to a JSONL file, also :record <file> in the session")
 	err := viper.BindPFlags(rootCmd.PersistentFlags())
 	if err != nil {
 		log.Fatal(err)
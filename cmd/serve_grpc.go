@@ -0,0 +1,60 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/spf13/cobra"
+)
+
+// serveGrpcCmd represents the serve-grpc command
+var serveGrpcCmd = &cobra.Command{
+	Use:   "serve-grpc",
+	Short: "Expose RCON commands over gRPC",
+	Long: `Serve the Rcon service of grpcserver/rcon.proto, Execute, ExecuteStream
+	and Status, forwarding commands to the servers in the config file over
+	pooled connections. gRPC needs TLS here; with --client-ca and
+	--require-client-cert only clients with a certificate it signed are let
+	in. Deadlines set by clients apply to the commands.
+	For example:
+
+	rcon serve-grpc --listen :50051 --default lobby --tls-cert server.pem --tls-key server.key \
+	    --client-ca clients.pem --require-client-cert
+
+`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := cli.GrpcOptions{}
+		opts.Listen, _ = cmd.Flags().GetString("listen")
+		opts.Default, _ = cmd.Flags().GetString("default")
+		opts.TLS = tlsFlags(cmd)
+
+		upstreams, err := serverUpstreams(opts.Default)
+		if err != nil {
+			return err
+		}
+		return cli.ServeGRPC(upstreams, opts)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveGrpcCmd)
+
+	serveGrpcCmd.Flags().String("listen", ":50051", "address to listen on")
+	serveGrpcCmd.Flags().String("default", "", "server profile of requests without a server")
+	addTLSFlags(serveGrpcCmd)
+}
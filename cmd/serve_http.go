@@ -0,0 +1,86 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// serveHttpCmd represents the serve-http command
+var serveHttpCmd = &cobra.Command{
+	Use:   "serve-http",
+	Short: "Expose RCON commands over HTTP",
+	Long: `Serve POST /command, taking {"cmd": "...", "server": "lobby"}, and GET
+	/healthz, forwarding commands to the servers in the config file over
+	pooled connections. Requests carry a token from the http section as
+	"Authorization: Bearer <token>", whose role limits the servers and
+	commands they may use.
+	For example:
+
+	rcon serve-http --listen :8443 --default lobby --tls-cert gw.pem --tls-key gw.key
+	curl -H "Authorization: Bearer 4f2c..." -d '{"cmd": "list"}' https://localhost:8443/command
+
+	Without a certificate the gateway only listens on loopback addresses,
+	unless --insecure.
+
+	with a config file such as:
+
+	servers:
+	  lobby:
+	    host: lobby.internal
+	    password: ***
+	http:
+	  roles:
+	    ci:
+	      servers: [lobby]
+	      commands: ["say *", "whitelist add *"]
+	  tokens:
+	    4f2c...: ci
+
+`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := cli.GatewayOptions{}
+		opts.Listen, _ = cmd.Flags().GetString("listen")
+		opts.Default, _ = cmd.Flags().GetString("default")
+		opts.AuditPath, _ = cmd.Flags().GetString("audit")
+		opts.Insecure, _ = cmd.Flags().GetBool("insecure")
+		opts.TLS = tlsFlags(cmd)
+		if err := viper.UnmarshalKey("http", &opts.Policy); err != nil {
+			return fmt.Errorf("invalid http config: %w", err)
+		}
+
+		upstreams, err := serverUpstreams(opts.Default)
+		if err != nil {
+			return err
+		}
+		return cli.ServeHTTP(upstreams, opts)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveHttpCmd)
+
+	serveHttpCmd.Flags().String("listen", "127.0.0.1:8080", "address to listen on")
+	serveHttpCmd.Flags().String("default", "", "server profile of requests without a server")
+	serveHttpCmd.Flags().String("audit", "", "append a JSON line per command to this file")
+	serveHttpCmd.Flags().Bool("insecure", false, "allow plain HTTP on a non-loopback address")
+	addTLSFlags(serveHttpCmd)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/spf13/cobra"
+	"net"
+	"os"
+	"strconv"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the server's version and players",
+	Long: `Show the server's version and online players, and with --full its
+	tick rate, time and difficulty. With --ping the status is fetched with a
+	Server List Ping, as the multiplayer screen does, which needs no password,
+	and with --bedrock from a Bedrock server with a RakNet ping.
+	For example:
+
+	rcon status
+	rcon status --full --target survival
+	rcon status --ping --server-port 25565
+	rcon status --bedrock
+
+`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		full, _ := cmd.Flags().GetBool("full")
+		target, _ := cmd.Flags().GetString("target")
+		ping, _ := cmd.Flags().GetBool("ping")
+		bedrock, _ := cmd.Flags().GetBool("bedrock")
+
+		server, err := serverAddress(target)
+		if err != nil {
+			return err
+		}
+		if ping || bedrock {
+			port, _ := cmd.Flags().GetInt("server-port")
+			if bedrock && !cmd.Flags().Changed("server-port") {
+				port = 19132
+			}
+			host, _, err := net.SplitHostPort(server.HostUri)
+			if err != nil {
+				return err
+			}
+			address := net.JoinHostPort(host, strconv.Itoa(port))
+			ok := false
+			if bedrock {
+				ok = cli.BedrockStatus(address, os.Stdout)
+			} else {
+				ok = cli.PingStatus(address, os.Stdout)
+			}
+			if !ok {
+				return errors.New("status unavailable")
+			}
+			return nil
+		}
+		if !cli.Status(server, os.Stdout, full) {
+			return errors.New("status unavailable")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().Bool("full", false, "include performance, time and difficulty")
+	statusCmd.Flags().Bool("ping", false, "use the Server List Ping instead of RCON")
+	statusCmd.Flags().Bool("bedrock", false, "ping a Bedrock server instead, on port 19132 unless --server-port is set")
+	statusCmd.Flags().Int("server-port", 25565, "server's game port, for --ping and --bedrock")
+	statusCmd.Flags().String("target", "", "server profile from the config file")
+}
@@ -0,0 +1,39 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/StarForger/neb-mc-rcon/tlsutil"
+	"github.com/spf13/cobra"
+)
+
+// Add the TLS flags shared by the serve modes
+func addTLSFlags(cmd *cobra.Command) {
+	cmd.Flags().String("tls-cert", "", "TLS certificate file")
+	cmd.Flags().String("tls-key", "", "TLS key file")
+	cmd.Flags().String("client-ca", "", "CA bundle verifying client certificates")
+	cmd.Flags().Bool("require-client-cert", false, "reject clients without a certificate")
+}
+
+// TLS settings from the flags added by addTLSFlags
+func tlsFlags(cmd *cobra.Command) tlsutil.Config {
+	cfg := tlsutil.Config{}
+	cfg.CertFile, _ = cmd.Flags().GetString("tls-cert")
+	cfg.KeyFile, _ = cmd.Flags().GetString("tls-key")
+	cfg.ClientCAFile, _ = cmd.Flags().GetString("client-ca")
+	cfg.RequireClientCert, _ = cmd.Flags().GetBool("require-client-cert")
+	return cfg
+}
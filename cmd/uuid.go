@@ -0,0 +1,91 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"github.com/StarForger/neb-mc-rcon/mojang"
+	"github.com/spf13/cobra"
+	homedir "github.com/mitchellh/go-homedir"
+	"os"
+	"path/filepath"
+)
+
+// uuidCmd represents the uuid command
+var uuidCmd = &cobra.Command{
+	Use:   "uuid <name|uuid> ...",
+	Short: "Resolve player names and UUIDs",
+	Long: `Resolve player names to UUIDs, and UUIDs to names, via the Mojang API.
+	Results are cached in $HOME/.rcon_mojang.json.
+	For example:
+
+	rcon uuid Notch jeb_
+
+`,
+	Args: cobra.MinimumNArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := mojangClient()
+		defer client.Save()
+
+		failed := false
+		for _, arg := range args {
+			p, err := client.Lookup(context.Background(), arg)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, arg+":", err)
+				failed = true
+				continue
+			}
+			fmt.Fprintln(os.Stdout, p.Name, p.UUID)
+		}
+		if failed {
+			return fmt.Errorf("some lookups failed")
+		}
+		return nil
+	},
+}
+
+// Mojang API client with the cache in $HOME/.rcon_mojang.json loaded, to be
+// saved when done
+func mojangClient() *mojang.Client {
+	client := mojang.NewClient()
+	if home, err := homedir.Dir(); err == nil {
+		client.CacheFile = filepath.Join(home, ".rcon_mojang.json")
+	}
+	if err := client.Load(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return client
+}
+
+// Resolver for sync files, when they list UUIDs or uuids is set
+func syncResolver(names []string, uuids bool) (mcapi.Resolver, func()) {
+	need := uuids
+	for _, name := range names {
+		need = need || mojang.IsUUID(name)
+	}
+	if !need {
+		return nil, func() {}
+	}
+	client := mojangClient()
+	return client, func() { client.Save() }
+}
+
+func init() {
+	rootCmd.AddCommand(uuidCmd)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2021 StarForger <sparkforger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/internal/cli"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// whitelistSyncCmd represents the whitelist-sync command, named apart from
+// the server's whitelist command which is sent as any other
+var whitelistSyncCmd = &cobra.Command{
+	Use:   "whitelist-sync <file>",
+	Short: "Make the server whitelist match a local file",
+	Long: `Diff a local whitelist (whitelist.json, or one name or UUID per line)
+	against the server's whitelist and apply only the necessary add/remove
+	commands. UUIDs are resolved via the Mojang API. For example:
+
+	rcon whitelist-sync whitelist.json
+	rcon whitelist-sync players.txt --dry-run
+
+`,
+	Args: cobra.ExactArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		uuids, _ := cmd.Flags().GetBool("uuids")
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		names, err := cli.ReadNames(file)
+		if err != nil {
+			return err
+		}
+
+		server, err := serverAddress("")
+		if err != nil {
+			return err
+		}
+		resolver, done := syncResolver(names, uuids)
+		defer done()
+		if !cli.WhitelistSync(server, os.Stdout, names, resolver, dryRun) {
+			return errors.New("whitelist sync incomplete")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whitelistSyncCmd)
+
+	whitelistSyncCmd.Flags().Bool("dry-run", false, "show the changes without applying them")
+	whitelistSyncCmd.Flags().Bool("uuids", false, "report players with their UUIDs, looked up via the Mojang API")
+}
@@ -0,0 +1,288 @@
+package conformance
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Protocol checks for RCON servers, so server and plugin authors can verify
+// their implementation works with this client.
+
+type Status string
+
+const (
+	Pass Status = "PASS"
+	Fail Status = "FAIL"
+	Skip Status = "SKIP"
+)
+
+type Config struct {
+	Address  string
+	Password string
+	Timeout  time.Duration // per check, defaults to 10s
+	// Command expected to produce a response larger than one packet
+	LargeCommand string
+	Logger       logging.Logger
+}
+
+type Result struct {
+	Name     string
+	Status   Status
+	Detail   string
+	Duration time.Duration
+}
+
+type Report struct {
+	Address string
+	Results []Result
+}
+
+type check struct {
+	name string
+	run  func(cfg Config) (Status, string)
+}
+
+var checks = []check{
+	{"auth-success", checkAuthSuccess},
+	{"auth-failure", checkAuthFailure},
+	{"max-size-payload", checkMaxPayload},
+	{"fragmentation", checkFragmentation},
+	{"invalid-type", checkInvalidType},
+	{"coalesced-packets", checkCoalesced},
+}
+
+// Run every check against the server
+func Run(cfg Config) *Report {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.LargeCommand == "" {
+		cfg.LargeCommand = "help"
+	}
+
+	log := logging.Or(cfg.Logger)
+
+	report := &Report{Address: cfg.Address}
+	for _, c := range checks {
+		log.Debug("conformance: running check", "check", c.name, "address", cfg.Address)
+		start := time.Now()
+		status, detail := c.run(cfg)
+		log.Info("conformance: check finished", "check", c.name, "status", status, "detail", detail)
+		report.Results = append(report.Results, Result{
+			Name:     c.name,
+			Status:   status,
+			Detail:   detail,
+			Duration: time.Since(start),
+		})
+	}
+	return report
+}
+
+// True when no check failed
+func (r *Report) Passed() bool {
+	for _, res := range r.Results {
+		if res.Status == Fail {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Report) Write(out io.Writer) {
+	fmt.Fprintf(out, "RCON conformance report for %s\n", r.Address)
+	for _, res := range r.Results {
+		fmt.Fprintf(out, "  %-4s  %-18s %8s  %s\n", res.Status, res.Name, res.Duration.Round(time.Millisecond), res.Detail)
+	}
+	if r.Passed() {
+		fmt.Fprintln(out, "result: compatible")
+	} else {
+		fmt.Fprintln(out, "result: NOT compatible")
+	}
+}
+
+func dial(cfg Config) (net.Conn, error) {
+	c, err := net.DialTimeout("tcp", cfg.Address, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c.SetDeadline(time.Now().Add(cfg.Timeout))
+	return c, nil
+}
+
+// Dial and authenticate with the configured password
+func login(cfg Config) (net.Conn, error) {
+	c, err := dial(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Write(encode(1, typeLogin, []byte(cfg.Password))); err != nil {
+		c.Close()
+		return nil, err
+	}
+	for {
+		// Some servers send an empty command response before the login response
+		p, err := readPacket(c)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		if p.typ != typeLoginResponse {
+			continue
+		}
+		if p.id != 1 {
+			c.Close()
+			return nil, fmt.Errorf("login rejected (id %d)", p.id)
+		}
+		return c, nil
+	}
+}
+
+func checkAuthSuccess(cfg Config) (Status, string) {
+	c, err := login(cfg)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	c.Close()
+	return Pass, "login response echoed the request id"
+}
+
+func checkAuthFailure(cfg Config) (Status, string) {
+	c, err := dial(cfg)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	defer c.Close()
+
+	if _, err := c.Write(encode(7, typeLogin, []byte(cfg.Password+"\x7fconformance"))); err != nil {
+		return Fail, err.Error()
+	}
+	for {
+		p, err := readPacket(c)
+		if err != nil {
+			return Fail, "no login response: " + err.Error()
+		}
+		if p.typ != typeLoginResponse {
+			continue
+		}
+		if p.id != -1 {
+			return Fail, fmt.Sprintf("wrong password answered with id %d, want -1", p.id)
+		}
+		return Pass, "wrong password answered with id -1"
+	}
+}
+
+func checkMaxPayload(cfg Config) (Status, string) {
+	c, err := login(cfg)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	defer c.Close()
+
+	// Unknown command, so nothing happens in game
+	cmd := "conformance-" + strings.Repeat("x", payloadRequestMax-len("conformance-"))
+	if _, err := c.Write(encode(10, typeCommand, []byte(cmd))); err != nil {
+		return Fail, err.Error()
+	}
+	p, err := readPacket(c)
+	if err != nil {
+		return Fail, fmt.Sprintf("no response to %d byte command: %s", len(cmd), err)
+	}
+	if p.id != 10 || p.typ != typeResponse {
+		return Fail, fmt.Sprintf("response id %d type %d, want id 10 type 0", p.id, p.typ)
+	}
+	return Pass, fmt.Sprintf("%d byte command answered", len(cmd))
+}
+
+func checkFragmentation(cfg Config) (Status, string) {
+	c, err := login(cfg)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	defer c.Close()
+
+	// Follow the command with an invalid type packet, whose response marks
+	// the end of the fragments
+	data := append(encode(20, typeCommand, []byte(cfg.LargeCommand)), encode(21, typeInvalid, nil)...)
+	if _, err := c.Write(data); err != nil {
+		return Fail, err.Error()
+	}
+
+	var payload bytes.Buffer
+	fragments := 0
+	for {
+		p, err := readPacket(c)
+		if err != nil {
+			return Fail, fmt.Sprintf("after %d fragments: %s", fragments, err)
+		}
+		if p.id == 21 {
+			break
+		}
+		if p.id != 20 || p.typ != typeResponse {
+			return Fail, fmt.Sprintf("fragment id %d type %d, want id 20 type 0", p.id, p.typ)
+		}
+		fragments++
+		payload.Write(p.payload)
+	}
+
+	if fragments < 2 {
+		return Skip, fmt.Sprintf("%q fit in one packet (%d bytes)", cfg.LargeCommand, payload.Len())
+	}
+	return Pass, fmt.Sprintf("%d bytes in %d fragments", payload.Len(), fragments)
+}
+
+func checkInvalidType(cfg Config) (Status, string) {
+	c, err := login(cfg)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	defer c.Close()
+
+	if _, err := c.Write(encode(30, typeInvalid, nil)); err != nil {
+		return Fail, err.Error()
+	}
+	p, err := readPacket(c)
+	if err != nil {
+		return Fail, "no response to invalid type: " + err.Error()
+	}
+	if p.id != 30 {
+		return Fail, fmt.Sprintf("response id %d, want 30", p.id)
+	}
+
+	// The connection must still be usable
+	if _, err := c.Write(encode(31, typeCommand, nil)); err != nil {
+		return Fail, err.Error()
+	}
+	if p, err := readPacket(c); err != nil || p.id != 31 {
+		return Fail, "connection unusable after invalid type"
+	}
+	return Pass, fmt.Sprintf("answered %q", p.payload)
+}
+
+func checkCoalesced(cfg Config) (Status, string) {
+	c, err := login(cfg)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	defer c.Close()
+
+	// Two requests in a single write
+	data := append(encode(40, typeCommand, nil), encode(41, typeCommand, nil)...)
+	if _, err := c.Write(data); err != nil {
+		return Fail, err.Error()
+	}
+	for _, want := range []int32{40, 41} {
+		p, err := readPacket(c)
+		if err != nil {
+			return Fail, fmt.Sprintf("waiting for response %d: %s", want, err)
+		}
+		if p.id != want {
+			return Fail, fmt.Sprintf("response id %d, want %d", p.id, want)
+		}
+	}
+	return Pass, "both requests answered in order"
+}
@@ -0,0 +1,53 @@
+package conformance
+
+import (
+	"context"
+	"github.com/StarForger/neb-mc-rcon/rconserver"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The repository's own server passes every check
+func TestRconserver(t *testing.T) {
+	s, err := rconserver.Listen("127.0.0.1:0", "password", func(ctx context.Context, cmd string) string {
+		if cmd == "help" {
+			return strings.Repeat("/help [page]\n", 1000)
+		}
+		return "Unknown or incomplete command"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	report := Run(Config{Address: s.Addr().String(), Password: "password", Timeout: 5 * time.Second})
+	for _, r := range report.Results {
+		if r.Status != Pass {
+			t.Errorf("%s: %s %s", r.Name, r.Status, r.Detail)
+		}
+	}
+	if len(report.Results) != len(checks) {
+		t.Errorf("%d results for %d checks", len(report.Results), len(checks))
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	s, err := rconserver.Listen("127.0.0.1:0", "password", func(ctx context.Context, cmd string) string {
+		return ""
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	report := Run(Config{Address: s.Addr().String(), Password: "wrong", Timeout: 5 * time.Second})
+	if report.Passed() {
+		t.Fatal("passed with the wrong password")
+	}
+	for _, r := range report.Results {
+		if r.Name == "auth-failure" && r.Status != Pass {
+			t.Errorf("auth-failure: %s %s", r.Status, r.Detail)
+		}
+	}
+}
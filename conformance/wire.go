@@ -0,0 +1,63 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Raw packet handling, kept independent of the conn package so the checks
+// exercise the wire format directly.
+
+const (
+	typeResponse       = 0
+	typeCommand        = 2
+	typeLoginResponse  = 2
+	typeLogin          = 3
+	typeInvalid        = 200
+	payloadRequestMax  = 1024
+	payloadResponseMax = 4096
+	lengthMin          = 10
+)
+
+type packet struct {
+	id      int32
+	typ     int32
+	payload []byte
+}
+
+func encode(id int32, typ int32, payload []byte) []byte {
+	b := bytes.NewBuffer(make([]byte, 0, len(payload)+14))
+	binary.Write(b, binary.LittleEndian, int32(len(payload)+lengthMin))
+	binary.Write(b, binary.LittleEndian, id)
+	binary.Write(b, binary.LittleEndian, typ)
+	b.Write(payload)
+	b.Write([]byte{0, 0})
+	return b.Bytes()
+}
+
+func readPacket(r io.Reader) (*packet, error) {
+	var length int32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	if length < lengthMin || length > payloadResponseMax+lengthMin {
+		return nil, fmt.Errorf("invalid packet length %d", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	p := &packet{
+		id:      int32(binary.LittleEndian.Uint32(body[0:])),
+		typ:     int32(binary.LittleEndian.Uint32(body[4:])),
+		payload: body[8 : length-2],
+	}
+	if body[length-2] != 0 || body[length-1] != 0 {
+		return p, fmt.Errorf("packet %d not terminated by two null bytes", p.id)
+	}
+	return p, nil
+}
@@ -0,0 +1,69 @@
+package conn
+
+import (
+	"context"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/packet"
+)
+
+// Command of a batch that failed, by its index
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("connection: command %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Execute commands in order, each as its own request. Every command is
+// checked against the request limit before any is sent, then all are sent
+// without waiting so the batch costs about one round trip. Responses are
+// by index, empty for commands that failed; the error is a *BatchError for
+// the first failure. Retries don't apply.
+func (c *Connection) ExecuteAll(ctx context.Context, cmds []string) ([]string, error) {
+	for i, cmd := range cmds {
+		if limit := c.protocol.requestMax(); len(cmd) > limit {
+			return nil, &BatchError{i, &LengthError{len(cmd), limit}}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, classify(err)
+	}
+
+	calls := make([]*call, len(cmds))
+	responses := make([]string, len(cmds))
+	var first error
+	fail := func(i int, err error) {
+		if err != nil && first == nil {
+			first = &BatchError{i, err}
+		}
+	}
+
+	start := c.clock.Now()
+	for i, cmd := range cmds {
+		call, err := c.start(packet.TypeCommandRequest, cmd)
+		if err != nil {
+			fail(i, c.observe(nil, cmd, start, exchange{}, err))
+			break
+		}
+		calls[i] = call
+	}
+	for i, call := range calls {
+		if call == nil {
+			break
+		}
+		var span Span
+		if c.tracer != nil {
+			span = traceExecute(ctx, c.tracer, c.hostUri, cmds[i])
+		}
+		response, t, err := c.wait(ctx, call)
+		responses[i] = response
+		fail(i, c.observe(span, cmds[i], start, t, err))
+	}
+	return responses, first
+}
@@ -0,0 +1,57 @@
+package conn_test
+
+import (
+	"context"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/rconserver"
+	"net"
+	"testing"
+)
+
+// Allocations of a command and its response, the server's included, with
+// a little room for the runtime. Measured at 6.
+const maxExecuteAllocs = 8
+
+// Connection logged in to an rconserver answering "list", over net.Pipe
+func pipeConnection(tb testing.TB) *conn.Connection {
+	tb.Helper()
+	s := rconserver.NewServer("password", func(ctx context.Context, cmd string) string {
+		return "There are 2 of a max of 20 players online: Steve, Alex"
+	})
+	tb.Cleanup(func() { s.Close() })
+
+	dial := func(ctx context.Context, network string, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go s.ServeConn(server)
+		return client, nil
+	}
+	c, err := conn.Dial("pipe:25575", "password", conn.WithDialFunc(dial))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { c.Close() })
+	return c
+}
+
+func BenchmarkExecute(b *testing.B) {
+	c := pipeConnection(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Execute("list"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestExecuteAllocs(t *testing.T) {
+	c := pipeConnection(t)
+	allocs := testing.AllocsPerRun(200, func() {
+		if _, err := c.Execute("list"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > maxExecuteAllocs {
+		t.Errorf("%.1f allocations per command, want at most %d", allocs, maxExecuteAllocs)
+	}
+}
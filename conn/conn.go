@@ -0,0 +1,28 @@
+package conn
+
+import (
+	"context"
+)
+
+// Client is the least every transport offers, running commands and
+// closing. It is implemented by Connection, webrcon.Connection and Pool,
+// and is the interface to write against, and mock, for code that only
+// sends commands.
+type Client interface {
+	ExecuteContext(ctx context.Context, cmd string) (string, error)
+	Close() error
+}
+
+// Conn is the transport independent view of an RCON session, a Client
+// that can also be pinged. Code that only sends commands should depend on
+// Client rather than *Connection.
+type Conn interface {
+	Client
+	Execute(cmd string) (string, error)
+	Ping() error
+}
+
+var (
+	_ Conn   = (*Connection)(nil)
+	_ Client = (*Pool)(nil)
+)
@@ -0,0 +1,234 @@
+package conn_test
+
+import (
+	"context"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/packet"
+	"github.com/StarForger/neb-mc-rcon/rconserver"
+	"github.com/StarForger/neb-mc-rcon/rcontest"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Request read by a scripted server
+type request struct {
+	id   int32
+	typ  packet.PacketType
+	body string
+}
+
+// Connect to a server that accepts the login and hands the connection to
+// script, which reads requests with next and writes raw packets to c
+func scripted(t *testing.T, script func(c net.Conn, next func() (request, error)), opts ...conn.Option) *conn.Connection {
+	t.Helper()
+	var wg sync.WaitGroup
+	dial := func(ctx context.Context, network string, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		t.Cleanup(func() { server.Close() })
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d := packet.NewRequestDecoder(server)
+			next := func() (request, error) {
+				p, err := d.Decode()
+				if err != nil {
+					return request{}, err
+				}
+				return request{p.GetId(), p.GetType(), p.GetPayload()}, nil
+			}
+			login, err := next()
+			if err != nil {
+				return
+			}
+			if _, err := server.Write(packet.Append(nil, login.id, packet.TypeLoginResponse, "")); err != nil {
+				return
+			}
+			script(server, next)
+		}()
+		return client, nil
+	}
+	// Cleanups run last first, so the server is closed before the wait
+	t.Cleanup(wg.Wait)
+
+	c, err := conn.Dial("scripted:25575", "password", append([]conn.Option{conn.WithDialFunc(dial)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// Run each command at once, returning the responses in the same order
+func executeAll(t *testing.T, c *conn.Connection, cmds ...string) []string {
+	t.Helper()
+	responses := make([]string, len(cmds))
+	errs := make([]error, len(cmds))
+	var wg sync.WaitGroup
+	for i, cmd := range cmds {
+		wg.Add(1)
+		go func(i int, cmd string) {
+			defer wg.Done()
+			responses[i], errs[i] = c.Execute(cmd)
+		}(i, cmd)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("%s: %v", cmds[i], err)
+		}
+	}
+	return responses
+}
+
+// However the server orders and splits its responses, each reaches the
+// command that asked for it
+func TestMultiplex(t *testing.T) {
+	cmds := []string{"one", "two", "three"}
+	tests := []struct {
+		name  string
+		write func(c net.Conn, responses [][]byte) error
+	}{
+		{"in order", func(c net.Conn, responses [][]byte) error {
+			for _, r := range responses {
+				if _, err := c.Write(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"reversed", func(c net.Conn, responses [][]byte) error {
+			for i := len(responses) - 1; i >= 0; i-- {
+				if _, err := c.Write(responses[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"coalesced", func(c net.Conn, responses [][]byte) error {
+			var all []byte
+			for i := len(responses) - 1; i >= 0; i-- {
+				all = append(all, responses[i]...)
+			}
+			_, err := c.Write(all)
+			return err
+		}},
+		{"split", func(c net.Conn, responses [][]byte) error {
+			var all []byte
+			for _, r := range responses {
+				all = append(all, r...)
+			}
+			// Packets straddle writes, headers included
+			for len(all) > 0 {
+				n := 5
+				if n > len(all) {
+					n = len(all)
+				}
+				if _, err := c.Write(all[:n]); err != nil {
+					return err
+				}
+				all = all[n:]
+			}
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := scripted(t, func(c net.Conn, next func() (request, error)) {
+				// Answer once every command is in flight
+				var responses [][]byte
+				for range cmds {
+					r, err := next()
+					if err != nil {
+						return
+					}
+					responses = append(responses, packet.Append(nil, r.id, packet.TypeCommandResponse, "ran "+r.body))
+				}
+				tt.write(c, responses)
+			})
+
+			responses := executeAll(t, c, cmds...)
+			for i, cmd := range cmds {
+				if responses[i] != "ran "+cmd {
+					t.Errorf("%s: got %q", cmd, responses[i])
+				}
+			}
+		})
+	}
+}
+
+// Responses filling whole packets are followed by a sentinel request, and
+// the fragments up to its answer make the response
+func TestFragmentedResponse(t *testing.T) {
+	for _, size := range []int{
+		packet.PayloadResponseMax - 1,
+		packet.PayloadResponseMax,
+		packet.PayloadResponseMax + 1,
+		3 * packet.PayloadResponseMax,
+		10000,
+	} {
+		response := strings.Repeat("a", size-1) + "z"
+		server := rcontest.NewServer(t, rcontest.Options{
+			Responses: map[string]string{"help": response, "list": "There are 0 of a max of 20 players online: "},
+		})
+		c := server.Dial()
+
+		got, err := c.Execute("help")
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if got != response {
+			t.Fatalf("%d bytes: got %d bytes", size, len(got))
+		}
+		// The sentinel's answer isn't mistaken for the next response
+		if got, err := c.Execute("list"); err != nil || got != "There are 0 of a max of 20 players online: " {
+			t.Fatalf("%d bytes: next command got %q, %v", size, got, err)
+		}
+	}
+}
+
+// rconserver splits responses between runes, a few bytes short of full
+// packets
+func TestFragmentedRunes(t *testing.T) {
+	response := "x" + strings.Repeat("é", packet.PayloadResponseMax)
+	s := rconserver.NewServer("password", func(ctx context.Context, cmd string) string {
+		return response
+	})
+	defer s.Close()
+	dial := func(ctx context.Context, network string, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go s.ServeConn(server)
+		return client, nil
+	}
+	c, err := conn.Dial("pipe:25575", "password", conn.WithDialFunc(dial))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if got, err := c.Execute("help"); err != nil || got != response {
+		t.Fatalf("got %d bytes, %v", len(got), err)
+	}
+}
+
+func TestFragmentedConcurrent(t *testing.T) {
+	long := strings.Repeat("x", 2*packet.PayloadResponseMax+10)
+	server := rcontest.NewServer(t, rcontest.Options{
+		Handler: func(cmd string) string {
+			if cmd == "long" {
+				return long
+			}
+			return "ran " + cmd
+		},
+	})
+	c := server.Dial()
+
+	responses := executeAll(t, c, "long", "short", "long", "other")
+	want := []string{long, "ran short", long, "ran other"}
+	for i := range want {
+		if responses[i] != want[i] {
+			t.Errorf("response %d: got %d bytes, want %d", i, len(responses[i]), len(want[i]))
+		}
+	}
+}
@@ -1,115 +1,305 @@
 package conn
 
 import (	
+	"context"						// cancellation and deadlines
 	"errors"						// manipulate errors	
+	"github.com/StarForger/neb-mc-rcon/clock"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"github.com/StarForger/neb-mc-rcon/packet"
 	"net"								// interface for network I/O
+	"strings"						// host URI schemes
 	"sync"							// basic synchronization primitives such as mutual exclusion locks
+	"sync/atomic"				// atomic counters
 	"time"							// for measuring and displaying time
 	// "log"
 )
 
+// Defaults, see WithConnectTimeout and WithReadTimeout
 const (
 	connTimeout = 10 * time.Second
 	readTimeout = 1 * time.Minute
 )
 
+// Host URI prefix of a Unix domain socket path, unix:///run/rcon.sock
+const unixScheme = "unix://"
+
 type Connection struct {
+	stats			counters // first, for 64 bit alignment
 	id				int32
 	conn      net.Conn	
-	buffer   	[]byte	
-	queue 		[]byte
-	lock    	sync.Mutex		
+	nextId		int32 // last request id handed out
+	decoder		*packet.Decoder
+	counter		counter
+	encoder		*packet.Encoder // guarded by writeLock
+	writeLock	sync.Mutex
+	lock    	sync.Mutex // guards pending, failed and closing
+	pending		map[int32]*call // by request id
+	calls			sync.Pool
+	extra			chan *Packet // packets nobody was waiting for, see ReadPacket
+	failed		error // why the reader stopped
+	closing		bool
+	done			chan struct{} // closed by Close
+	log				logging.Logger
+	clock			clock.Clock
+	debug			bool // skip building log arguments nobody reads
+	metrics		MetricsHook // nil for none
+	tracer		Tracer // nil for none
+	retry			RetryPolicy
+	splitLines	bool
+	hostUri		string
+	connected	time.Time
+	authenticated	int32 // atomic, 1 once logged in
+	hexDump		bool
+	maxResponse	int // payload bytes
+	validation	packet.Validation // of command responses
+	protocol	Protocol
+	trailers	map[int32]struct{} // second packets of Source terminators still to come, guarded by lock
+	readTimeout	time.Duration
+	writeTimeout	time.Duration
 }
 
 var ( 	
-	ErrorResponseMismatch = errors.New("connection: response type mismatch")		
+	ErrorResponseMismatch = errors.New("connection: response type mismatch")
+	ErrorResponseTooLarge = errors.New("connection: response larger than the maximum size")		
 )
 
-func Dial(hostUri string, password string) (*Connection, error) {	
-	c, err := connect(hostUri)
+func Dial(hostUri string, password string, opts ...Option) (*Connection, error) {	
+	return DialContext(context.Background(), hostUri, password, opts...)
+}
+
+// Dial and log in, giving up when ctx is done
+func DialContext(ctx context.Context, hostUri string, password string, opts ...Option) (*Connection, error) {
+	o := newOptions(opts)
+
+	var span Span
+	if o.tracer != nil {
+		span = traceDial(ctx, o.tracer, hostUri)
+	}
+	start := o.clock.Now()
+	c, err := dial(ctx, hostUri, password, o)
+	if span != nil {
+		endSpan(span, err)
+	}
+	if o.metrics != nil {
+		o.metrics.Login(LoginMetrics{hostUri, o.clock.Since(start), err})
+	}
+	return c, err
+}
+
+func dial(ctx context.Context, hostUri string, password string, o options) (*Connection, error) {
+	o.logger.Debug("rcon: dialing", "address", hostUri)
+	c, err := connect(ctx, hostUri, o)
 	if err != nil {
-		return nil, err
+		o.logger.Debug("rcon: dial failed", "address", hostUri, "error", err)
+		return nil, classify(err)
 	}
 
+	stop := c.watch(ctx)
 	loginPacket, err := c.login(password)
+	c.release()
+	if stop() && err != nil {
+		err = err.(*AuthError).fail(ctx.Err())
+	}
 	if err != nil {
-		return nil, err
+		c.log.Debug("rcon: login failed", "address", hostUri, "error", err)
+		c.conn.Close()
+		return nil, classify(err)
 	}
 
 	c.id = loginPacket.GetId()
+	c.nextId = c.id
+	atomic.StoreInt32(&c.authenticated, 1)
+	c.conn.SetReadDeadline(time.Time{}) // the reader waits indefinitely, requests time out instead
+	go c.readLoop()
+	if o.heartbeat > 0 {
+		go c.heartbeat(o.heartbeat, o.onHeartbeat)
+	}
+	c.log.Info("rcon: authenticated", "address", hostUri, "id", c.id)
 
 	return c, nil
 }
 
+// Execute a command. Safe for concurrent use, commands are sent as they
+// are made and each response is matched to its request by id.
 func (c *Connection) Execute(cmd string) (string, error) {	
-	request, err := CreateCommandRequest(c.id, cmd)
-	if err != nil {
-		return "", err
-	}	
+	return c.ExecuteContext(context.Background(), cmd)
+}
 
-	_, err = c.conn.Write(request.GetEncoded())
-	if err != nil {
-		return "", err
+// Execute command, giving up on the response when ctx is done. A deadline
+// on ctx replaces the connection's read timeout. A response arriving after
+// that is left for ReadPacket.
+func (c *Connection) ExecuteContext(ctx context.Context, cmd string) (string, error) {
+	if c.splitLines && strings.Contains(cmd, "\n") {
+		responses, err := c.ExecuteAll(ctx, strings.Split(cmd, "\n"))
+		return strings.Join(responses, "\n"), err
 	}
 
-	data, err := c.read()
-	if err != nil {
-		return "", err
+	var span Span
+	if c.tracer != nil {
+		span = traceExecute(ctx, c.tracer, c.hostUri, cmd)
 	}
+	start := c.clock.Now()
+	response, t, err := c.executeRetry(ctx, cmd)
+	return response, c.observe(span, cmd, start, t, err)
+}
 
-	response, err := CreateCommandResponse(data)
+// Execute command, allowing it d instead of the connection's read timeout,
+// for slow commands such as save-all flush
+func (c *Connection) ExecuteTimeout(cmd string, d time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return c.ExecuteContext(ctx, cmd)
+}
+
+// Record a finished command in the stats, metrics and trace
+func (c *Connection) observe(span Span, cmd string, start time.Time, t exchange, err error) error {
+	latency := c.clock.Since(start)
+	err = classify(err)
+	if span != nil {
+		span.SetAttribute(attrRequestId, t.id)
+		span.SetAttribute(attrRequestSize, t.sent)
+		span.SetAttribute(attrResponseSize, t.received)
+		endSpan(span, err)
+	}
+	c.stats.command(latency, err)
+	if c.metrics != nil {
+		c.metrics.Command(CommandMetrics{cmd, latency, t.sent, t.received, err})
+	}
+	return err
+}
+
+func (c *Connection) execute(ctx context.Context, cmd string) (string, exchange, error) {
+	if limit := c.protocol.requestMax(); len(cmd) > limit {
+		return "", exchange{}, &LengthError{len(cmd), limit}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", exchange{}, err
+	}
+
+	var start time.Time
+	if c.debug {
+		c.log.Debug("rcon: execute", "command", cmd)
+		start = c.clock.Now()
+	}
+
+	payload, t, err := c.roundTrip(ctx, packet.TypeCommandRequest, cmd)
 	if err != nil {
-		return "", err
-	}	
+		if c.debug {
+			c.log.Debug("rcon: execute failed", "id", t.id, "duration", c.clock.Since(start), "error", err)
+		}
+		return "", t, err
+	}
 
-	name, _ := response.GetMetadata()
+	if c.debug {
+		c.log.Debug("rcon: response", "id", t.id, "bytes", len(payload), "duration", c.clock.Since(start))
+	}
 
-	if name != "command" || response.GetMethod() != "response" {
-		return "", ErrorResponseMismatch
+	return payload, t, nil	
+}
+
+// Abort pending reads and writes when ctx is done, without a goroutine for
+// contexts that can't end. The returned stop must be called once the
+// exchange is over, and reports whether ctx ended.
+func (c *Connection) watch(ctx context.Context) (stop func() bool) {
+	if ctx.Done() == nil {
+		return func() bool { return false }
 	}
 
-	c.queue = data[response.GetLength() + 4:] // include length
-	c.id = response.GetId()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			c.conn.SetDeadline(time.Now()) // unblock pending write/read
+		case <-done:
+		}
+	}()
 
-	return response.GetPayload(), nil	
-}	
+	return func() bool {
+		close(done)
+		<-stopped
+		if ctx.Err() == nil {
+			return false
+		}
+		c.conn.SetDeadline(time.Time{})
+		return true
+	}
+}
+
+// Round trip an empty command to check the connection is alive. Servers
+// answer it without running anything players see, and the answer only
+// counts when it carries the request's id. Retries don't apply.
+func (c *Connection) Ping() (error) {
+	start := c.clock.Now()
+	_, t, err := c.roundTrip(context.Background(), packet.TypeCommandRequest, "")
+	return c.observe(nil, "", start, t, err)
+}
+
+// When a packet was last sent or received
+func (c *Connection) LastActivity() time.Time {
+	sent := atomic.LoadInt64(&c.stats.lastSent)
+	if received := atomic.LoadInt64(&c.stats.lastReceived); received > sent {
+		return time.Unix(0, received)
+	}
+	return time.Unix(0, sent)
+}
 
 func (c *Connection) Close() (error) {
+	c.log.Debug("rcon: closing", "address", c.conn.RemoteAddr())
+	c.lock.Lock()
+	if !c.closing {
+		c.closing = true
+		close(c.done)
+	}
+	c.lock.Unlock()
 	return c.conn.Close()
 }
 
+// Log in, before the reader is started. Failures are an *AuthError.
 func (c *Connection) login(password string) (*Packet, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	auth := &AuthError{}
 
-	loginRequest, err := CreateLoginRequest(password)
+	loginRequest, err := packet.CreateRequest(0, packet.TypeLoginRequest, password, c.clock.Now())
 	if err != nil {
-		return nil, err
+		return nil, auth.fail(err)
 	}	
 
-	_, err = c.conn.Write(loginRequest.GetEncoded())
+	_, err = loginRequest.WriteTo(writer{c})
 	if err != nil {
-		return nil, err
+		return nil, auth.fail(err)
 	}
 
-	loginResponse, err := c.loginReadAttempt()
-	// Retry authentication once (RCON bug)	
-	if err == ErrorResponseMismatch {
-		loginResponse, err = c.loginReadAttempt()
+	loginResponse, err := c.loginReadAttempt(auth)
+	// Retry authentication once (RCON bug), which Factorio doesn't have
+	if err == ErrorResponseMismatch && c.protocol != ProtocolFactorio {
+		c.log.Warn("rcon: unexpected login response, retrying read")
+		atomic.AddInt64(&c.stats.authRetries, 1)
+		auth.Retried = true
+		loginResponse, err = c.loginReadAttempt(auth)
 	}
 	if err != nil {
-		return nil, err
+		return nil, auth.fail(err)
 	}
 
 	return loginResponse, nil
 }
 
-func (c *Connection) loginReadAttempt() (*Packet, error) {	
+func (c *Connection) loginReadAttempt(auth *AuthError) (*Packet, error) {	
+	auth.Attempts++
 
-	data, err := c.read()
+	loginResponse, err := c.read()
 	if err != nil {
 		return nil, err
 	}
+	auth.Response = loginResponse.GetPayload()
 
-	loginResponse, err := CreateLoginResponse(data)
+	err = loginResponse.Verify(packet.TypeLoginResponse)
+	// Some servers send an empty command response ahead of the login one
+	if err == ErrorMismatchType {
+		return nil, ErrorResponseMismatch
+	}
 	if err != nil {
 		return nil, err
 	}	
@@ -123,44 +313,67 @@ func (c *Connection) loginReadAttempt() (*Packet, error) {
 	return loginResponse, nil
 }
 
-func (c *Connection) read() ([]byte, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
-	c.conn.SetReadDeadline(time.Now().Add(readTimeout))
-	var size int
-	var err error
-	if c.queue != nil {
-		copy(c.buffer, c.queue)
-		size = len(c.queue)
-		c.queue = nil
-	} else {
-		size, err = c.conn.Read(c.buffer)
-		if err != nil {
-			return nil, err
-		}
-	}		
+func (c *Connection) write(data []byte) (int, error) {
+	if c.writeTimeout > 0 {
+		// Socket deadlines are by the system clock, not the connection's
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	n, err := c.conn.Write(data)
+	atomic.AddInt64(&c.stats.bytesSent, int64(n))
+	c.logPacket("rcon: packet sent", data[:n])
+	return n, err
+}
+
+func connect(ctx context.Context, hostUri string, o options) (*Connection, error)  {	
+	network, address := "tcp", hostUri
+	if strings.HasPrefix(hostUri, unixScheme) {
+		network, address = "unix", strings.TrimPrefix(hostUri, unixScheme)
+	}
 
-	if size < 4 {		
-		s, err := c.conn.Read(c.buffer[size:])
+	dial := o.dial
+	if dial == nil {
+		dial = o.dialer.DialContext
+	}
+	if o.proxy != nil && network == "tcp" {
+		proxy, err := proxyFor(*o.proxy, hostUri)
 		if err != nil {
 			return nil, err
 		}
-		size += s
-	}	
-
-	return c.buffer[:size], nil
-}
-
-func connect(hostUri string) (*Connection, error)  {	
-	conn, err := net.DialTimeout("tcp", hostUri, connTimeout)
+		if proxy != nil {
+			dial = proxyDial(dial, proxy)
+		}
+	}
+	conn, err := dial(ctx, network, address)
 	if err != nil {
 		return nil, err
 	}
 	c := &Connection{
 		conn: conn,
-		buffer: make([]byte, SizeMax),
+		log: o.logger,
+		clock: o.clock,
+		debug: o.logger != logging.Nop,
+		hexDump: o.hexDump,
+		metrics: o.metrics,
+		tracer: o.tracer,
+		retry: o.retry,
+		splitLines: o.splitLines,
+		validation: o.protocol.validation(o.validation),
+		protocol: o.protocol,
+		hostUri: hostUri,
+		connected: o.clock.Now(),
+		maxResponse: o.protocol.responseMax(o.maxResponse),
+		readTimeout: o.readTimeout,
+		writeTimeout: o.writeTimeout,
+		pending: map[int32]*call{},
+		trailers: map[int32]struct{}{},
+		extra: make(chan *Packet, 16),
+		done: make(chan struct{}),
 	}
+	c.counter.c = c
+	c.decoder = packet.NewDecoder(c.counter)
+	c.decoder.MaxPayload = c.maxResponse
+	c.encoder = packet.NewEncoder(writer{c})
+	c.calls.New = c.newCall
 	return c, nil
 }
 
@@ -0,0 +1,13 @@
+// Package conn is the client library for the Minecraft RCON protocol.
+//
+//	c, err := conn.Dial("localhost:25575", "password")
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	response, err := c.Execute("list")
+//
+// The import path github.com/StarForger/neb-mc-rcon/conn is the stable public
+// API of this module; the command line tool's internals live under internal/.
+package conn
@@ -0,0 +1,112 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Kinds of failure, matched with errors.Is. The errors returned wrap the
+// underlying cause, which errors.Is and errors.As also find.
+var (
+	ErrorAuthFailed      = errors.New("connection: authentication failed")
+	ErrorTimeout         = errors.New("connection: timed out")
+	ErrorClosed          = errors.New("connection: closed")
+	ErrorPayloadTooLarge = errors.New("connection: payload too large")
+)
+
+// Error of a kind, with its cause
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+func wrap(kind error, err error) error {
+	return &kindError{kind: kind, err: err}
+}
+
+// Wrap err with its kind when it has one, otherwise return it as is. A
+// failure to dial isn't a closed connection.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var kind *kindError
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case err == ErrorClosed, errors.As(err, &kind):
+		return err
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return wrap(ErrorTimeout, err)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &opErr) && opErr.Op != "dial":
+		return wrap(ErrorClosed, err)
+	case err == ErrorInvalidId:
+		return wrap(ErrorAuthFailed, err)
+	case err == ErrorMaxLength, err == ErrorResponseTooLarge:
+		return wrap(ErrorPayloadTooLarge, err)
+	}
+	return err
+}
+
+// Failed login. Err wraps ErrorInvalidId, and so matches ErrorAuthFailed,
+// when the password was rejected. Otherwise the server didn't answer, it
+// may still be starting: Err is then a timeout, the connection closing or
+// an unexpected response.
+type AuthError struct {
+	Response string // payload of the last login response read
+	Attempts int    // login responses read, or tried to
+	Retried  bool   // whether a second response was read after a mismatch
+	Err      error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("connection: login failed after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// Whether the server rejected the password
+func (e *AuthError) BadPassword() bool {
+	return errors.Is(e.Err, ErrorInvalidId)
+}
+
+func (e *AuthError) fail(err error) *AuthError {
+	e.Err = classify(err)
+	return e
+}
+
+// Command longer than a request allows, matching ErrorPayloadTooLarge and
+// ErrorMaxLength
+type LengthError struct {
+	Length int // bytes
+	Limit  int
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("connection: command of %d bytes exceeds the %d byte request limit", e.Length, e.Limit)
+}
+
+func (e *LengthError) Unwrap() error {
+	return ErrorMaxLength
+}
+
+func (e *LengthError) Is(target error) bool {
+	return target == ErrorPayloadTooLarge
+}
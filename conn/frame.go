@@ -0,0 +1,68 @@
+package conn
+
+import (
+	"encoding/binary"
+	"github.com/StarForger/neb-mc-rcon/packet"
+	"sync/atomic"
+)
+
+// Counts bytes read from the socket
+type counter struct {
+	c *Connection
+}
+
+func (r counter) Read(p []byte) (int, error) {
+	n, err := r.c.conn.Read(p)
+	atomic.AddInt64(&r.c.stats.bytesReceived, int64(n))
+	return n, err
+}
+
+// Writes to the socket through Connection.write
+type writer struct {
+	c *Connection
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	return w.c.write(p)
+}
+
+// Read the next packet, valid until the next read or release. Reads are
+// made by one goroutine at a time, login and then the connection's reader.
+//
+// Packets larger than the maximum response size are skipped, returning
+// ErrorResponseTooLarge with the packet's length and request id.
+func (c *Connection) read() (*Packet, error) {
+	p, err := c.decoder.Decode()
+	if err == ErrorMaxLength {
+		return p, ErrorResponseTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.logPacket("rcon: packet received", p.GetEncoded())
+	return p, nil
+}
+
+// Return the buffer of the last packet read to the pool
+func (c *Connection) release() {
+	c.decoder.Release()
+}
+
+// Log a packet sent or received, with a hex dump when enabled. The payload
+// of login requests, the password, is never dumped.
+func (c *Connection) logPacket(msg string, data []byte) {
+	if !c.debug || len(data) < 12 {
+		return
+	}
+	id := int32(binary.LittleEndian.Uint32(data[4:]))
+	code := int32(binary.LittleEndian.Uint32(data[8:]))
+	if !c.hexDump {
+		c.log.Debug(msg, "id", id, "type", code, "bytes", len(data))
+		return
+	}
+	p := packet.DecodeResponse(data)
+	if msg == "rcon: packet sent" {
+		p = packet.DecodeRequest(data)
+	}
+	c.log.Debug(msg, "id", id, "type", code, "bytes", len(data), "hex", p.Dump())
+}
@@ -0,0 +1,42 @@
+package conn
+
+import (
+	"time"
+)
+
+// Ping whenever nothing has been sent or received for an interval, until
+// the connection is closed or fails
+func (c *Connection) heartbeat(interval time.Duration, failed func(error)) {
+	ticker := c.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C():
+		}
+
+		if c.clock.Since(c.LastActivity()) < interval {
+			continue
+		}
+		err := c.Ping()
+		if err == nil {
+			continue
+		}
+
+		c.log.Warn("rcon: heartbeat failed", "address", c.conn.RemoteAddr(), "error", err)
+		c.lock.Lock()
+		stopped := c.failed != nil
+		c.lock.Unlock()
+		if stopped && err == ErrorClosed {
+			return
+		}
+		if failed != nil {
+			failed(err)
+		}
+		if stopped {
+			return
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package conn
+
+import (
+	"context"
+	"github.com/StarForger/neb-mc-rcon/clock"
+	"github.com/StarForger/neb-mc-rcon/packet"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// Caller waiting for the response to one request
+type call struct {
+	id       int32
+	sentinel int32 // request marking the end of a fragmented response, once sent
+	result   string
+	payload  strings.Builder // fragments so far
+	err      error
+	exchange exchange
+	done     chan struct{}
+	timer    clock.Timer
+}
+
+func (c *Connection) newCall() interface{} {
+	t := c.clock.NewTimer(time.Hour)
+	t.Stop()
+	return &call{done: make(chan struct{}, 1), timer: t}
+}
+
+// Next request id, positive and unique among requests in flight
+func (c *Connection) newId() int32 {
+	for {
+		if id := atomic.AddInt32(&c.nextId, 1) & 0x7fffffff; id > 0 {
+			return id
+		}
+	}
+}
+
+// Request id and bytes of the packets exchanged for one command
+type exchange struct {
+	id       int32
+	sent     int
+	received int
+}
+
+// Send a request and wait for its response, giving up after the read
+// timeout or when ctx is done. Any number of goroutines may call this, each
+// response is matched to its request by id.
+func (c *Connection) roundTrip(ctx context.Context, code packet.PacketType, body string) (string, exchange, error) {
+	call, err := c.start(code, body)
+	if err != nil {
+		return "", exchange{}, err
+	}
+	return c.wait(ctx, call)
+}
+
+// Register a call and send its request
+func (c *Connection) start(code packet.PacketType, body string) (*call, error) {
+	call := c.calls.Get().(*call)
+	call.id = c.newId()
+
+	c.lock.Lock()
+	if c.failed != nil {
+		err := c.failed
+		c.lock.Unlock()
+		c.calls.Put(call)
+		return nil, err
+	}
+	c.pending[call.id] = call
+	call.exchange.id = call.id
+	call.exchange.sent = 4 + packet.LengthMin + len(body)
+	if c.protocol == ProtocolSource {
+		call.sentinel = c.newId()
+		call.exchange.sent += 4 + packet.LengthMin
+		c.pending[call.sentinel] = call
+	}
+	c.lock.Unlock()
+
+	err := c.send(call.id, code, body)
+	if err == nil && c.protocol == ProtocolSource {
+		err = c.send(call.sentinel, packet.TypeCommandResponse, "")
+	}
+	if err != nil {
+		c.unregister(call)
+		c.reset(call)
+		return nil, err
+	}
+	return call, nil
+}
+
+// Wait for the response to a started call, giving up when ctx is done or,
+// unless ctx has a deadline of its own, after the read timeout
+func (c *Connection) wait(ctx context.Context, call *call) (string, exchange, error) {
+	var expired <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok {
+		call.timer.Reset(c.readTimeout)
+		expired = call.timer.C()
+	}
+	select {
+	case <-call.done:
+	case <-expired:
+		if c.unregister(call) {
+			call.err = os.ErrDeadlineExceeded
+		} else {
+			<-call.done
+		}
+	case <-ctx.Done():
+		if c.unregister(call) {
+			call.err = ctx.Err()
+		} else {
+			<-call.done
+		}
+	}
+	if expired != nil && !call.timer.Stop() {
+		select {
+		case <-call.timer.C():
+		default:
+		}
+	}
+
+	result, t, err := call.result, call.exchange, call.err
+	c.reset(call)
+	return result, t, err
+}
+
+// Return a finished call to the pool
+func (c *Connection) reset(call *call) {
+	call.result, call.err, call.sentinel, call.exchange = "", nil, 0, exchange{}
+	call.payload.Reset()
+	c.calls.Put(call)
+}
+
+// Stop waiting for call, false if its response already arrived
+func (c *Connection) unregister(call *call) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.pending[call.id] != call {
+		return false
+	}
+	delete(c.pending, call.id)
+	if call.sentinel != 0 {
+		delete(c.pending, call.sentinel)
+	}
+	return true
+}
+
+// Complete call, with c.lock held
+func (c *Connection) finish(call *call, err error) {
+	delete(c.pending, call.id)
+	if call.sentinel != 0 {
+		delete(c.pending, call.sentinel)
+	}
+	call.err = err
+	call.done <- struct{}{}
+}
+
+func (c *Connection) send(id int32, code packet.PacketType, body string) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	atomic.StoreInt64(&c.stats.lastSent, c.clock.Now().UnixNano())
+	p := packet.NewRequest(id, code, body)
+	return c.encoder.Encode(&p)
+}
+
+// Hand each packet to the call waiting for it until the connection fails
+func (c *Connection) readLoop() {
+	for {
+		p, err := c.read()
+		if err == nil || err == ErrorResponseTooLarge {
+			atomic.StoreInt64(&c.stats.lastReceived, c.clock.Now().UnixNano())
+		}
+		if err == ErrorResponseTooLarge {
+			c.dispatch(p, err)
+			continue
+		}
+		if err != nil {
+			c.release()
+			c.stop(err)
+			return
+		}
+
+		err = p.VerifyWith(packet.TypeCommandResponse, c.maxResponse, c.validation)
+		c.dispatch(p, err)
+	}
+}
+
+func (c *Connection) dispatch(p *Packet, err error) {
+	id := p.GetId()
+
+	c.lock.Lock()
+	call := c.pending[id]
+	switch {
+	case call == nil:
+		if _, ok := c.trailers[id]; ok {
+			delete(c.trailers, id)
+			c.lock.Unlock()
+			return
+		}
+		c.lock.Unlock()
+		c.unsolicited(p)
+		return
+	}
+
+	call.exchange.received += 4 + int(p.GetLength())
+	switch {
+	case err != nil:
+		c.finish(call, err)
+
+	case call.sentinel != 0 && id == call.sentinel:
+		call.result = call.payload.String()
+		if c.protocol == ProtocolSource {
+			c.expectTrailer(id)
+		}
+		c.finish(call, nil)
+	case call.sentinel != 0:
+		call.payload.WriteString(p.GetPayload())
+
+	// A full packet may be the first fragment of a longer response, one a
+	// few bytes short too when the server splits between runes. The server
+	// answers requests in order, so a request it rejects (an invalid type)
+	// sent now is answered after the last fragment.
+	case c.protocol == ProtocolMinecraft && len(p.GetPayload()) > packet.PayloadResponseMax-utf8.UTFMax:
+		call.payload.WriteString(p.GetPayload())
+		call.sentinel = c.newId()
+		call.exchange.sent += 4 + packet.LengthMin
+		c.pending[call.sentinel] = call
+		c.lock.Unlock()
+		if err := c.send(call.sentinel, typeSentinel, ""); err != nil {
+			c.lock.Lock()
+			if c.pending[call.id] == call {
+				c.finish(call, err)
+			}
+			c.lock.Unlock()
+		}
+		return
+
+	default:
+		call.result = p.GetPayload()
+		c.finish(call, nil)
+	}
+	c.lock.Unlock()
+}
+
+// Keep a packet nobody is waiting for, a late response say, for ReadPacket.
+// When nobody reads them the oldest are dropped.
+func (c *Connection) unsolicited(p *Packet) {
+	p = p.Copy()
+	if c.debug {
+		c.log.Debug("rcon: unsolicited packet", "id", p.GetId(), "bytes", len(p.GetPayload()))
+	}
+	for {
+		select {
+		case c.extra <- p:
+			return
+		default:
+		}
+		select {
+		case <-c.extra:
+		default:
+		}
+	}
+}
+
+// Fail every call and any made later with err
+func (c *Connection) stop(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closing {
+		err = ErrorClosed
+	} else {
+		c.log.Warn("rcon: connection lost", "address", c.conn.RemoteAddr(), "error", err)
+		err = wrap(ErrorClosed, err)
+	}
+	c.failed = err
+	for _, call := range c.pending {
+		if c.pending[call.id] == call {
+			c.finish(call, err)
+		}
+	}
+	close(c.extra)
+}
+
+// Next packet that arrived without a request waiting for it, such as the
+// response to a command whose context ended first. Packets are received
+// by the connection as they arrive, so this blocks until one is available
+// or the connection fails.
+func (c *Connection) ReadPacket() (*Packet, error) {
+	p, ok := <-c.extra
+	if !ok {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		return nil, c.failed
+	}
+	return p, nil
+}
+
+// Whether the connection was closed or lost
+func (c *Connection) broken() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.failed != nil || c.closing
+}
@@ -0,0 +1,172 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Connections idle for longer are pinged before Get hands them out
+const poolCheckIdle = 30 * time.Second
+
+var (
+	ErrorPoolClosed = errors.New("connection: pool closed")
+)
+
+// Returned by Get once the pool is closed, matching ErrorClosed too
+var errorPoolClosed = wrap(ErrorClosed, ErrorPoolClosed)
+
+// Pool of up to size authenticated connections to one server. Connections
+// are dialed when first needed and replaced when they fail.
+type Pool struct {
+	replaced int64 // failed connections discarded, accessed atomically
+	hostUri  string
+	password string
+	opts     []Option
+	idle     chan *Connection
+	slots    chan struct{} // one per connection open or being dialed
+	lock     sync.Mutex    // guards closed, and sends to idle
+	closed   bool
+	done     chan struct{} // closed by Close, waking callers of Get
+}
+
+func NewPool(hostUri string, password string, size int, opts ...Option) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{
+		hostUri:  hostUri,
+		password: password,
+		opts:     opts,
+		idle:     make(chan *Connection, size),
+		slots:    make(chan struct{}, size),
+		done:     make(chan struct{}),
+	}
+}
+
+// Take a connection, dialing one if none are idle and the pool has room,
+// otherwise waiting for one to be put back, until the pool is closed.
+// Return it with Put.
+func (p *Pool) Get(ctx context.Context) (*Connection, error) {
+	for {
+		if p.isClosed() {
+			return nil, errorPoolClosed
+		}
+
+		select {
+		case c := <-p.idle:
+			if p.healthy(c) {
+				return c, nil
+			}
+			p.replace(c)
+			continue
+		default:
+		}
+
+		select {
+		case c := <-p.idle:
+			if p.healthy(c) {
+				return c, nil
+			}
+			p.replace(c)
+		case p.slots <- struct{}{}:
+			c, err := DialContext(ctx, p.hostUri, p.password, p.opts...)
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			atomic.StoreInt64(&c.stats.reconnects, atomic.LoadInt64(&p.replaced))
+			return c, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-p.done:
+			return nil, errorPoolClosed
+		}
+	}
+}
+
+// Return a connection taken with Get. Failed connections are closed and
+// replaced by the next Get.
+func (p *Pool) Put(c *Connection) {
+	// Sent under the lock Close takes before draining idle, so no
+	// connection is left idle in a closed pool. idle holds one for each
+	// slot, the send never blocks.
+	p.lock.Lock()
+	closed, broken := p.closed, c.broken()
+	if !closed && !broken {
+		p.idle <- c
+	}
+	p.lock.Unlock()
+
+	switch {
+	case closed:
+		p.discard(c)
+	case broken:
+		p.replace(c)
+	}
+}
+
+// Execute a command on a pooled connection
+func (p *Pool) Execute(ctx context.Context, cmd string) (string, error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer p.Put(c)
+	return c.ExecuteContext(ctx, cmd)
+}
+
+// Execute, making Pool a Client
+func (p *Pool) ExecuteContext(ctx context.Context, cmd string) (string, error) {
+	return p.Execute(ctx, cmd)
+}
+
+// Close idle connections, and the others as they are put back. Callers
+// waiting in Get fail with ErrorPoolClosed.
+func (p *Pool) Close() error {
+	p.lock.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	p.lock.Unlock()
+
+	for {
+		select {
+		case c := <-p.idle:
+			p.discard(c)
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *Pool) isClosed() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.closed
+}
+
+// Whether c can be handed out, pinging it when it has been idle a while
+func (p *Pool) healthy(c *Connection) bool {
+	if c.broken() {
+		return false
+	}
+	if c.clock.Since(c.LastActivity()) < poolCheckIdle {
+		return true
+	}
+	return c.Ping() == nil
+}
+
+// Discard a failed connection, which the next dial re-establishes
+func (p *Pool) replace(c *Connection) {
+	atomic.AddInt64(&p.replaced, 1)
+	p.discard(c)
+}
+
+func (p *Pool) discard(c *Connection) {
+	c.Close()
+	<-p.slots
+}
@@ -0,0 +1,122 @@
+package conn_test
+
+import (
+	"context"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/rcontest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoolReconnects(t *testing.T) {
+	server := rcontest.NewServer(t, rcontest.Options{})
+	p := conn.NewPool("rcontest:25575", rcontest.DefaultPassword, 1, conn.WithDialFunc(server.DialFunc()))
+	defer p.Close()
+	ctx := context.Background()
+
+	c, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Stats().Reconnects; n != 0 {
+		t.Errorf("first connection: %d reconnects", n)
+	}
+	p.Put(c)
+
+	// A healthy connection is handed out again
+	if again, err := p.Get(ctx); err != nil || again != c {
+		t.Fatalf("got %p, %v, want the idle connection %p", again, err, c)
+	}
+	c.Close()
+	p.Put(c)
+
+	c, err = p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(c)
+	if n := c.Stats().Reconnects; n != 1 {
+		t.Errorf("replacement: %d reconnects, want 1", n)
+	}
+	if _, err := c.Execute("list"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Close wakes a Get waiting for a full pool
+func TestPoolCloseWakesGet(t *testing.T) {
+	server := rcontest.NewServer(t, rcontest.Options{})
+	p := conn.NewPool("rcontest:25575", rcontest.DefaultPassword, 1, conn.WithDialFunc(server.DialFunc()))
+	ctx := context.Background()
+
+	c, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.Get(ctx)
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		t.Fatalf("Get returned from a full pool: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	p.Close()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, conn.ErrorClosed) || !errors.Is(err, conn.ErrorPoolClosed) {
+			t.Fatalf("got %v, want ErrorClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get still waiting after Close")
+	}
+
+	p.Put(c)
+	if c.Ping() == nil {
+		t.Error("connection put back after Close left open")
+	}
+	if _, err := p.Get(ctx); !errors.Is(err, conn.ErrorClosed) {
+		t.Fatalf("Get after Close: %v", err)
+	}
+	// Closing twice is harmless
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Connections put back while the pool closes are closed, none left idle
+func TestPoolPutDuringClose(t *testing.T) {
+	server := rcontest.NewServer(t, rcontest.Options{})
+	for i := 0; i < 50; i++ {
+		p := conn.NewPool("rcontest:25575", rcontest.DefaultPassword, 4, conn.WithDialFunc(server.DialFunc()))
+		var taken []*conn.Connection
+		for len(taken) < 4 {
+			c, err := p.Get(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			taken = append(taken, c)
+		}
+
+		var wg sync.WaitGroup
+		for _, c := range taken {
+			wg.Add(1)
+			go func(c *conn.Connection) {
+				defer wg.Done()
+				p.Put(c)
+			}(c)
+		}
+		p.Close()
+		wg.Wait()
+		for _, c := range taken {
+			if c.Ping() == nil {
+				t.Fatal("connection left open in a closed pool")
+			}
+		}
+	}
+}
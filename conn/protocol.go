@@ -0,0 +1,60 @@
+package conn
+
+import (
+	"github.com/StarForger/neb-mc-rcon/packet"
+)
+
+// Dialect of RCON spoken by the server, see WithProtocol
+type Protocol int
+
+const (
+	// Minecraft: requests up to 1024 bytes, a response longer than a packet
+	// is told by a full first fragment
+	ProtocolMinecraft Protocol = iota
+	// Valve Source servers (CS:GO, TF2, Garry's Mod): requests up to 4096
+	// bytes, and every command is followed by an empty
+	// SERVERDATA_RESPONSE_VALUE, which the server mirrors back as a pair of
+	// packets after the last fragment of the response
+	ProtocolSource
+	// Factorio: requests up to 4096 bytes, responses in a single packet of
+	// any size and of whatever type, and no second read of the login
+	// response
+	ProtocolFactorio
+)
+
+// Response payload limit of Factorio unless WithMaxResponseSize raises it
+const factorioResponseMax = 1 << 20
+
+// Payload limit of requests
+func (p Protocol) requestMax() int {
+	if p == ProtocolSource || p == ProtocolFactorio {
+		return packet.PayloadResponseMax
+	}
+	return packet.PayloadRequestMax
+}
+
+// Response payload limit given the configured one
+func (p Protocol) responseMax(size int) int {
+	if p == ProtocolFactorio && size < factorioResponseMax {
+		return factorioResponseMax
+	}
+	return size
+}
+
+// Validation of command responses given the configured one
+func (p Protocol) validation(v packet.Validation) packet.Validation {
+	if p == ProtocolFactorio {
+		return packet.Lenient
+	}
+	return v
+}
+
+// Packets of the mirrored pair still to come after a response ended, with
+// c.lock held. Entries for servers that never send the second are dropped
+// once there are many.
+func (c *Connection) expectTrailer(id int32) {
+	if len(c.trailers) >= 64 {
+		c.trailers = map[int32]struct{}{}
+	}
+	c.trailers[id] = struct{}{}
+}
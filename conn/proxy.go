@@ -0,0 +1,216 @@
+package conn
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrorProxy = errors.New("connection: proxy failed")
+)
+
+// Proxy for hostUri, from proxyUrl or when that is empty the ALL_PROXY and
+// NO_PROXY environment variables. Nil for a direct connection.
+func proxyFor(proxyUrl string, hostUri string) (*url.URL, error) {
+	if proxyUrl == "" {
+		proxyUrl = getenv("ALL_PROXY")
+		if proxyUrl == "" || noProxy(hostUri) {
+			return nil, nil
+		}
+	}
+	u, err := url.Parse(proxyUrl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorProxy, err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrorProxy, u.Scheme)
+	}
+	return u, nil
+}
+
+// Either case of an environment variable, as curl accepts
+func getenv(name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return os.Getenv(strings.ToLower(name))
+}
+
+// Whether NO_PROXY excludes hostUri, by host or domain suffix
+func noProxy(hostUri string) bool {
+	host, _, err := net.SplitHostPort(hostUri)
+	if err != nil {
+		host = hostUri
+	}
+	for _, entry := range strings.Split(getenv("NO_PROXY"), ",") {
+		entry = strings.TrimPrefix(strings.TrimSpace(entry), ".")
+		if entry == "*" || entry != "" && (host == entry || strings.HasSuffix(host, "."+entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dial through proxy, reaching it with dial
+func proxyDial(dial DialFunc, proxy *url.URL) DialFunc {
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		port := proxy.Port()
+		if port == "" {
+			port = "1080"
+			if proxy.Scheme == "http" {
+				port = "80"
+			}
+		}
+		conn, err := dial(ctx, network, net.JoinHostPort(proxy.Hostname(), port))
+		if err != nil {
+			return nil, err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		if proxy.Scheme == "http" {
+			err = httpConnect(conn, address, proxy.User)
+		} else {
+			err = socksConnect(conn, address, proxy.User)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return conn, nil
+	}
+}
+
+// SOCKS5 CONNECT (RFC 1928), with username/password authentication (RFC
+// 1929) when user is set. Host names are resolved by the proxy.
+func socksConnect(conn net.Conn, address string, user *url.Userinfo) error {
+	host, portText, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return err
+	}
+
+	greeting := []byte{5, 1, 0} // no authentication
+	if user != nil {
+		greeting = []byte{5, 2, 0, 2} // or username/password
+	}
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+	switch {
+	case reply[0] != 5:
+		return fmt.Errorf("%w: not a SOCKS5 proxy", ErrorProxy)
+	case reply[1] == 2 && user != nil:
+		password, _ := user.Password()
+		auth := []byte{1, byte(len(user.Username()))}
+		auth = append(auth, user.Username()...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply[:]); err != nil {
+			return err
+		}
+		if reply[1] != 0 {
+			return fmt.Errorf("%w: authentication rejected", ErrorProxy)
+		}
+	case reply[1] != 0:
+		return fmt.Errorf("%w: no acceptable authentication method", ErrorProxy)
+	}
+
+	request := []byte{5, 1, 0} // CONNECT
+	if ip := net.ParseIP(host); ip == nil {
+		request = append(request, 3, byte(len(host)))
+		request = append(request, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		request = append(request, 1)
+		request = append(request, ip4...)
+	} else {
+		request = append(request, 4)
+		request = append(request, ip...)
+	}
+	request = append(request, byte(port>>8), byte(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	// Version, status, reserved, address type, then the bound address
+	var head [4]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return err
+	}
+	if head[1] != 0 {
+		return fmt.Errorf("%w: SOCKS5 status %d", ErrorProxy, head[1])
+	}
+	var skip int
+	switch head[3] {
+	case 1:
+		skip = net.IPv4len
+	case 4:
+		skip = net.IPv6len
+	case 3:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return err
+		}
+		skip = int(n[0])
+	default:
+		return fmt.Errorf("%w: SOCKS5 address type %d", ErrorProxy, head[3])
+	}
+	bound := make([]byte, skip+2)
+	_, err = io.ReadFull(conn, bound)
+	return err
+}
+
+// HTTP CONNECT, with basic authentication when user is set
+func httpConnect(conn net.Conn, address string, user *url.Userinfo) error {
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if user != nil {
+		password, _ := user.Password()
+		request.SetBasicAuth(user.Username(), password)
+		request.Header.Set("Proxy-Authorization", request.Header.Get("Authorization"))
+		request.Header.Del("Authorization")
+	}
+	if err := request.Write(conn); err != nil {
+		return err
+	}
+
+	// The server sends nothing more until the first request, so the
+	// buffered reader can't consume any of the tunnelled stream
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", ErrorProxy, response.Status)
+	}
+	return nil
+}
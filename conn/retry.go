@@ -0,0 +1,71 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// How Execute retries commands that fail transiently, on the same
+// connection. Retried commands may run twice, when only the response was
+// lost, so only retry commands that are safe to repeat.
+type RetryPolicy struct {
+	// Attempts including the first, below 2 never retries
+	MaxAttempts int
+	// Wait before the given retry, 1 for the first. Nil retries at once.
+	Backoff func(retry int) time.Duration
+	// Whether an error is worth retrying, nil for Transient
+	Retryable func(err error) bool
+}
+
+// Backoff doubling from base up to max
+func ExponentialBackoff(base time.Duration, max time.Duration) func(retry int) time.Duration {
+	return func(retry int) time.Duration {
+		d := base
+		for i := 1; i < retry && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// Whether err is a response that timed out on a connection still open. A
+// closed connection, rejected password, oversized payload or ended context
+// won't succeed on another attempt.
+func Transient(err error) bool {
+	return errors.Is(err, ErrorTimeout) && !errors.Is(err, ErrorClosed) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// Execute under the retry policy, waiting between attempts unless ctx ends
+func (c *Connection) executeRetry(ctx context.Context, cmd string) (string, exchange, error) {
+	p := c.retry
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = Transient
+	}
+
+	var total exchange
+	for attempt := 1; ; attempt++ {
+		response, t, err := c.execute(ctx, cmd)
+		err = classify(err)
+		total.id = t.id
+		total.sent += t.sent
+		total.received += t.received
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return response, total, err
+		}
+
+		c.log.Debug("rcon: retrying", "command", cmd, "attempt", attempt+1, "error", err)
+		if p.Backoff != nil {
+			select {
+			case <-c.clock.After(p.Backoff(attempt)):
+			case <-ctx.Done():
+				return "", total, classify(ctx.Err())
+			}
+		}
+	}
+}
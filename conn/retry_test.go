@@ -0,0 +1,164 @@
+package conn_test
+
+import (
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/clock"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/packet"
+	"github.com/StarForger/neb-mc-rcon/rcontest"
+	"net"
+	"testing"
+	"time"
+)
+
+// Wait for the mock clock to have n timers, as the connection starts them
+// from its own goroutines
+func waitPending(t *testing.T, m *clock.Mock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Pending() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d timers pending, want %d", m.Pending(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	mock := clock.NewMock(time.Now())
+	c := scripted(t, func(c net.Conn, next func() (request, error)) {
+		// Never answer
+		for {
+			if _, err := next(); err != nil {
+				return
+			}
+		}
+	}, conn.WithClock(mock), conn.WithReadTimeout(5*time.Second))
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.Execute("list")
+		errs <- err
+	}()
+
+	waitPending(t, mock, 1)
+	mock.Advance(5*time.Second - time.Millisecond)
+	select {
+	case err := <-errs:
+		t.Fatalf("gave up early: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	mock.Advance(time.Millisecond)
+	err := <-errs
+	if !errors.Is(err, conn.ErrorTimeout) {
+		t.Fatalf("got %v, want ErrorTimeout", err)
+	}
+	if errors.Is(err, conn.ErrorClosed) {
+		t.Fatalf("connection closed by a timeout: %v", err)
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	// Nothing reads after the login, so writes block
+	c := scripted(t, func(c net.Conn, next func() (request, error)) {},
+		conn.WithWriteTimeout(20*time.Millisecond))
+
+	_, err := c.ExecuteTimeout("list", time.Second)
+	if !errors.Is(err, conn.ErrorTimeout) {
+		t.Fatalf("got %v, want ErrorTimeout", err)
+	}
+}
+
+// The write timeout is a socket deadline, which a mock clock set in the
+// past mustn't expire before anything is written
+func TestWriteTimeoutMockClock(t *testing.T) {
+	server := rcontest.NewServer(t, rcontest.Options{Responses: map[string]string{"list": "ok"}})
+	c := server.Dial(conn.WithClock(clock.NewMock(time.Unix(0, 0))), conn.WithWriteTimeout(time.Second))
+
+	if response, err := c.Execute("list"); err != nil || response != "ok" {
+		t.Fatalf("got %q, %v", response, err)
+	}
+}
+
+// A timed out command is retried after the backoff, on the clock
+func TestRetryBackoff(t *testing.T) {
+	mock := clock.NewMock(time.Now())
+	start := mock.Now()
+	received := make(chan time.Duration, 3)
+	c := scripted(t, func(c net.Conn, next func() (request, error)) {
+		for attempt := 1; ; attempt++ {
+			r, err := next()
+			if err != nil {
+				return
+			}
+			received <- mock.Since(start)
+			// Answer the third attempt only
+			if attempt == 3 {
+				c.Write(packet.Append(nil, r.id, packet.TypeCommandResponse, "done"))
+			}
+		}
+	},
+		conn.WithClock(mock),
+		conn.WithReadTimeout(5*time.Second),
+		conn.WithRetry(conn.RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     conn.ExponentialBackoff(time.Second, 10*time.Second),
+		}),
+	)
+
+	type result struct {
+		response string
+		err      error
+	}
+	results := make(chan result, 1)
+	go func() {
+		response, err := c.Execute("save-all")
+		results <- result{response, err}
+	}()
+
+	// Read timeout, backoff, read timeout, backoff, answer
+	for _, step := range []time.Duration{5 * time.Second, time.Second, 5 * time.Second, 2 * time.Second} {
+		waitPending(t, mock, 1)
+		mock.Advance(step)
+	}
+
+	r := <-results
+	if r.err != nil || r.response != "done" {
+		t.Fatalf("got %q, %v", r.response, r.err)
+	}
+	for i, want := range []time.Duration{0, 6 * time.Second, 13 * time.Second} {
+		if got := <-received; got != want {
+			t.Errorf("attempt %d sent at %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	mock := clock.NewMock(time.Now())
+	c := scripted(t, func(c net.Conn, next func() (request, error)) {
+		for {
+			if _, err := next(); err != nil {
+				return
+			}
+		}
+	},
+		conn.WithClock(mock),
+		conn.WithReadTimeout(time.Second),
+		conn.WithRetry(conn.RetryPolicy{MaxAttempts: 2}),
+	)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.Execute("list")
+		errs <- err
+	}()
+	// No backoff, the retry starts its timer at once
+	for i := 0; i < 2; i++ {
+		waitPending(t, mock, 1)
+		mock.Advance(time.Second)
+	}
+	if err := <-errs; !errors.Is(err, conn.ErrorTimeout) {
+		t.Fatalf("got %v, want ErrorTimeout", err)
+	}
+}
@@ -0,0 +1,64 @@
+package conn
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+type Phase int
+
+const (
+	PhaseConnected     Phase = iota // dialed, logging in
+	PhaseAuthenticated              // ready for commands
+	PhaseClosed                     // closed or lost
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseConnected:
+		return "connected"
+	case PhaseAuthenticated:
+		return "authenticated"
+	case PhaseClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// Snapshot of a Connection
+type State struct {
+	Phase        Phase
+	RemoteAddr   net.Addr
+	RequestId    int32         // negotiated at login, later requests count up from it
+	Uptime       time.Duration // since dialed
+	Commands     int64
+	LastActivity time.Time
+	Err          error // why the connection was lost, ErrorClosed after Close
+}
+
+// The connection's state, safe to call while commands run
+func (c *Connection) State() State {
+	s := State{
+		Phase:        PhaseConnected,
+		RemoteAddr:   c.conn.RemoteAddr(),
+		RequestId:    c.id,
+		Uptime:       c.clock.Since(c.connected),
+		Commands:     atomic.LoadInt64(&c.stats.commands),
+		LastActivity: c.LastActivity(),
+	}
+	if atomic.LoadInt32(&c.authenticated) != 0 {
+		s.Phase = PhaseAuthenticated
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.failed != nil || c.closing {
+		s.Phase = PhaseClosed
+		s.Err = c.failed
+		if s.Err == nil {
+			s.Err = ErrorClosed
+		}
+	}
+	return s
+}
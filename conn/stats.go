@@ -0,0 +1,86 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Cumulative counters for a Connection
+type Stats struct {
+	Commands       int64 // commands executed, including pings
+	Failures       int64 // commands that returned an error
+	BytesSent      int64
+	BytesReceived  int64
+	Reconnects     int64 // failed connections its Pool replaced before dialing it
+	AuthRetries    int64 // login responses read again after a mismatch
+	LastLatency    time.Duration
+	AverageLatency time.Duration // over successful commands
+}
+
+// Receives a measurement as each login and command completes, to export
+// to Prometheus, StatsD and the like. Methods are called on the goroutine
+// that dialed or ran the command, so must be quick and safe for concurrent
+// use.
+type MetricsHook interface {
+	Login(m LoginMetrics)
+	Command(m CommandMetrics)
+}
+
+type LoginMetrics struct {
+	Address string
+	Latency time.Duration // dialing and authenticating
+	Err     error         // an *AuthError when the server was reached but login failed
+}
+
+type CommandMetrics struct {
+	Command       string
+	Latency       time.Duration
+	BytesSent     int // including any request marking the end of a fragmented response
+	BytesReceived int
+	Err           error
+}
+
+// Counters updated atomically, the int64 fields first keep them aligned
+// on 32 bit platforms
+type counters struct {
+	commands      int64
+	failures      int64
+	bytesSent     int64
+	bytesReceived int64
+	reconnects    int64
+	authRetries   int64
+	lastLatency   int64
+	totalLatency  int64
+	timed         int64
+	lastSent      int64 // unix nanoseconds by the connection's clock
+	lastReceived  int64
+}
+
+func (s *counters) command(latency time.Duration, err error) {
+	atomic.AddInt64(&s.commands, 1)
+	if err != nil {
+		atomic.AddInt64(&s.failures, 1)
+		return
+	}
+	atomic.StoreInt64(&s.lastLatency, int64(latency))
+	atomic.AddInt64(&s.totalLatency, int64(latency))
+	atomic.AddInt64(&s.timed, 1)
+}
+
+// Counters since Dial, safe to call while commands run
+func (c *Connection) Stats() Stats {
+	s := &c.stats
+	stats := Stats{
+		Commands:      atomic.LoadInt64(&s.commands),
+		Failures:      atomic.LoadInt64(&s.failures),
+		BytesSent:     atomic.LoadInt64(&s.bytesSent),
+		BytesReceived: atomic.LoadInt64(&s.bytesReceived),
+		Reconnects:    atomic.LoadInt64(&s.reconnects),
+		AuthRetries:   atomic.LoadInt64(&s.authRetries),
+		LastLatency:   time.Duration(atomic.LoadInt64(&s.lastLatency)),
+	}
+	if timed := atomic.LoadInt64(&s.timed); timed > 0 {
+		stats.AverageLatency = time.Duration(atomic.LoadInt64(&s.totalLatency) / timed)
+	}
+	return stats
+}
@@ -0,0 +1,73 @@
+package conn
+
+import (
+	"context"
+	"strings"
+)
+
+// Starts spans for Dial and Execute. The interfaces are small enough that
+// an OpenTelemetry tracer adapts in a few lines, without this package
+// depending on it:
+//
+//	type otelTracer struct{ t trace.Tracer }
+//
+//	func (o otelTracer) Start(ctx context.Context, name string) (context.Context, conn.Span) {
+//		ctx, span := o.t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttribute(key string, value interface{}) {
+//		s.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+//	}
+//	func (s otelSpan) RecordError(err error) {
+//		s.Span.RecordError(err)
+//		s.SetStatus(codes.Error, err.Error())
+//	}
+//	func (s otelSpan) End() { s.Span.End() }
+//
+// passed as conn.WithTracer(otelTracer{provider.Tracer("rcon")}).
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Span attributes
+const (
+	attrHost         = "rcon.host"
+	attrCommand      = "rcon.command"
+	attrRequestId    = "rcon.request_id"
+	attrRequestSize  = "rcon.request.size"
+	attrResponseSize = "rcon.response.size"
+)
+
+func traceDial(ctx context.Context, t Tracer, hostUri string) Span {
+	_, span := t.Start(ctx, "rcon.dial")
+	span.SetAttribute(attrHost, hostUri)
+	return span
+}
+
+// The command's name only, arguments may hold private data
+func traceExecute(ctx context.Context, t Tracer, hostUri string, cmd string) Span {
+	name := cmd
+	if i := strings.IndexByte(cmd, ' '); i >= 0 {
+		name = cmd[:i]
+	}
+	_, span := t.Start(ctx, "rcon.execute")
+	span.SetAttribute(attrHost, hostUri)
+	span.SetAttribute(attrCommand, name)
+	return span
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,192 @@
+// Package gateway exposes RCON commands over HTTP, for CI jobs and
+// webhooks:
+//
+//	POST /command  {"cmd": "say deploy finished", "server": "lobby"}
+//	GET  /healthz
+//
+// Requests carry an acl token as "Authorization: Bearer <token>", whose
+// role limits the servers and commands they may use.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/acl"
+	"github.com/StarForger/neb-mc-rcon/audit"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Largest request body read
+const bodyMax = 64 << 10
+
+// Time allowed for a command whose request has no deadline of its own
+const commandTimeout = 30 * time.Second
+
+type CommandRequest struct {
+	Cmd    string `json:"cmd"`
+	Server string `json:"server,omitempty"` // the default server when empty
+}
+
+type CommandResponse struct {
+	Server   string `json:"server,omitempty"`
+	Response string `json:"response"`
+	Error    string `json:"error,omitempty"`
+}
+
+type Gateway struct {
+	upstreams  map[string]conn.Client
+	authorizer *acl.Authorizer
+	mux        *http.ServeMux
+	// Server of requests without one, none when empty
+	Default string
+	// Records every command, forwarded or denied, nil for none
+	Audit *audit.Logger
+	// Receives a line per request, none by default
+	Logger logging.Logger
+}
+
+// Gateway to upstreams by name, for requests with a token of authorizer
+func New(upstreams map[string]conn.Client, authorizer *acl.Authorizer) *Gateway {
+	g := &Gateway{
+		upstreams:  upstreams,
+		authorizer: authorizer,
+		mux:        http.NewServeMux(),
+		Logger:     logging.Nop,
+	}
+	g.mux.HandleFunc("/command", g.command)
+	g.mux.HandleFunc("/healthz", g.healthz)
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	g.mux.ServeHTTP(rec, r)
+	logging.Or(g.Logger).Info("rcon: http request", "method", r.Method, "path", r.URL.Path,
+		"status", rec.status, "remote", r.RemoteAddr, "duration", time.Since(start))
+}
+
+func (g *Gateway) healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+func (g *Gateway) command(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeJSON(w, http.StatusMethodNotAllowed, CommandResponse{Error: "method not allowed"})
+		return
+	}
+
+	var req CommandRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, bodyMax)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, CommandResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+	if req.Server == "" {
+		req.Server = g.Default
+	}
+	if strings.TrimSpace(req.Cmd) == "" || req.Server == "" {
+		writeJSON(w, http.StatusBadRequest, CommandResponse{Server: req.Server, Error: "cmd and server are required"})
+		return
+	}
+
+	start := time.Now()
+	record := audit.Record{Time: start, Client: r.RemoteAddr, Server: req.Server, Command: req.Cmd}
+	response, status, err := g.execute(r, req, &record)
+	record.Duration = time.Since(start)
+	result := CommandResponse{Server: req.Server, Response: response}
+	if err != nil {
+		record.Error, result.Error = err.Error(), err.Error()
+	} else {
+		record.Response = response
+	}
+	if g.Audit != nil {
+		if err := g.Audit.Log(record); err != nil {
+			logging.Or(g.Logger).Error("rcon: audit failed", "error", err)
+		}
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	writeJSON(w, status, result)
+}
+
+// Run the request's command, with the HTTP status to answer
+func (g *Gateway) execute(r *http.Request, req CommandRequest, record *audit.Record) (string, int, error) {
+	token := bearer(r)
+	role, err := g.authorizer.Authorize(token, req.Server, req.Cmd)
+	record.Role = role
+	if err != nil {
+		record.Denied = true
+		return "", authStatus(err), err
+	}
+
+	upstream, ok := g.upstreams[req.Server]
+	if !ok {
+		record.Denied = true
+		return "", http.StatusNotFound, errors.New("gateway: unknown server")
+	}
+
+	ctx := r.Context()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+	}
+	response, err := upstream.ExecuteContext(ctx, req.Cmd)
+	switch {
+	case errors.Is(err, conn.ErrorTimeout):
+		return "", http.StatusGatewayTimeout, err
+	case err != nil:
+		return "", http.StatusBadGateway, err
+	}
+	return response, http.StatusOK, nil
+}
+
+// Token of an "Authorization: Bearer" header, empty if there is none
+func bearer(r *http.Request) string {
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if len(h) < len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(h[len(prefix):])
+}
+
+func authStatus(err error) int {
+	switch err {
+	case acl.ErrorUnknownToken:
+		return http.StatusUnauthorized
+	case acl.ErrorRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusForbidden
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Keeps the status written, for the request log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
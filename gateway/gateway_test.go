@@ -0,0 +1,214 @@
+package gateway_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StarForger/neb-mc-rcon/acl"
+	"github.com/StarForger/neb-mc-rcon/audit"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/gateway"
+	"github.com/StarForger/neb-mc-rcon/rcontest"
+)
+
+// Gateway to "lobby", answering "ran <command>", and a "slow" server, for
+// the tokens "admin" and "mod" whose role may list and say on the lobby,
+// twice
+func newGateway(t *testing.T) (*gateway.Gateway, *bytes.Buffer) {
+	t.Helper()
+	authorizer, err := acl.NewAuthorizer(acl.Policy{
+		Roles: map[string]acl.Role{
+			"admin":     {Servers: []string{"*"}, Commands: []string{"*"}},
+			"moderator": {Servers: []string{"lobby"}, Commands: []string{"list", "say *"}, Rate: 0.001, Burst: 2},
+		},
+		Tokens: map[string]string{"admin": "admin", "mod": "moderator"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lobby := rcontest.NewServer(t, rcontest.Options{
+		Handler: func(cmd string) string { return "ran " + cmd },
+	})
+	slow := rcontest.NewServer(t, rcontest.Options{Delay: 500 * time.Millisecond})
+	g := gateway.New(map[string]conn.Client{"lobby": lobby.Dial(), "slow": slow.Dial()}, authorizer)
+	g.Default = "lobby"
+	var log bytes.Buffer
+	g.Audit = audit.NewLogger(&log)
+	return g, &log
+}
+
+type result struct {
+	status int
+	header http.Header
+	body   gateway.CommandResponse
+}
+
+func post(t *testing.T, g *gateway.Gateway, token string, body string) result {
+	t.Helper()
+	ts := httptest.NewServer(g)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/command", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("content type %q", ct)
+	}
+	res := result{status: resp.StatusCode, header: resp.Header}
+	if err := json.NewDecoder(resp.Body).Decode(&res.body); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	return res
+}
+
+func TestCommand(t *testing.T) {
+	g, log := newGateway(t)
+
+	res := post(t, g, "admin", `{"cmd": "say deploy finished"}`)
+	if res.status != http.StatusOK || res.body != (gateway.CommandResponse{Server: "lobby", Response: "ran say deploy finished"}) {
+		t.Fatalf("HTTP %d, %+v", res.status, res.body)
+	}
+	res = post(t, g, "admin", `{"cmd": "list", "server": "lobby"}`)
+	if res.status != http.StatusOK || res.body.Response != "ran list" {
+		t.Fatalf("named server: HTTP %d, %+v", res.status, res.body)
+	}
+
+	var r audit.Record
+	if err := json.Unmarshal([]byte(strings.SplitN(log.String(), "\n", 2)[0]), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Role != "admin" || r.Server != "lobby" || r.Command != "say deploy finished" || r.Response != "ran say deploy finished" || r.Denied {
+		t.Errorf("audit record %+v", r)
+	}
+}
+
+// Every failure is answered with a JSON body carrying the error
+func TestCommandErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+		error  string
+	}{
+		{"no token", "", `{"cmd": "list"}`, http.StatusUnauthorized, acl.ErrorUnknownToken.Error()},
+		{"unknown token", "nope", `{"cmd": "list"}`, http.StatusUnauthorized, acl.ErrorUnknownToken.Error()},
+		{"command denied", "mod", `{"cmd": "stop"}`, http.StatusForbidden, acl.ErrorCommandDenied.Error()},
+		{"server denied", "mod", `{"cmd": "list", "server": "slow"}`, http.StatusForbidden, acl.ErrorServerDenied.Error()},
+		{"unknown server", "admin", `{"cmd": "list", "server": "creative"}`, http.StatusNotFound, "gateway: unknown server"},
+		{"no command", "admin", `{"cmd": "  "}`, http.StatusBadRequest, "cmd and server are required"},
+		{"not json", "admin", `cmd=list`, http.StatusBadRequest, "invalid request"},
+		{"too large", "admin", `{"cmd": "` + strings.Repeat("x", 65<<10) + `"}`, http.StatusBadRequest, "invalid request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _ := newGateway(t)
+			res := post(t, g, tt.token, tt.body)
+			if res.status != tt.status || !strings.HasPrefix(res.body.Error, tt.error) {
+				t.Fatalf("HTTP %d, %+v, want %d %q", res.status, res.body, tt.status, tt.error)
+			}
+			if res.status == http.StatusUnauthorized && res.header.Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate %q", res.header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestCommandNoDefault(t *testing.T) {
+	g, _ := newGateway(t)
+	g.Default = ""
+	if res := post(t, g, "admin", `{"cmd": "list"}`); res.status != http.StatusBadRequest {
+		t.Fatalf("HTTP %d, %+v", res.status, res.body)
+	}
+}
+
+func TestCommandRateLimit(t *testing.T) {
+	g, log := newGateway(t)
+	for i := 0; i < 2; i++ {
+		if res := post(t, g, "mod", `{"cmd": "list"}`); res.status != http.StatusOK {
+			t.Fatalf("command %d: HTTP %d, %+v", i, res.status, res.body)
+		}
+	}
+	res := post(t, g, "mod", `{"cmd": "list"}`)
+	if res.status != http.StatusTooManyRequests || res.body.Error != acl.ErrorRateLimited.Error() {
+		t.Fatalf("HTTP %d, %+v", res.status, res.body)
+	}
+	if !strings.Contains(log.String(), `"denied":true`) {
+		t.Errorf("refusal not audited:\n%s", log.String())
+	}
+}
+
+// A command outliving the request's deadline is a gateway timeout
+func TestCommandTimeout(t *testing.T) {
+	g, _ := newGateway(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "/command", strings.NewReader(`{"cmd": "list", "server": "slow"}`)).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer admin")
+
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	var body gateway.CommandResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusGatewayTimeout || body.Error == "" {
+		t.Fatalf("HTTP %d, %+v", w.Code, body)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	g, _ := newGateway(t)
+	ts := httptest.NewServer(g)
+	defer ts.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		allow  string
+	}{
+		{http.MethodGet, "/command", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodPut, "/command", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodGet, "/healthz", http.StatusOK, ""},
+		{http.MethodHead, "/healthz", http.StatusOK, ""},
+		{http.MethodPost, "/healthz", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{http.MethodGet, "/nowhere", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body gateway.CommandResponse
+		if tt.path == "/command" {
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+				t.Errorf("%s %s: JSON body %+v, %v", tt.method, tt.path, body, err)
+			}
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || resp.Header.Get("Allow") != tt.allow {
+			t.Errorf("%s %s: HTTP %d, Allow %q", tt.method, tt.path, resp.StatusCode, resp.Header.Get("Allow"))
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package grpcserver
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Messages of rcon.proto, encoded and decoded by hand with the protobuf
+// wire format, as much of it as these messages use
+
+var ErrorMalformed = errors.New("grpcserver: malformed message")
+
+// Wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+type ExecuteRequest struct {
+	Server  string
+	Command string
+}
+
+type ExecuteResponse struct {
+	Server   string
+	Command  string
+	Response string
+	Error    string
+}
+
+type StatusRequest struct {
+	Server string
+}
+
+type StatusResponse struct {
+	Server        string
+	Version       string
+	PlayersOnline int32
+	PlayersMax    int32
+	Players       []string
+}
+
+func (m *ExecuteRequest) unmarshal(data []byte) error {
+	return decode(data, func(field int, value []byte) {
+		switch field {
+		case 1:
+			m.Server = string(value)
+		case 2:
+			m.Command = string(value)
+		}
+	})
+}
+
+func (m *StatusRequest) unmarshal(data []byte) error {
+	return decode(data, func(field int, value []byte) {
+		if field == 1 {
+			m.Server = string(value)
+		}
+	})
+}
+
+func (m *ExecuteResponse) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Server)
+	b = appendString(b, 2, m.Command)
+	b = appendString(b, 3, m.Response)
+	b = appendString(b, 4, m.Error)
+	return b
+}
+
+func (m *StatusResponse) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Server)
+	b = appendString(b, 2, m.Version)
+	b = appendInt32(b, 3, m.PlayersOnline)
+	b = appendInt32(b, 4, m.PlayersMax)
+	for _, p := range m.Players {
+		b = appendTag(b, 5, wireBytes)
+		b = appendUvarint(b, uint64(len(p)))
+		b = append(b, p...)
+	}
+	return b
+}
+
+// Call field with the contents of each length delimited field, skipping
+// the others
+func decode(data []byte, field func(n int, value []byte)) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return ErrorMalformed
+		}
+		data = data[n:]
+
+		switch tag & 7 {
+		case wireVarint:
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return ErrorMalformed
+			}
+		case wireFixed64:
+			n = 8
+		case wireFixed32:
+			n = 4
+		case wireBytes:
+			length, m := binary.Uvarint(data)
+			if m <= 0 || length > uint64(len(data)-m) {
+				return ErrorMalformed
+			}
+			field(int(tag>>3), data[m:m+int(length)])
+			n = m + int(length)
+		default:
+			return ErrorMalformed
+		}
+		if n > len(data) {
+			return ErrorMalformed
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func appendTag(b []byte, field int, wire int) []byte {
+	return appendUvarint(b, uint64(field)<<3|uint64(wire))
+}
+
+// Empty strings are left out, as proto3 does
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// Negative numbers take ten bytes, as int32 fields do in proto3
+func appendInt32(b []byte, field int, v int32) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireVarint)
+	return appendUvarint(b, uint64(int64(v)))
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
@@ -0,0 +1,315 @@
+// Package grpcserver serves the Rcon service of rcon.proto, RCON commands
+// over gRPC for clients in other languages. It is an http.Handler speaking
+// gRPC's HTTP/2 framing directly, so it needs a TLS listener offering h2,
+// where client certificates can be required.
+package grpcserver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Largest request message read
+const messageMax = 4 << 20
+
+// Status codes, see https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+type Code int
+
+const (
+	CodeOK                Code = 0
+	CodeCanceled          Code = 1
+	CodeUnknown           Code = 2
+	CodeInvalidArgument   Code = 3
+	CodeDeadlineExceeded  Code = 4
+	CodeNotFound          Code = 5
+	CodeResourceExhausted Code = 8
+	CodeUnimplemented     Code = 12
+	CodeInternal          Code = 13
+	CodeUnavailable       Code = 14
+)
+
+// Failed call, sent as its grpc-status and grpc-message
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("grpcserver: status %d: %s", e.Code, e.Message)
+}
+
+type Server struct {
+	upstreams map[string]conn.Client
+	// Server of requests without one, none when empty
+	Default string
+	// Receives a line per call, none by default
+	Logger logging.Logger
+}
+
+// Server forwarding to upstreams by name
+func New(upstreams map[string]conn.Client) *Server {
+	return &Server{upstreams: upstreams, Logger: logging.Nop}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if r.Method != http.MethodPost || r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Before anything can fail, clients drop responses of other types
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	ctx := r.Context()
+	if timeout := r.Header.Get("Grpc-Timeout"); timeout != "" {
+		d, err := parseTimeout(timeout)
+		if err != nil {
+			s.finish(w, r, start, &Error{CodeInvalidArgument, err.Error()})
+			return
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	var err error
+	switch r.URL.Path {
+	case "/rcon.v1.Rcon/Execute":
+		err = s.execute(ctx, w, r.Body)
+	case "/rcon.v1.Rcon/ExecuteStream":
+		err = s.executeStream(ctx, w, r.Body)
+	case "/rcon.v1.Rcon/Status":
+		err = s.status(ctx, w, r.Body)
+	default:
+		err = &Error{CodeUnimplemented, "unknown method " + r.URL.Path}
+	}
+	s.finish(w, r, start, err)
+}
+
+func (s *Server) execute(ctx context.Context, w http.ResponseWriter, body io.Reader) error {
+	data, err := readMessage(body)
+	if err != nil {
+		return err
+	}
+	var req ExecuteRequest
+	if err := req.unmarshal(data); err != nil {
+		return &Error{CodeInvalidArgument, err.Error()}
+	}
+
+	resp, err := s.run(ctx, req)
+	if err != nil {
+		return err
+	}
+	return writeMessage(w, resp.marshal())
+}
+
+func (s *Server) executeStream(ctx context.Context, w http.ResponseWriter, body io.Reader) error {
+	// Headers first, clients may wait for them before sending
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	for {
+		data, err := readMessage(body)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req ExecuteRequest
+		if err := req.unmarshal(data); err != nil {
+			return &Error{CodeInvalidArgument, err.Error()}
+		}
+		if ctx.Err() != nil {
+			return contextError(ctx.Err())
+		}
+
+		resp, err := s.run(ctx, req)
+		if err != nil {
+			resp = &ExecuteResponse{Server: req.Server, Command: req.Command, Error: err.(*Error).Message}
+		}
+		if err := writeMessage(w, resp.marshal()); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) status(ctx context.Context, w http.ResponseWriter, body io.Reader) error {
+	data, err := readMessage(body)
+	if err != nil {
+		return err
+	}
+	var req StatusRequest
+	if err := req.unmarshal(data); err != nil {
+		return &Error{CodeInvalidArgument, err.Error()}
+	}
+	name, upstream, err := s.upstream(req.Server)
+	if err != nil {
+		return err
+	}
+
+	client := mcapi.New(upstream)
+	version, err := client.ServerVersion(ctx)
+	if err != nil {
+		return statusError(err)
+	}
+	online, max, names, err := client.Players(ctx)
+	if err != nil {
+		return statusError(err)
+	}
+	resp := &StatusResponse{
+		Server:        name,
+		Version:       strings.TrimSpace(version.Brand + " " + version.MinecraftVersion),
+		PlayersOnline: int32(online),
+		PlayersMax:    int32(max),
+		Players:       names,
+	}
+	return writeMessage(w, resp.marshal())
+}
+
+// Run a request's command, failing with an *Error
+func (s *Server) run(ctx context.Context, req ExecuteRequest) (*ExecuteResponse, error) {
+	name, upstream, err := s.upstream(req.Server)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Command) == "" {
+		return nil, &Error{CodeInvalidArgument, "command is required"}
+	}
+	response, err := upstream.ExecuteContext(ctx, req.Command)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &ExecuteResponse{Server: name, Command: req.Command, Response: response}, nil
+}
+
+func (s *Server) upstream(name string) (string, conn.Client, error) {
+	if name == "" {
+		name = s.Default
+	}
+	if name == "" {
+		return "", nil, &Error{CodeInvalidArgument, "server is required"}
+	}
+	upstream, ok := s.upstreams[name]
+	if !ok {
+		return "", nil, &Error{CodeNotFound, "unknown server " + strconv.Quote(name)}
+	}
+	return name, upstream, nil
+}
+
+// Send the call's status as trailers and log it
+func (s *Server) finish(w http.ResponseWriter, r *http.Request, start time.Time, err error) {
+	code, message := CodeOK, ""
+	if err != nil {
+		e, ok := err.(*Error)
+		if !ok {
+			e = &Error{CodeInternal, err.Error()}
+		}
+		code, message = e.Code, e.Message
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(code)))
+	if message != "" {
+		w.Header().Set("Grpc-Message", encodeMessage(message))
+	}
+	logging.Or(s.Logger).Info("rcon: grpc call", "method", r.URL.Path, "code", int(code),
+		"remote", r.RemoteAddr, "duration", time.Since(start))
+}
+
+// Status of a failed command
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return contextError(err)
+	case errors.Is(err, conn.ErrorTimeout):
+		return &Error{CodeDeadlineExceeded, err.Error()}
+	case errors.Is(err, conn.ErrorPayloadTooLarge):
+		return &Error{CodeInvalidArgument, err.Error()}
+	case errors.Is(err, conn.ErrorClosed), errors.Is(err, conn.ErrorAuthFailed), errors.Is(err, conn.ErrorPoolClosed):
+		return &Error{CodeUnavailable, err.Error()}
+	}
+	return &Error{CodeUnknown, err.Error()}
+}
+
+func contextError(err error) error {
+	if err == context.Canceled {
+		return &Error{CodeCanceled, err.Error()}
+	}
+	return &Error{CodeDeadlineExceeded, err.Error()}
+}
+
+// Read a length prefixed message, io.EOF at the end of the stream
+func readMessage(r io.Reader) ([]byte, error) {
+	var head [5]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, &Error{CodeInternal, err.Error()}
+	}
+	if head[0] != 0 {
+		return nil, &Error{CodeUnimplemented, "compressed messages are not supported"}
+	}
+	length := binary.BigEndian.Uint32(head[1:])
+	if length > messageMax {
+		return nil, &Error{CodeResourceExhausted, "message larger than " + strconv.Itoa(messageMax) + " bytes"}
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, &Error{CodeInternal, err.Error()}
+	}
+	return data, nil
+}
+
+func writeMessage(w http.ResponseWriter, data []byte) error {
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	if _, err := w.Write(append(frame, data...)); err != nil {
+		return &Error{CodeInternal, err.Error()}
+	}
+	w.(http.Flusher).Flush()
+	return nil
+}
+
+// Parse a grpc-timeout header: up to 8 digits and a unit
+func parseTimeout(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+		'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+	}
+	if len(s) < 2 || len(s) > 9 {
+		return 0, errors.New("invalid grpc-timeout " + strconv.Quote(s))
+	}
+	unit, ok := units[s[len(s)-1]]
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if !ok || err != nil || n < 0 {
+		return 0, errors.New("invalid grpc-timeout " + strconv.Quote(s))
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// Percent encode a grpc-message, as the protocol requires outside
+// printable ASCII
+func encodeMessage(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,339 @@
+package grpcserver_test
+
+// Interop tests: calls are made as a gRPC client would, over HTTP/2 with
+// TLS, and messages are built and read field by field from rcon.proto
+// without the server's own encoders. The module has no grpc-go dependency
+// to generate a client with, so framing follows the protocol spec,
+// https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/grpcserver"
+	"github.com/StarForger/neb-mc-rcon/rcontest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fields of a message read back, strings and varints by number
+type message struct {
+	strings map[int][]string
+	varints map[int]uint64
+}
+
+// Length delimited fields, in order, as protoc's encoders write them
+func encode(fields ...interface{}) []byte {
+	var b []byte
+	for i := 0; i+1 < len(fields); i += 2 {
+		s := fields[i+1].(string)
+		if s == "" {
+			continue
+		}
+		b = append(b, byte(fields[i].(int)<<3|2))
+		var length [binary.MaxVarintLen64]byte
+		b = append(b, length[:binary.PutUvarint(length[:], uint64(len(s)))]...)
+		b = append(b, s...)
+	}
+	return b
+}
+
+func decode(t *testing.T, data []byte) message {
+	t.Helper()
+	m := message{strings: map[int][]string{}, varints: map[int]uint64{}}
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("bad tag in % x", data)
+		}
+		data = data[n:]
+		switch tag & 7 {
+		case 0:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				t.Fatalf("bad varint in % x", data)
+			}
+			m.varints[int(tag>>3)] = v
+			data = data[n:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || int(length) > len(data)-n {
+				t.Fatalf("bad length in % x", data)
+			}
+			m.strings[int(tag>>3)] = append(m.strings[int(tag>>3)], string(data[n:n+int(length)]))
+			data = data[n+int(length):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+	}
+	return m
+}
+
+func (m message) string(field int) string {
+	if s := m.strings[field]; len(s) > 0 {
+		return s[len(s)-1]
+	}
+	return ""
+}
+
+// Length prefixed message, uncompressed
+func frame(data []byte) []byte {
+	head := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(head[1:], uint32(len(data)))
+	return append(head, data...)
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var head [5]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(head[1:]))
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+// Result of a call: its messages and status
+type result struct {
+	messages [][]byte
+	status   string
+	message  string
+}
+
+// gRPC server in front of an upstream "lobby", over TLS with HTTP/2
+func startServer(t *testing.T, opts rcontest.Options) *httptest.Server {
+	t.Helper()
+	upstream := rcontest.NewServer(t, opts).Dial()
+	s := grpcserver.New(map[string]conn.Client{"lobby": upstream})
+	s.Default = "lobby"
+
+	ts := httptest.NewUnstartedServer(s)
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func call(t *testing.T, ts *httptest.Server, method string, header http.Header, requests ...[]byte) result {
+	t.Helper()
+	var body bytes.Buffer
+	for _, r := range requests {
+		body.Write(frame(r))
+	}
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/rcon.v1.Rcon/"+method, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/grpc+proto")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("%s over HTTP/%d", method, resp.ProtoMajor)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/grpc" {
+		t.Fatalf("%s: HTTP %d, content type %q", method, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var res result
+	for {
+		data, err := readFrame(resp.Body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		res.messages = append(res.messages, data)
+	}
+	// Trailers are only complete once the body has been read
+	res.status = resp.Trailer.Get("Grpc-Status")
+	res.message = resp.Trailer.Get("Grpc-Message")
+	return res
+}
+
+func TestExecute(t *testing.T) {
+	ts := startServer(t, rcontest.Options{
+		Responses: map[string]string{"list": "There are 0 of a max of 20 players online: "},
+	})
+
+	tests := []struct {
+		name     string
+		server   string
+		command  string
+		status   string
+		response string
+	}{
+		{"default server", "", "list", "0", "There are 0 of a max of 20 players online: "},
+		{"named server", "lobby", "list", "0", "There are 0 of a max of 20 players online: "},
+		{"unknown server", "nowhere", "list", "5", ""},
+		{"no command", "lobby", "  ", "3", ""},
+		{"over the request limit", "lobby", strings.Repeat("x", 1500), "3", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := call(t, ts, "Execute", nil, encode(1, tt.server, 2, tt.command))
+			if res.status != tt.status {
+				t.Fatalf("status %s (%s), want %s", res.status, res.message, tt.status)
+			}
+			if tt.status != "0" {
+				if len(res.messages) != 0 {
+					t.Errorf("%d messages with an error status", len(res.messages))
+				}
+				if res.message == "" {
+					t.Error("no grpc-message")
+				}
+				return
+			}
+			if len(res.messages) != 1 {
+				t.Fatalf("%d messages, want 1", len(res.messages))
+			}
+			m := decode(t, res.messages[0])
+			if m.string(1) != "lobby" || m.string(2) != tt.command || m.string(3) != tt.response || m.string(4) != "" {
+				t.Errorf("response %+v", m.strings)
+			}
+		})
+	}
+}
+
+func TestExecuteDeadline(t *testing.T) {
+	ts := startServer(t, rcontest.Options{Delay: 200 * time.Millisecond})
+
+	header := http.Header{"Grpc-Timeout": {"50m"}}
+	res := call(t, ts, "Execute", header, encode(2, "list"))
+	if res.status != "4" {
+		t.Fatalf("status %s (%s), want DEADLINE_EXCEEDED", res.status, res.message)
+	}
+
+	header = http.Header{"Grpc-Timeout": {"soon"}}
+	if res := call(t, ts, "Execute", header, encode(2, "list")); res.status != "3" {
+		t.Fatalf("status %s (%s) for a bad grpc-timeout, want INVALID_ARGUMENT", res.status, res.message)
+	}
+}
+
+func TestExecuteStream(t *testing.T) {
+	ts := startServer(t, rcontest.Options{
+		Handler: func(cmd string) string { return "ran " + cmd },
+	})
+
+	res := call(t, ts, "ExecuteStream", nil,
+		encode(2, "say one"),
+		encode(1, "nowhere", 2, "say two"),
+		encode(1, "lobby", 2, "say three"),
+	)
+	if res.status != "0" {
+		t.Fatalf("status %s (%s)", res.status, res.message)
+	}
+
+	want := [][4]string{
+		{"lobby", "say one", "ran say one", ""},
+		{"nowhere", "say two", "", `unknown server "nowhere"`},
+		{"lobby", "say three", "ran say three", ""},
+	}
+	if len(res.messages) != len(want) {
+		t.Fatalf("%d messages, want %d", len(res.messages), len(want))
+	}
+	for i, data := range res.messages {
+		m := decode(t, data)
+		got := [4]string{m.string(1), m.string(2), m.string(3), m.string(4)}
+		if got != want[i] {
+			t.Errorf("message %d: %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+// Each response arrives as its request is answered, before the client
+// has finished sending
+func TestExecuteStreamInterleaved(t *testing.T) {
+	ts := startServer(t, rcontest.Options{
+		Handler: func(cmd string) string { return "ran " + cmd },
+	})
+
+	body, requests := io.Pipe()
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/rcon.v1.Rcon/ExecuteStream", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	for _, cmd := range []string{"time query daytime", "weather clear"} {
+		if _, err := requests.Write(frame(encode(2, cmd))); err != nil {
+			t.Fatal(err)
+		}
+		data, err := readFrame(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := decode(t, data); m.string(3) != "ran "+cmd {
+			t.Fatalf("%s: response %+v", cmd, m.strings)
+		}
+	}
+	requests.Close()
+	if _, err := readFrame(resp.Body); err != io.EOF {
+		t.Fatalf("after the last response: %v", err)
+	}
+	if status := resp.Trailer.Get("Grpc-Status"); status != "0" {
+		t.Fatalf("status %s", status)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ts := startServer(t, rcontest.Options{
+		Responses: map[string]string{
+			"version": "This server is running Paper version git-Paper-196 (MC: 1.20.4) (Implementing API version 1.20.4-R0.1-SNAPSHOT)",
+			"list":    "There are 2 of a max of 20 players online: Steve, Alex",
+		},
+	})
+
+	res := call(t, ts, "Status", nil, encode(1, "lobby"))
+	if res.status != "0" || len(res.messages) != 1 {
+		t.Fatalf("status %s (%s), %d messages", res.status, res.message, len(res.messages))
+	}
+	m := decode(t, res.messages[0])
+	if m.string(1) != "lobby" || !strings.Contains(m.string(2), "1.20.4") {
+		t.Errorf("server %q, version %q", m.string(1), m.string(2))
+	}
+	if m.varints[3] != 2 || m.varints[4] != 20 {
+		t.Errorf("players %d of %d", m.varints[3], m.varints[4])
+	}
+	if !reflect.DeepEqual(m.strings[5], []string{"Steve", "Alex"}) {
+		t.Errorf("players %q", m.strings[5])
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	ts := startServer(t, rcontest.Options{})
+	if res := call(t, ts, "Shutdown", nil, nil); res.status != "12" {
+		t.Fatalf("status %s (%s), want UNIMPLEMENTED", res.status, res.message)
+	}
+}
+
+func TestNotGRPC(t *testing.T) {
+	ts := startServer(t, rcontest.Options{})
+	resp, err := ts.Client().Post(ts.URL+"/rcon.v1.Rcon/Execute", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("HTTP %d, want 415", resp.StatusCode)
+	}
+}
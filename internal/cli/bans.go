@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"io"
+)
+
+// Apply desired, names or UUIDs, to the server's player ban list and report
+// the changes, returns false if anything failed. With a resolver UUIDs are
+// resolved and the names reported with theirs.
+func BanSync(server Upstream, out io.Writer, desired []string, reason string, resolver mcapi.Resolver, dryRun bool) bool {
+	c := dial(server)
+	defer c.Close()
+
+	ctx := context.Background()
+	client := mcapi.New(c)
+	client.SetResolver(resolver)
+	bans := client.Bans()
+	label := labeller(ctx, client, resolver)
+
+	if dryRun {
+		ban, pardon, err := bans.Plan(ctx, desired)
+		if err != nil {
+			fmt.Fprintln(out, "Ban list error: ", err.Error())
+			return false
+		}
+		for _, name := range ban {
+			fmt.Fprintln(out, "would ban", label(name))
+		}
+		for _, name := range pardon {
+			fmt.Fprintln(out, "would pardon", label(name))
+		}
+		fmt.Fprintf(out, "%d to ban, %d to pardon\n", len(ban), len(pardon))
+		return true
+	}
+
+	result, err := bans.Sync(ctx, desired, reason)
+	if err != nil {
+		fmt.Fprintln(out, "Ban list error: ", err.Error())
+		return false
+	}
+	for _, name := range result.Banned {
+		fmt.Fprintln(out, "banned", label(name))
+	}
+	for _, name := range result.Pardoned {
+		fmt.Fprintln(out, "pardoned", label(name))
+	}
+	for name, err := range result.Failed {
+		fmt.Fprintln(out, "failed", name+":", err)
+	}
+	fmt.Fprintf(out, "%d banned, %d pardoned, %d failed\n", len(result.Banned), len(result.Pardoned), len(result.Failed))
+	return len(result.Failed) == 0
+}
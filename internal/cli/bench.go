@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Load test settings
+type BenchOptions struct {
+	Concurrency int
+	Duration    time.Duration
+	Command     string
+}
+
+type benchResult struct {
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+// Run command repeatedly over concurrent connections and report throughput
+func Bench(server Upstream, out io.Writer, opts BenchOptions) {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+
+	// Connect every worker up front so dial time is not measured
+	conns := make([]conn.Conn, opts.Concurrency)
+	for i := range conns {
+		c, err := connect(server)
+		if err != nil {
+			log.Fatal("Failed to connect to RCON server: ", err)
+		}
+		conns[i] = c
+	}
+
+	results := make([]benchResult, opts.Concurrency)
+	deadline := time.Now().Add(opts.Duration)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = benchWorker(server, conns[i], opts.Command, deadline)
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// Merge worker results
+	var latencies []time.Duration
+	errs := map[string]int{}
+	for _, r := range results {
+		latencies = append(latencies, r.latencies...)
+		for class, n := range r.errors {
+			errs[class] += n
+		}
+	}
+
+	printBench(out, opts, elapsed, latencies, errs)
+}
+
+func benchWorker(server Upstream, c conn.Conn, cmd string, deadline time.Time) benchResult {
+	r := benchResult{errors: map[string]int{}}
+	defer func() {
+		if c != nil {
+			c.Close()
+		}
+	}()
+
+	for time.Now().Before(deadline) {
+		if c == nil {
+			// Reconnect after a connection level failure
+			var err error
+			c, err = connect(server)
+			if err != nil {
+				r.errors["dial"]++
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+		}
+
+		sent := time.Now()
+		_, err := c.Execute(cmd)
+		if err != nil {
+			class := benchErrorClass(err)
+			r.errors[class]++
+			if class == "timeout" || class == "closed" {
+				c.Close()
+				c = nil
+			}
+			continue
+		}
+		r.latencies = append(r.latencies, time.Since(sent))
+	}
+
+	return r
+}
+
+func benchErrorClass(err error) string {
+	switch {
+	case errors.Is(err, conn.ErrorTimeout):
+		return "timeout"
+	case errors.Is(err, conn.ErrorClosed):
+		return "closed"
+	case errors.Is(err, conn.ErrorResponseMismatch):
+		return "mismatch"
+	case errors.Is(err, conn.ErrorAuthFailed):
+		return "auth"
+	case errors.Is(err, conn.ErrorPayloadTooLarge), errors.Is(err, conn.ErrorMinLength),
+		errors.Is(err, conn.ErrorMismatchType), errors.Is(err, conn.ErrorMismatchedPayloadLength):
+		return "packet"
+	}
+	return "other"
+}
+
+func printBench(out io.Writer, opts BenchOptions, elapsed time.Duration, latencies []time.Duration, errs map[string]int) {
+	total := len(latencies)
+	failed := 0
+	for _, n := range errs {
+		failed += n
+	}
+
+	fmt.Fprintf(out, "command:      %q\n", opts.Command)
+	fmt.Fprintf(out, "concurrency:  %d\n", opts.Concurrency)
+	fmt.Fprintf(out, "duration:     %s\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(out, "requests:     %d ok, %d failed\n", total, failed)
+	fmt.Fprintf(out, "throughput:   %.1f req/s\n", float64(total)/elapsed.Seconds())
+
+	if total > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		var sum time.Duration
+		for _, l := range latencies {
+			sum += l
+		}
+		fmt.Fprintln(out, "latency:")
+		fmt.Fprintf(out, "  min  %s\n", latencies[0])
+		fmt.Fprintf(out, "  avg  %s\n", sum/time.Duration(total))
+		fmt.Fprintf(out, "  p50  %s\n", percentile(latencies, 50))
+		fmt.Fprintf(out, "  p90  %s\n", percentile(latencies, 90))
+		fmt.Fprintf(out, "  p99  %s\n", percentile(latencies, 99))
+		fmt.Fprintf(out, "  max  %s\n", latencies[total-1])
+	}
+
+	if failed > 0 {
+		classes := make([]string, 0, len(errs))
+		for class := range errs {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		fmt.Fprintln(out, "errors:")
+		for _, class := range classes {
+			fmt.Fprintf(out, "  %-9s %d\n", class, errs[class])
+		}
+	}
+}
+
+// sorted must be in ascending order
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"regexp"
+)
+
+// Exit status of the command line tool
+const (
+	ExitFailure = 1 // a command failed, or its response reported a failure
+	ExitConnect = 2 // connecting or logging in failed
+)
+
+var (
+	ErrorFailedResponse = errors.New("cli: response reports a failure")
+)
+
+// Patterns responses are checked against, see SetResponseCheck
+var (
+	failPattern    *regexp.Regexp
+	successPattern *regexp.Regexp
+)
+
+// Treat responses matching fail as failures, and with success set those
+// not matching it too. Without fail, the failures any command can report
+// apply: unknown command, incorrect argument and player not found.
+func SetResponseCheck(fail string, success string) error {
+	failPattern, successPattern = nil, nil
+	var err error
+	if fail != "" {
+		if failPattern, err = regexp.Compile(fail); err != nil {
+			return fmt.Errorf("fail regex: %w", err)
+		}
+	}
+	if success != "" {
+		if successPattern, err = regexp.Compile(success); err != nil {
+			return fmt.Errorf("success regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// Error for a response reporting a failure, nil for success
+func responseFailure(response string) error {
+	plain := mcapi.StripFormatting(response)
+	if failPattern == nil {
+		if err := mcapi.CommandFailure(plain); err != nil {
+			return err
+		}
+	} else if failPattern.MatchString(plain) {
+		return fmt.Errorf("%w: %q", ErrorFailedResponse, plain)
+	}
+	if successPattern != nil && !successPattern.MatchString(plain) {
+		return fmt.Errorf("%w: %q does not match the success regex", ErrorFailedResponse, plain)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"github.com/StarForger/neb-mc-rcon/webrcon"
+	"os"
+	"log"
+	"io"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const prompt = "[rcon] $ "
+
+// Options for every connection, the environment's ALL_PROXY applies
+var dialOptions = []conn.Option{conn.WithProxy("")}
+
+// How long connecting keeps retrying, see SetWait
+var wait time.Duration
+
+// Protocols servers can speak: rcon, the default, or webrcon for Rust's
+// WebRCON
+func CheckProtocol(name string) error {
+	switch name {
+	case "", "rcon", "webrcon":
+		return nil
+	}
+	return fmt.Errorf("unknown protocol %q, expected rcon or webrcon", name)
+}
+
+// Log connections' diagnostics to stderr: logins, commands with their
+// request ids and timing, and with wire every packet as a hex dump. RCON
+// connections only, WebRCON has no logging.
+func SetVerbose(verbose bool, wire bool) {
+	if !verbose && !wire {
+		return
+	}
+	dialOptions = append(dialOptions, conn.WithLogger(logging.New(os.Stderr, logging.LevelDebug)))
+	if wire {
+		dialOptions = append(dialOptions, conn.WithHexDump())
+	}
+}
+
+// Keep retrying to connect for up to timeout, for servers still starting up.
+// A rejected password is not retried. Zero tries once.
+func SetWait(timeout time.Duration) {
+	wait = timeout
+}
+
+// Looped run, an interactive session with the options given
+func Run(server Upstream, in io.Reader, out io.Writer, opts SessionOptions) {
+	// Connect
+	s := &shell{
+		conn:     dial(server),
+		server:   server,
+		opts:     opts,
+		out:      out,
+		rec:      opts.Record,
+	}
+	defer func() { s.conn.Close() }()
+
+	s.run(in)
+}
+
+// Execute command, returning its error once reported in the output format
+func Execute(server Upstream, out io.Writer, command ... string) error {
+	return ExecuteAll(server, out, []string{strings.Join(command, " ")}, false)
+}
+
+// Execute commands in order over one connection, returning the first error
+// once reported in the output format. With stopOnError the commands after a
+// failure are skipped.
+func ExecuteAll(server Upstream, out io.Writer, commands []string, stopOnError bool) error {
+	// Connect	
+	c := dial(server)
+	defer c.Close()
+
+	// Send commands
+	return executeAll(c, server.HostUri, out, os.Stderr, commands, stopOnError)
+}
+
+// ExecuteAll on a connection, results written to out and errors to errOut
+func executeAll(c conn.Client, server string, out io.Writer, errOut io.Writer, commands []string, stopOnError bool) error {
+	var first error
+	for _, cmd := range commands {
+		if output == "text" && len(commands) > 1 {
+			fmt.Fprintln(out, prompt+cmd)
+		}
+		if err := send(c, server, out, errOut, cmd); err != nil {
+			if first == nil {
+				first = err
+			}
+			if stopOnError {
+				break
+			}
+		}
+	}
+	return first
+}
+
+// Connect or exit
+func dial(server Upstream) conn.Client {
+	c, err := connect(server)
+	if err != nil {
+		log.Print("Failed to connect to RCON server: ", err)
+		os.Exit(ExitConnect)
+	}
+	return c
+}
+
+// Connect with the server's protocol, retrying for the wait set. Without a
+// password it is asked for, when there is a terminal to ask on.
+func connect(server Upstream) (conn.Conn, error) {
+	if server.Password == "" && IsTerminal(os.Stdin) {
+		var err error
+		if server.Password, err = PromptPassword("Password: "); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline := time.Now().Add(wait)
+	backoff := conn.ExponentialBackoff(250*time.Millisecond, 5*time.Second)
+	for retry := 1; ; retry++ {
+		c, err := connectOnce(server)
+		left := time.Until(deadline)
+		if err == nil || errors.Is(err, conn.ErrorAuthFailed) || left <= 0 {
+			return c, err
+		}
+		if retry == 1 {
+			fmt.Fprintln(os.Stderr, "Waiting for RCON server:", err)
+		}
+		if pause := backoff(retry); pause < left {
+			left = pause
+		}
+		time.Sleep(left)
+	}
+}
+
+func connectOnce(server Upstream) (conn.Conn, error) {
+	if server.Protocol == "webrcon" {
+		return webrcon.Dial(server.HostUri, server.Password)
+	}
+	return conn.Dial(server.HostUri, server.Password, dialOptions...)
+}
+
+// Whether err means the server hung up, as it does after stop
+func closedByServer(err error) bool {
+	return errors.Is(err, conn.ErrorClosed) || err == io.EOF
+}
+
+func send(conn conn.Client, server string, out io.Writer, errOut io.Writer, cmds string) error {
+	start := time.Now()
+	response, err := conn.ExecuteContext(context.Background(), cmds)
+	latency := time.Since(start)
+	if closedByServer(err) {
+		return nil
+	}
+
+	var failure error
+	if err == nil {
+		failure = responseFailure(response)
+	}
+
+	switch output {
+	case "json", "yaml":
+		if err == nil {
+			err = failure
+		}
+		if werr := writeResult(out, newResult(server, cmds, response, latency, err)); werr != nil {
+			return werr
+		}
+		return err
+	}
+
+	if err != nil {
+		fmt.Fprintln(errOut, "Execute error: ", err.Error())
+		return err
+	}
+	if output == "raw" {
+		fmt.Fprintln(out, response)
+	} else {
+		print(out, response)
+	}
+	if failure != nil {
+		fmt.Fprintln(errOut, "Command failed:", failure)
+	}
+	return failure
+}
+
+func print(out io.Writer, msg string) {
+	// translate or strip out formatting codes
+	fmt.Fprintln(out, formatCodes(msg))
+}
\ No newline at end of file
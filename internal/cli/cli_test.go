@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/rconserver"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server answering commands until stop, which drops the connection
+// without a response as Minecraft does, recording the commands it received
+func stoppingServer(t *testing.T) (Upstream, func() []string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex // guards received and conns
+	var received []string
+	conns := map[string]net.Conn{}
+	s := rconserver.NewServer("password", func(ctx context.Context, cmd string) string {
+		lock.Lock()
+		defer lock.Unlock()
+		received = append(received, cmd)
+		if cmd == "stop" {
+			conns[rconserver.ClientAddr(ctx).String()].Close()
+			return ""
+		}
+		return "ran " + cmd
+	})
+	t.Cleanup(func() { s.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			lock.Lock()
+			conns[c.RemoteAddr().String()] = c
+			lock.Unlock()
+			go s.ServeConn(c)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+
+	commands := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), received...)
+	}
+	return Upstream{HostUri: l.Addr().String(), Password: "password"}, commands
+}
+
+func TestShellServerCloses(t *testing.T) {
+	server, _ := stoppingServer(t)
+	c, err := connect(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var out bytes.Buffer
+	s := &shell{conn: c, server: server, out: &out}
+	if !s.execute("list") {
+		t.Fatal("session ended after list")
+	}
+	if s.execute("stop") {
+		t.Fatal("session goes on after the server closed the connection")
+	}
+}
+
+func TestReplayServerCloses(t *testing.T) {
+	server, commands := stoppingServer(t)
+	entries := []RecordEntry{{Command: "list"}, {Command: "stop"}, {Command: "say after"}}
+
+	var out bytes.Buffer
+	Replay(server, &out, entries, 1)
+	if strings.Contains(out.String(), "say after") {
+		t.Errorf("replay went on after the server closed the connection:\n%s", out.String())
+	}
+	if got := strings.Join(commands(), ","); got != "list,stop" {
+		t.Errorf("server received %s", got)
+	}
+}
+
+func TestSendServerCloses(t *testing.T) {
+	server, _ := stoppingServer(t)
+	c, err := conn.Dial(server.HostUri, server.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var out, errOut bytes.Buffer
+	if err := send(c, server.HostUri, &out, &errOut, "stop"); err != nil {
+		t.Fatalf("stop reported as a failure: %v", err)
+	}
+	if errOut.Len() != 0 {
+		t.Errorf("error output %q", errOut.String())
+	}
+}
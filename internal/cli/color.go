@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Whether responses are printed in color, see SetColor
+var color = false
+
+// Formatting codes: §x followed by six §digit for a hex color (Spigot,
+// BungeeCord), or a single code
+var formatCode = regexp.MustCompile(`§[xX](?:§[0-9a-fA-F]){6}|§[0-9a-zA-Z]`)
+
+// SGR parameters of each color and format code. Obfuscated (k) has none.
+var ansiCodes = map[byte]string{
+	'0': "30", '1': "34", '2': "32", '3': "36",
+	'4': "31", '5': "35", '6': "33", '7': "37",
+	'8': "90", '9': "94", 'a': "92", 'b': "96",
+	'c': "91", 'd': "95", 'e': "93", 'f': "97",
+	'l': "1", 'm': "9", 'n': "4", 'o': "3", 'r': "0",
+}
+
+// Translate formatting codes in responses to ANSI colors, unless disabled
+// by NO_COLOR or stdout is not a terminal. Otherwise codes are removed.
+func SetColor(enabled bool) {
+	color = enabled && os.Getenv("NO_COLOR") == "" && isTerminal(int(os.Stdout.Fd()))
+}
+
+// Response as printed: formatting codes translated or removed
+func formatCodes(msg string) string {
+	if !color {
+		return formatCode.ReplaceAllLiteralString(msg, "")
+	}
+
+	used := false
+	msg = formatCode.ReplaceAllStringFunc(msg, func(code string) string {
+		sgr := ansi(code)
+		if sgr == "" {
+			return ""
+		}
+		used = true
+		return "\x1b[" + sgr + "m"
+	})
+	if used {
+		msg += "\x1b[0m"
+	}
+	return msg
+}
+
+// SGR parameters of a formatting code, empty for none
+func ansi(code string) string {
+	code = strings.ToLower(code)
+	if strings.HasPrefix(code, "§x") {
+		hex := strings.Replace(code[len("§x"):], "§", "", -1)
+		rgb, _ := strconv.ParseUint(hex, 16, 32)
+		return "0;38;2;" + strconv.Itoa(int(rgb>>16)) + ";" + strconv.Itoa(int(rgb>>8&0xff)) + ";" + strconv.Itoa(int(rgb&0xff))
+	}
+
+	c := code[len(code)-1]
+	sgr, ok := ansiCodes[c]
+	if !ok {
+		return ""
+	}
+	// A color resets the formatting before it, as in game
+	if c <= '9' || c >= 'a' && c <= 'f' {
+		return "0;" + sgr
+	}
+	return sgr
+}
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Player names older than this are refreshed on the next completion
+	playersStale   = 15 * time.Second
+	playersTimeout = 5 * time.Second
+)
+
+// Vanilla command names, as of 1.17
+var commandNames = []string{
+	"advancement", "attribute", "ban", "ban-ip", "banlist", "bossbar",
+	"clear", "clone", "data", "datapack", "debug", "defaultgamemode", "deop",
+	"difficulty", "effect", "enchant", "execute", "experience", "fill",
+	"forceload", "function", "gamemode", "gamerule", "give", "help", "item",
+	"kick", "kill", "list", "locate", "locatebiome", "loot", "me", "msg",
+	"op", "pardon", "pardon-ip", "particle", "playsound", "recipe", "reload",
+	"save-all", "save-off", "save-on", "say", "schedule", "scoreboard",
+	"seed", "setblock", "setidletimeout", "setworldspawn", "spawnpoint",
+	"spectate", "spreadplayers", "stop", "stopsound", "summon", "tag",
+	"team", "teammsg", "teleport", "tell", "tellraw", "time", "title", "tm",
+	"tp", "trigger", "w", "weather", "whitelist", "worldborder", "xp",
+}
+
+// Kinds of argument completed
+const (
+	argOther = iota
+	argPlayer
+	argGamemode
+	argDifficulty
+	argWeather
+)
+
+// Kind of each argument of the commands whose arguments are completed
+var commandArguments = map[string][]int{
+	"ban":        {argPlayer},
+	"clear":      {argPlayer},
+	"deop":       {argPlayer},
+	"difficulty": {argDifficulty},
+	"gamemode":   {argGamemode, argPlayer},
+	"give":       {argPlayer},
+	"kick":       {argPlayer},
+	"kill":       {argPlayer},
+	"msg":        {argPlayer},
+	"op":         {argPlayer},
+	"spectate":   {argPlayer, argPlayer},
+	"teleport":   {argPlayer, argPlayer},
+	"tell":       {argPlayer},
+	"tp":         {argPlayer, argPlayer},
+	"w":          {argPlayer},
+	"weather":    {argWeather},
+}
+
+var argumentWords = map[int][]string{
+	argGamemode:   {"adventure", "creative", "spectator", "survival"},
+	argDifficulty: {"easy", "hard", "normal", "peaceful"},
+	argWeather:    {"clear", "rain", "thunder"},
+}
+
+// Completes command names and arguments, player names from a cached list
+// result that is refreshed in the background
+type completer struct {
+	api        *mcapi.Client
+	lock       sync.Mutex
+	players    []string
+	updated    time.Time
+	refreshing bool
+}
+
+// Completer for commands sent on c, fetching the players right away
+func newCompleter(c conn.Client) *completer {
+	comp := &completer{api: mcapi.New(c)}
+	comp.refresh()
+	return comp
+}
+
+// Completions of the word ending head, the line up to the cursor: where the
+// word starts in head, and what it could be
+func (c *completer) complete(head string) (start int, candidates []string) {
+	start = strings.LastIndexByte(head, ' ') + 1
+	word := head[start:]
+	args := strings.Fields(head[:start])
+
+	if len(args) == 0 {
+		// A leading slash, as typed in game, is kept
+		slash := ""
+		if strings.HasPrefix(word, "/") {
+			slash, word = "/", word[1:]
+		}
+		for _, name := range commandNames {
+			if strings.HasPrefix(name, word) {
+				candidates = append(candidates, slash+name)
+			}
+		}
+		return start, candidates
+	}
+
+	kinds := commandArguments[strings.TrimPrefix(args[0], "/")]
+	if len(args) > len(kinds) {
+		return start, nil
+	}
+	switch kind := kinds[len(args)-1]; kind {
+	case argPlayer:
+		for _, name := range c.getPlayers() {
+			if strings.HasPrefix(strings.ToLower(name), strings.ToLower(word)) {
+				candidates = append(candidates, name)
+			}
+		}
+	default:
+		for _, w := range argumentWords[kind] {
+			if strings.HasPrefix(w, word) {
+				candidates = append(candidates, w)
+			}
+		}
+	}
+	return start, candidates
+}
+
+// Cached player names, refreshing them when stale
+func (c *completer) getPlayers() []string {
+	c.lock.Lock()
+	stale := time.Since(c.updated) > playersStale
+	players := c.players
+	c.lock.Unlock()
+
+	if stale {
+		c.refresh()
+	}
+	return players
+}
+
+// Fetch the player names in the background, unless already under way
+func (c *completer) refresh() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.refreshing {
+		return
+	}
+	c.refreshing = true
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), playersTimeout)
+		defer cancel()
+		_, _, names, err := c.api.Players(ctx)
+		sort.Strings(names)
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.refreshing = false
+		if err == nil {
+			c.players = names
+			c.updated = time.Now()
+		}
+	}()
+}
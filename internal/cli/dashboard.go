@@ -0,0 +1,384 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Lines of command output kept, and latency samples
+	dashboardLogMax     = 500
+	dashboardSamplesMax = 512
+	dashboardPrompt     = "> "
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Live view of a server: players online, the latency of polling them and
+// commands sent from an input line, redrawn every second
+type dashboard struct {
+	server   string
+	conn     conn.Client
+	api      *mcapi.Client
+	out      io.Writer
+	interval time.Duration
+
+	lock      sync.Mutex
+	online    int
+	max       int
+	players   []string
+	pollErr   error
+	latencies []time.Duration
+	log       []string
+	history   []string
+
+	// Input line, only touched by the drawing loop
+	input  []rune
+	browse int
+}
+
+// Run the dashboard on stdin and stdout, polling every interval, until
+// Ctrl-C or Ctrl-D
+func Dashboard(server Upstream, interval time.Duration) error {
+	if !IsTerminal(os.Stdin) || !IsTerminal(os.Stdout) {
+		return errors.New("dashboard needs a terminal")
+	}
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	c := dial(server)
+	defer c.Close()
+
+	d := &dashboard{
+		server:   server.HostUri,
+		conn:     c,
+		api:      mcapi.New(c),
+		out:      os.Stdout,
+		interval: interval,
+	}
+	return d.run()
+}
+
+func (d *dashboard) run() error {
+	fd := int(os.Stdin.Fd())
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	// Alternate screen, restored on the way out
+	io.WriteString(d.out, "\x1b[?1049h")
+	defer io.WriteString(d.out, "\x1b[?1049l")
+
+	keys := make(chan rune)
+	go func() {
+		in := bufio.NewReader(os.Stdin)
+		for {
+			key, err := readKey(in)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- key
+		}
+	}()
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	go d.poll(notify)
+
+	redraw := time.NewTicker(time.Second)
+	defer redraw.Stop()
+	for {
+		d.draw()
+		select {
+		case key, ok := <-keys:
+			if !ok || !d.key(key, notify) {
+				return nil
+			}
+		case <-changed:
+		case <-redraw.C:
+		}
+	}
+}
+
+// Refresh the players every interval, for as long as the connection lasts
+func (d *dashboard) poll(notify func()) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		start := time.Now()
+		online, max, names, err := d.api.Players(ctx)
+		latency := time.Since(start)
+		cancel()
+		sort.Strings(names)
+
+		d.lock.Lock()
+		d.pollErr = err
+		if err == nil {
+			d.online, d.max, d.players = online, max, names
+			d.latencies = append(d.latencies, latency)
+			if len(d.latencies) > dashboardSamplesMax {
+				d.latencies = d.latencies[len(d.latencies)-dashboardSamplesMax:]
+			}
+		}
+		d.lock.Unlock()
+		notify()
+
+		if errors.Is(err, conn.ErrorClosed) {
+			return
+		}
+		time.Sleep(d.interval)
+	}
+}
+
+// Handle a key typed, false to quit
+func (d *dashboard) key(key rune, notify func()) bool {
+	switch key {
+	case ctrl('C'), ctrl('D'):
+		return false
+	case '\r', '\n':
+		cmd := strings.TrimSpace(string(d.input))
+		d.input = d.input[:0]
+		if cmd != "" {
+			d.history = append(d.history, cmd)
+			d.browse = len(d.history)
+			go d.execute(cmd, notify)
+		}
+	case 0x7f, ctrl('H'):
+		if len(d.input) > 0 {
+			d.input = d.input[:len(d.input)-1]
+		}
+	case ctrl('U'), keyEscape:
+		d.input = d.input[:0]
+	case keyUp:
+		if d.browse > 0 {
+			d.browse--
+			d.input = []rune(d.history[d.browse])
+		}
+	case keyDown:
+		if d.browse < len(d.history) {
+			d.browse++
+			d.input = d.input[:0]
+			if d.browse < len(d.history) {
+				d.input = []rune(d.history[d.browse])
+			}
+		}
+	default:
+		if key >= ' ' {
+			d.input = append(d.input, key)
+		}
+	}
+	return true
+}
+
+func (d *dashboard) execute(cmd string, notify func()) {
+	d.addLog(dashboardPrompt + cmd)
+	notify()
+
+	response, err := d.conn.ExecuteContext(context.Background(), cmd)
+	if err != nil {
+		d.addLog("error: " + err.Error())
+	} else {
+		for _, line := range strings.Split(mcapi.StripFormatting(strings.TrimRight(response, "\n")), "\n") {
+			d.addLog("  " + line)
+		}
+	}
+	notify()
+}
+
+func (d *dashboard) addLog(line string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.log = append(d.log, line)
+	if len(d.log) > dashboardLogMax {
+		d.log = d.log[len(d.log)-dashboardLogMax:]
+	}
+}
+
+// Redraw the whole screen
+func (d *dashboard) draw() {
+	width, height, err := terminalSize(int(os.Stdout.Fd()))
+	if err != nil || width < 20 || height < 10 {
+		width, height = 80, 24
+	}
+
+	d.lock.Lock()
+	// Lines are cut to the width, headings already are
+	var lines []string
+	add := func(s string) { lines = append(lines, fit(s, width)) }
+	addHeading := func(title string) { lines = append(lines, heading(title, width)) }
+
+	status := fmt.Sprintf("%d/%d online", d.online, d.max)
+	if d.pollErr != nil {
+		status = "error: " + d.pollErr.Error()
+	}
+	lines = append(lines, "\x1b[7m"+pad(" rcon dashboard  "+d.server+"  "+status, width)+"\x1b[0m")
+
+	addHeading(fmt.Sprintf("Players (%d)", len(d.players)))
+	rows := columns(d.players, width, 4)
+	if len(rows) == 0 {
+		rows = []string{"  nobody online"}
+	}
+	for _, row := range rows {
+		add(row)
+	}
+
+	addHeading("Latency")
+	add("  " + sparkline(d.latencies, width-2))
+	add("  " + latencyStats(d.latencies))
+
+	addHeading("Commands")
+	room := height - len(lines) - 2
+	if room < 0 {
+		room = 0
+	}
+	log := d.log
+	if len(log) > room {
+		log = log[len(log)-room:]
+	}
+	for _, line := range log {
+		add(line)
+	}
+	for len(lines) < height-2 {
+		add("")
+	}
+	addHeading("Ctrl-C to quit")
+	input := string(d.input)
+	d.lock.Unlock()
+
+	// Keep the end of a long input in view
+	if max := width - len(dashboardPrompt) - 1; utf8.RuneCountInString(input) > max {
+		r := []rune(input)
+		input = string(r[len(r)-max:])
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for _, line := range lines {
+		b.WriteString(line + "\x1b[K\r\n")
+	}
+	b.WriteString(dashboardPrompt + input + "\x1b[K\x1b[J")
+	io.WriteString(d.out, b.String())
+}
+
+// Section heading across the width
+func heading(title string, width int) string {
+	s := "── " + title + " "
+	if n := width - utf8.RuneCountInString(s); n > 0 {
+		s += strings.Repeat("─", n)
+	}
+	return "\x1b[1m" + fit(s, width) + "\x1b[0m"
+}
+
+// Names in columns, at most maxRows rows, the last one noting any left out
+func columns(names []string, width int, maxRows int) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	cell := 0
+	for _, name := range names {
+		if n := utf8.RuneCountInString(name); n > cell {
+			cell = n
+		}
+	}
+	cell += 2
+	perRow := (width - 2) / cell
+	if perRow < 1 {
+		perRow = 1
+	}
+
+	var rows []string
+	for i := 0; i < len(names); i += perRow {
+		if len(rows) == maxRows-1 && len(names)-i > perRow {
+			rows = append(rows, fmt.Sprintf("  … and %d more", len(names)-i))
+			break
+		}
+		end := i + perRow
+		if end > len(names) {
+			end = len(names)
+		}
+		row := "  "
+		for _, name := range names[i:end] {
+			row += pad(name, cell)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Latest samples as bars scaled to the largest shown
+func sparkline(samples []time.Duration, width int) string {
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+	var max time.Duration
+	for _, s := range samples {
+		if s > max {
+			max = s
+		}
+	}
+	if max == 0 {
+		return ""
+	}
+	bars := make([]rune, len(samples))
+	for i, s := range samples {
+		bars[i] = sparks[int(int64(s)*int64(len(sparks)-1)/int64(max))]
+	}
+	return string(bars)
+}
+
+func latencyStats(samples []time.Duration) string {
+	if len(samples) == 0 {
+		return "no samples yet"
+	}
+	min, max, total := samples[0], samples[0], time.Duration(0)
+	for _, s := range samples {
+		if s < min {
+			min = s
+		}
+		if s > max {
+			max = s
+		}
+		total += s
+	}
+	avg := total / time.Duration(len(samples))
+	return fmt.Sprintf("last %v  min %v  avg %v  max %v", round(samples[len(samples)-1]), round(min), round(avg), round(max))
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(10 * time.Microsecond)
+}
+
+// s cut to width characters
+func fit(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
+
+// s padded with spaces to width characters
+func pad(s string, width int) string {
+	if n := width - utf8.RuneCountInString(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return fit(s, width)
+}
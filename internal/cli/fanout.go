@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Execute commands on every server concurrently, up to parallel at once.
+// Each server's output is written in one piece when it is done, every line
+// prefixed by the server's name unless results are json or yaml. Returns
+// an error naming the servers that failed, if any.
+func FanOut(upstreams map[string]Upstream, out io.Writer, commands []string, parallel int, stopOnError bool) error {
+	names := make([]string, 0, len(upstreams))
+	for name := range upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		failed []string
+		slots  = make(chan struct{}, parallel)
+	)
+	for _, name := range names {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(name string, u Upstream) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			var buf, errBuf bytes.Buffer
+			err := executeOn(name, u, &buf, &errBuf, commands, stopOnError)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if output == "json" || output == "yaml" {
+				// Results name their server
+				out.Write(buf.Bytes())
+			} else {
+				writePrefixed(out, name, buf.Bytes())
+			}
+			writePrefixed(os.Stderr, name, errBuf.Bytes())
+			if err != nil {
+				failed = append(failed, name)
+			}
+		}(name, upstreams[name])
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("failed on %d of %d servers: %s", len(failed), len(names), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Connect to one server of a fan-out and execute the commands
+func executeOn(name string, u Upstream, out io.Writer, errOut io.Writer, commands []string, stopOnError bool) error {
+	// Several servers can't share the terminal to ask for passwords
+	if u.Password == "" {
+		err := errors.New("no password configured")
+		fmt.Fprintln(errOut, "Failed to connect to RCON server:", err)
+		return err
+	}
+
+	c, err := connect(u)
+	if err != nil {
+		fmt.Fprintln(errOut, "Failed to connect to RCON server:", err)
+		return err
+	}
+	defer c.Close()
+
+	return executeAll(c, name, out, errOut, commands, stopOnError)
+}
+
+// Write output of a server, each line prefixed by its name
+func writePrefixed(out io.Writer, name string, data []byte) {
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line != "" {
+			fmt.Fprintf(out, "[%s] %s", name, line)
+		}
+	}
+}
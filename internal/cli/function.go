@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"io"
+)
+
+// Run the commands of an .mcfunction file over one connection, printing
+// each response, returns false if any command failed
+func RunFunction(server Upstream, out io.Writer, commands []mcapi.FunctionCommand, stopOnError bool) bool {
+	c := dial(server)
+	defer c.Close()
+
+	results := mcapi.New(c).RunFunction(context.Background(), commands, stopOnError)
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(out, "line %d: %s: %v\n", r.Line, r.Command, r.Err)
+			continue
+		}
+		if r.Response != "" {
+			print(out, r.Response)
+		}
+	}
+	fmt.Fprintf(out, "%d of %d commands run, %d failed\n", len(results), len(commands), failed)
+	return failed == 0
+}
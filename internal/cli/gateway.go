@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"github.com/StarForger/neb-mc-rcon/acl"
+	"github.com/StarForger/neb-mc-rcon/audit"
+	"github.com/StarForger/neb-mc-rcon/gateway"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"github.com/StarForger/neb-mc-rcon/tlsutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// HTTP gateway settings
+type GatewayOptions struct {
+	Listen    string
+	Default   string // server of requests without one
+	AuditPath string // none when empty
+	Policy    acl.Policy
+	TLS       tlsutil.Config
+	Insecure  bool // allow plain HTTP on a non-loopback address
+}
+
+// Serve RCON commands over HTTP on opts.Listen, forwarding them to
+// upstreams, until the listener fails. HTTPS when opts.TLS has a
+// certificate.
+func ServeHTTP(upstreams map[string]Upstream, opts GatewayOptions) error {
+	authorizer, err := acl.NewAuthorizer(opts.Policy)
+	if err != nil {
+		return err
+	}
+
+	clients := pools(upstreams)
+	defer closeAll(clients)
+
+	g := gateway.New(clients, authorizer)
+	g.Default = opts.Default
+	g.Logger = logging.New(os.Stderr, logging.LevelInfo)
+	if opts.AuditPath != "" {
+		log, err := audit.Open(opts.AuditPath)
+		if err != nil {
+			return err
+		}
+		defer log.Close()
+		g.Audit = log
+	}
+
+	l, err := listen(opts.Listen, opts.TLS, opts.Insecure)
+	if err != nil {
+		return err
+	}
+	g.Logger.Info("rcon: http gateway listening", "address", l.Addr(), "upstreams", len(clients), "tls", opts.TLS.Enabled())
+	server := &http.Server{
+		Handler:           g,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.Serve(l)
+}
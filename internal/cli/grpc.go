@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"crypto/tls"
+	"github.com/StarForger/neb-mc-rcon/grpcserver"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"github.com/StarForger/neb-mc-rcon/tlsutil"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// gRPC server settings
+type GrpcOptions struct {
+	Listen  string
+	Default string // server of requests without one
+	TLS     tlsutil.Config
+}
+
+// Serve the Rcon gRPC service on opts.Listen, forwarding commands to
+// upstreams, until the listener fails. gRPC runs over HTTP/2, which needs
+// TLS here.
+func ServeGRPC(upstreams map[string]Upstream, opts GrpcOptions) error {
+	tlsConfig, err := tlsutil.NewServerConfig(opts.TLS)
+	if err != nil {
+		return err
+	}
+	// Copied into the config of each client, which must negotiate HTTP/2
+	tlsConfig.NextProtos = []string{"h2"}
+
+	clients := pools(upstreams)
+	defer closeAll(clients)
+
+	s := grpcserver.New(clients)
+	s.Default = opts.Default
+	s.Logger = logging.New(os.Stderr, logging.LevelInfo)
+
+	l, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return err
+	}
+	s.Logger.Info("rcon: grpc listening", "address", l.Addr(), "upstreams", len(clients))
+	server := &http.Server{
+		Handler:           s,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.Serve(tls.NewListener(l, tlsConfig))
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/tlsutil"
+	"net"
+)
+
+var (
+	ErrorPlaintext = errors.New("cli: refusing to serve without TLS on a non-loopback address")
+)
+
+// Listen on addr with TLS when cfg has a certificate. Without one only
+// loopback addresses are allowed, unless insecure.
+func listen(addr string, cfg tlsutil.Config, insecure bool) (net.Listener, error) {
+	if !cfg.Enabled() && !insecure && !isLoopback(addr) {
+		return nil, fmt.Errorf("%w %s, set a certificate or allow it with --insecure", ErrorPlaintext, addr)
+	}
+	return tlsutil.Listen("tcp", addr, cfg)
+}
+
+// Whether addr only accepts connections from this host. An empty host
+// listens on every interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"time"
+)
+
+// Output format of commands run non-interactively, see SetOutput
+var output = "text"
+
+// Outcome of a command, as written in the json and yaml output formats
+type Result struct {
+	Command   string `json:"command" yaml:"command"`
+	Response  string `json:"response" yaml:"response"`
+	LatencyMs int64  `json:"latency_ms" yaml:"latency_ms"`
+	Server    string `json:"server" yaml:"server"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Write command results as name: text, the default, with formatting codes
+// removed; raw, the response as sent; or json or yaml, a Result
+func SetOutput(name string) error {
+	switch name {
+	case "text", "raw", "json", "yaml":
+		output = name
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, expected text, raw, json or yaml", name)
+}
+
+func newResult(server string, cmd string, response string, latency time.Duration, err error) Result {
+	r := Result{
+		Command:   cmd,
+		Response:  response,
+		LatencyMs: latency.Milliseconds(),
+		Server:    server,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// Write a result in the json or yaml output format, one JSON object per
+// line or one YAML document each
+func writeResult(out io.Writer, r Result) error {
+	if output == "yaml" {
+		data, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(append([]byte("---\n"), data...))
+		return err
+	}
+	return json.NewEncoder(out).Encode(r)
+}
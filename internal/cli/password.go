@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrorNoTerminal = errors.New("cli: no terminal to ask for the password on")
+)
+
+// Ask for a password on the terminal, without echoing it
+func PromptPassword(prompt string) (string, error) {
+	if !IsTerminal(os.Stdin) {
+		return "", ErrorNoTerminal
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := readPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(line), err
+}
+
+// Connect and log in once, to check a password
+func CheckLogin(server Upstream) error {
+	c, err := connectOnce(server)
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
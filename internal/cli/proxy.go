@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"context"
+	"github.com/StarForger/neb-mc-rcon/acl"
+	"github.com/StarForger/neb-mc-rcon/audit"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"github.com/StarForger/neb-mc-rcon/proxy"
+	"github.com/StarForger/neb-mc-rcon/ratelimit"
+	"github.com/StarForger/neb-mc-rcon/tlsutil"
+	"os"
+	"sync"
+)
+
+// Server forwarded to by the proxy and gateway, or run on by FanOut
+type Upstream struct {
+	HostUri  string
+	Password string
+	Protocol string // rcon when empty, or webrcon
+}
+
+// Proxy settings
+type ProxyOptions struct {
+	Listen      string
+	Default     string // upstream of commands without a route prefix
+	AuditPath   string // required, the proxy refuses to run unaudited
+	Policy      acl.Policy
+	MaxSessions int                         // concurrent sessions per token, zero is unlimited
+	Limits      map[string]ratelimit.Config // per client limits by upstream
+	TLS         tlsutil.Config
+	Insecure    bool // allow plain RCON on a non-loopback address
+}
+
+// Serve RCON clients on opts.Listen, forwarding their commands to
+// upstreams, until the listener fails. Clients must speak TLS when
+// opts.TLS has a certificate.
+func Proxy(upstreams map[string]Upstream, opts ProxyOptions) error {
+	if opts.AuditPath == "" {
+		return proxy.ErrorNoAudit
+	}
+	authorizer, err := acl.NewAuthorizer(opts.Policy)
+	if err != nil {
+		return err
+	}
+
+	clients := pools(upstreams)
+	defer closeAll(clients)
+
+	p := proxy.New(clients, authorizer)
+	p.Default = opts.Default
+	p.Logger = logging.New(os.Stderr, logging.LevelInfo)
+	p.Sessions = ratelimit.NewSessions(opts.MaxSessions)
+	for name, cfg := range opts.Limits {
+		p.SetLimits(name, cfg)
+	}
+	log, err := audit.Open(opts.AuditPath)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	p.Audit = log
+
+	l, err := listen(opts.Listen, opts.TLS, opts.Insecure)
+	if err != nil {
+		return err
+	}
+	p.Logger.Info("rcon: proxy listening", "address", l.Addr(), "upstreams", len(clients), "tls", opts.TLS.Enabled())
+	return p.Server().Serve(l)
+}
+
+// A connection pool to each upstream. WebRCON connections carry several
+// commands at once, so one redialed when it fails takes the pool's place.
+func pools(upstreams map[string]Upstream) map[string]conn.Client {
+	clients := map[string]conn.Client{}
+	for name, u := range upstreams {
+		if u.Protocol == "webrcon" {
+			clients[name] = &redialer{server: u}
+		} else {
+			clients[name] = conn.NewPool(u.HostUri, u.Password, 4, dialOptions...)
+		}
+	}
+	return clients
+}
+
+// Client on a connection dialed when first needed, and again after it
+// closed
+type redialer struct {
+	server Upstream
+	lock   sync.Mutex
+	conn   conn.Conn
+	closed bool
+}
+
+func (r *redialer) ExecuteContext(ctx context.Context, cmd string) (string, error) {
+	c, err := r.get()
+	if err != nil {
+		return "", err
+	}
+	response, err := c.ExecuteContext(ctx, cmd)
+	if closedByServer(err) {
+		r.drop(c)
+	}
+	return response, err
+}
+
+func (r *redialer) get() (conn.Conn, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return nil, conn.ErrorClosed
+	}
+	if r.conn == nil {
+		c, err := connectOnce(r.server)
+		if err != nil {
+			return nil, err
+		}
+		r.conn = c
+	}
+	return r.conn, nil
+}
+
+// Forget c, unless already replaced
+func (r *redialer) drop(c conn.Conn) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.conn == c {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
+
+func (r *redialer) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.closed = true
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+func closeAll(clients map[string]conn.Client) {
+	for _, c := range clients {
+		c.Close()
+	}
+}
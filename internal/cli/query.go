@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/query"
+	"io"
+	"strings"
+)
+
+// Print the server's Query stat, the full stat with full set, returns
+// false if the server didn't answer
+func Query(address string, out io.Writer, full bool) bool {
+	c, err := query.Dial(address)
+	if err != nil {
+		fmt.Fprintln(out, "Query error: ", err.Error())
+		return false
+	}
+	defer c.Close()
+
+	if !full {
+		s, err := c.Basic(context.Background())
+		if err != nil {
+			fmt.Fprintln(out, "Query error: ", err.Error())
+			return false
+		}
+		printQuery(out, s)
+		return true
+	}
+
+	s, err := c.Full(context.Background())
+	if err != nil {
+		fmt.Fprintln(out, "Query error: ", err.Error())
+		return false
+	}
+	printQuery(out, &s.BasicStat)
+	fmt.Fprintln(out, "Version:    ", s.Version)
+	if s.Software != "" {
+		fmt.Fprintln(out, "Software:   ", s.Software)
+	}
+	fmt.Fprintln(out, "Plugins:    ", strings.Join(s.Plugins, ", "))
+	fmt.Fprintln(out, "Online:     ", strings.Join(s.PlayerNames, ", "))
+	return true
+}
+
+func printQuery(out io.Writer, s *query.BasicStat) {
+	fmt.Fprintln(out, "MOTD:       ", s.MOTD)
+	fmt.Fprintln(out, "Game type:  ", s.GameType)
+	fmt.Fprintln(out, "Map:        ", s.Map)
+	fmt.Fprintf(out, "Players:     %d/%d\n", s.Players, s.MaxPlayers)
+	fmt.Fprintf(out, "Address:     %s:%d\n", s.Host, s.Port)
+}
@@ -0,0 +1,464 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	homedir "github.com/mitchellh/go-homedir"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// History kept between sessions, in the home directory
+	historyFile = ".rcon_history"
+	historyMax  = 1000
+)
+
+// Keys sent as escape sequences
+const (
+	keyUnknown rune = -(iota + 1)
+	keyEscape
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+)
+
+// Source of commands typed in an interactive session
+type lineReader interface {
+	// Next line without the line ending, io.EOF when input ends
+	ReadLine() (string, error)
+}
+
+// Line editing when in is a terminal, plain lines otherwise
+func newLineReader(in io.Reader, out io.Writer) lineReader {
+	if f, ok := in.(*os.File); ok && isTerminal(int(f.Fd())) {
+		return newEditor(f, out, historyPath())
+	}
+	return &scanReader{input: bufio.NewScanner(in), out: out}
+}
+
+// Whether f is a terminal, typed at rather than piped
+func IsTerminal(f *os.File) bool {
+	return isTerminal(int(f.Fd()))
+}
+
+// Lines read as they come, for piped input
+type scanReader struct {
+	input *bufio.Scanner
+	out   io.Writer
+}
+
+func (r *scanReader) ReadLine() (string, error) {
+	r.out.Write([]byte(prompt))
+	if r.input.Scan() {
+		return r.input.Text(), nil
+	}
+	if err := r.input.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// Terminal line editor with history: arrows move and browse history, Ctrl-R
+// searches it, Tab completes, Ctrl-C drops the line and Ctrl-D on an empty
+// line ends input
+type editor struct {
+	fd      int
+	in      *bufio.Reader
+	out     io.Writer
+	path    string // history file, empty to keep history in memory only
+	history []string
+	browse  int    // history entry shown, len(history) for the line typed
+	pending string // line typed before browsing history
+	line    []rune
+	pos     int
+
+	// Where the word ending head starts and what it could be, nil to not
+	// complete
+	complete func(head string) (start int, candidates []string)
+}
+
+func newEditor(f *os.File, out io.Writer, path string) *editor {
+	e := &editor{
+		fd:   int(f.Fd()),
+		in:   bufio.NewReader(f),
+		out:  out,
+		path: path,
+	}
+	e.loadHistory()
+	return e
+}
+
+func historyPath() string {
+	home, err := homedir.Dir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFile)
+}
+
+func (e *editor) ReadLine() (string, error) {
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	e.line, e.pos = e.line[:0], 0
+	e.browse, e.pending = len(e.history), ""
+	e.refresh()
+
+	var last rune
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+		tabbed := last == '\t'
+		last = key
+		if key == ctrl('R') {
+			if key, err = e.search(); err != nil {
+				return "", err
+			}
+		}
+
+		switch key {
+		case '\r', '\n':
+			e.out.Write([]byte("\r\n"))
+			line := string(e.line)
+			e.remember(line)
+			return line, nil
+		case ctrl('C'):
+			e.out.Write([]byte("^C\r\n"))
+			e.line, e.pos = e.line[:0], 0
+			e.browse, e.pending = len(e.history), ""
+		case ctrl('D'):
+			if len(e.line) == 0 {
+				e.out.Write([]byte("\r\n"))
+				return "", io.EOF
+			}
+			e.delete(e.pos, e.pos+1)
+		case 0x7f, ctrl('H'):
+			e.delete(e.pos-1, e.pos)
+		case keyDelete:
+			e.delete(e.pos, e.pos+1)
+		case keyLeft, ctrl('B'):
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyRight, ctrl('F'):
+			if e.pos < len(e.line) {
+				e.pos++
+			}
+		case keyHome, ctrl('A'):
+			e.pos = 0
+		case keyEnd, ctrl('E'):
+			e.pos = len(e.line)
+		case keyUp, ctrl('P'):
+			e.previous()
+		case keyDown, ctrl('N'):
+			e.next()
+		case ctrl('U'):
+			e.delete(0, e.pos)
+		case ctrl('K'):
+			e.delete(e.pos, len(e.line))
+		case ctrl('W'):
+			start := e.pos
+			for start > 0 && e.line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && e.line[start-1] != ' ' {
+				start--
+			}
+			e.delete(start, e.pos)
+		case ctrl('L'):
+			e.out.Write([]byte("\x1b[H\x1b[2J"))
+		case '\t':
+			e.completeWord(tabbed)
+		default:
+			if key >= 0 && unicode.IsPrint(key) {
+				e.line = append(e.line, 0)
+				copy(e.line[e.pos+1:], e.line[e.pos:])
+				e.line[e.pos] = key
+				e.pos++
+			}
+		}
+		e.refresh()
+	}
+}
+
+// Incremental reverse search of the history. Returns the key that ended
+// it, for ReadLine to handle with the line set to the match.
+func (e *editor) search() (rune, error) {
+	var query []rune
+	match := -1
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(e.history[i], string(query)) {
+				match = i
+				return
+			}
+		}
+	}
+
+	for {
+		shown := ""
+		if match >= 0 {
+			shown = e.history[match]
+		}
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), shown)
+
+		key, err := e.readKey()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case key == ctrl('R'):
+			if match > 0 && len(query) > 0 {
+				find(match - 1)
+			}
+		case key == 0x7f || key == ctrl('H'):
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match = -1
+				if len(query) > 0 {
+					find(len(e.history) - 1)
+				}
+			}
+		case key == ctrl('G'):
+			// Back to the line as it was
+			return keyUnknown, nil
+		case key >= 0 && unicode.IsPrint(key):
+			query = append(query, key)
+			if match < 0 {
+				match = len(e.history) - 1
+			}
+			find(match)
+		default:
+			if match >= 0 {
+				e.line = append(e.line[:0], []rune(e.history[match])...)
+				e.pos = len(e.line)
+				e.browse = match
+			}
+			return key, nil
+		}
+	}
+}
+
+// Complete the word before the cursor as far as the candidates agree, or
+// list them on a second Tab
+func (e *editor) completeWord(tabbed bool) {
+	if e.complete == nil {
+		return
+	}
+	head := string(e.line[:e.pos])
+	start, candidates := e.complete(head)
+	word := head[start:]
+
+	switch {
+	case len(candidates) == 1:
+		e.replaceWord(start, candidates[0]+" ")
+	case len(candidates) > 1 && len(commonPrefix(candidates)) > len(word):
+		e.replaceWord(start, commonPrefix(candidates))
+	case len(candidates) > 1 && tabbed:
+		e.out.Write([]byte("\r\n" + strings.Join(candidates, "  ") + "\r\n"))
+	default:
+		e.out.Write([]byte("\a"))
+	}
+}
+
+// Replace the line from byte offset start to the cursor with s
+func (e *editor) replaceWord(start int, s string) {
+	from := utf8.RuneCountInString(string(e.line[:e.pos])[:start])
+	tail := append([]rune(s), e.line[e.pos:]...)
+	e.line = append(e.line[:from], tail...)
+	e.pos = from + utf8.RuneCountInString(s)
+}
+
+func (e *editor) previous() {
+	if e.browse == 0 {
+		return
+	}
+	if e.browse == len(e.history) {
+		e.pending = string(e.line)
+	}
+	e.browse--
+	e.setLine(e.history[e.browse])
+}
+
+func (e *editor) next() {
+	if e.browse == len(e.history) {
+		return
+	}
+	e.browse++
+	if e.browse == len(e.history) {
+		e.setLine(e.pending)
+		return
+	}
+	e.setLine(e.history[e.browse])
+}
+
+func (e *editor) setLine(s string) {
+	e.line = append(e.line[:0], []rune(s)...)
+	e.pos = len(e.line)
+}
+
+// Remove line[from:to], clamped to the line
+func (e *editor) delete(from int, to int) {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(e.line) {
+		to = len(e.line)
+	}
+	if from >= to {
+		return
+	}
+	e.line = append(e.line[:from], e.line[to:]...)
+	e.pos = from
+}
+
+// Redraw prompt and line, leaving the cursor at pos
+func (e *editor) refresh() {
+	var buf bytes.Buffer
+	buf.WriteString("\r" + prompt + string(e.line) + "\x1b[K")
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Fprintf(&buf, "\x1b[%dD", back)
+	}
+	e.out.Write(buf.Bytes())
+}
+
+func (e *editor) readKey() (rune, error) {
+	return readKey(e.in)
+}
+
+// Next key pressed, escape sequences as one of the key constants
+func readKey(in *bufio.Reader) (rune, error) {
+	r, _, err := in.ReadRune()
+	if err != nil || r != 0x1b {
+		return r, err
+	}
+	// Terminals send a sequence in one go, a lone escape is the key itself
+	if in.Buffered() == 0 {
+		return keyEscape, nil
+	}
+	b, err := in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != '[' && b != 'O' {
+		return keyUnknown, nil
+	}
+
+	var params []byte
+	for {
+		c, err := in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c >= 0x40 && c <= 0x7e {
+			b = c
+			break
+		}
+		params = append(params, c)
+	}
+	switch b {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	case '~':
+		switch string(params) {
+		case "1", "7":
+			return keyHome, nil
+		case "4", "8":
+			return keyEnd, nil
+		case "3":
+			return keyDelete, nil
+		}
+	}
+	return keyUnknown, nil
+}
+
+func (e *editor) loadHistory() {
+	if e.path == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(e.path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > historyMax {
+		e.history = e.history[len(e.history)-historyMax:]
+		// Keep the file from growing without bound
+		trimmed := strings.Join(e.history, "\n") + "\n"
+		ioutil.WriteFile(e.path, []byte(trimmed), 0600)
+	}
+}
+
+// Add a line to the history, saving it right away so it survives a crash
+func (e *editor) remember(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > historyMax {
+		e.history = append([]string(nil), e.history[len(e.history)-historyMax:]...)
+	}
+
+	if e.path == "" {
+		return
+	}
+	f, err := os.OpenFile(e.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(line + "\n")
+}
+
+// Longest prefix shared by all of words
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// Not ending part way through a character
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// Control character for letter c
+func ctrl(c rune) rune {
+	return c & 0x1f
+}
@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Single recorded command (one JSON object per line). A file appended to
+// by several sessions holds each one's entries under its own session id.
+type RecordEntry struct {
+	Time     time.Time `json:"time"`
+	Offset   int64     `json:"offset_ms"` // since start of session
+	Command  string    `json:"command"`
+	Response string    `json:"response,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Server   string    `json:"server,omitempty"`
+	User     string    `json:"user,omitempty"` // local user who sent the command
+	Session  string    `json:"session,omitempty"`
+	Started  time.Time `json:"session_start,omitempty"`
+}
+
+// Captures commands sent during a session and their responses, appending
+// them to a file
+type Recorder struct {
+	path    string
+	server  string
+	user    string
+	session string
+	file    *os.File
+	enc     *json.Encoder
+	start   time.Time
+	lock    sync.Mutex
+}
+
+func NewRecorder(path string, server string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	r := &Recorder{
+		path:    path,
+		server:  server,
+		user:    currentUser(),
+		session: newSessionId(start),
+		file:    file,
+		enc:     json.NewEncoder(file),
+		start:   start,
+	}
+	return r, nil
+}
+
+// Random id telling sessions recorded to one file apart
+func newSessionId(start time.Time) string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return strconv.FormatInt(start.UnixNano(), 16)
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// Record a command sent at sent, with its response or error
+func (r *Recorder) Record(sent time.Time, cmd string, response string, err error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	entry := RecordEntry{
+		Time:     sent,
+		Offset:   sent.Sub(r.start).Milliseconds(),
+		Command:  cmd,
+		Response: response,
+		Server:   r.server,
+		User:     r.user,
+		Session:  r.session,
+		Started:  r.start,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return r.enc.Encode(entry)
+}
+
+func (r *Recorder) setServer(server string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.server = server
+}
+
+// File recorded to
+func (r *Recorder) GetPath() string {
+	return r.path
+}
+
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// Handle ":record [path | off]" typed in a session: start recording to
+// path, stop, or without an argument tell where the session is recorded.
+// Returns the recorder to use from then on.
+func recordCommand(arg string, rec *Recorder, server string, out io.Writer) *Recorder {
+	switch arg {
+	case "":
+		if rec == nil {
+			fmt.Fprintln(out, "Not recording")
+		} else {
+			fmt.Fprintln(out, "Recording to", rec.GetPath())
+		}
+		return rec
+	case "off":
+		if rec != nil {
+			rec.Close()
+			fmt.Fprintln(out, "Stopped recording to", rec.GetPath())
+		}
+		return nil
+	}
+
+	next, err := NewRecorder(arg, server)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Record error: ", err.Error())
+		return rec
+	}
+	if rec != nil {
+		rec.Close()
+	}
+	fmt.Fprintln(out, "Recording to", arg)
+	return next
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Read all entries from a recorded session
+func ReadRecording(in io.Reader) ([]RecordEntry, error) {
+	var entries []RecordEntry
+	input := bufio.NewScanner(in)
+	line := 0
+	for input.Scan() {
+		line++
+		if len(input.Bytes()) == 0 {
+			continue
+		}
+		var entry RecordEntry
+		if err := json.Unmarshal(input.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("recording: line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := input.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Re-run recorded commands, keeping their relative timing (divided by speed).
+// Sessions appended to one file are replayed one after the other, each
+// timed from its own start.
+func Replay(server Upstream, out io.Writer, entries []RecordEntry, speed float64) {
+	if speed <= 0 {
+		speed = 1
+	}
+
+	// Connect
+	conn := dial(server)
+	defer conn.Close()
+
+	var start time.Time
+	for i, entry := range entries {
+		if i == 0 || startsSession(entries[i-1], entry) {
+			start = time.Now()
+			if entry.Session != "" {
+				fmt.Fprintf(out, "# session %s, recorded on %s at %s\n", entry.Session, entry.Server, entry.Started.Format(time.RFC3339))
+			}
+		}
+		due := start.Add(time.Duration(float64(entry.Offset) / speed * float64(time.Millisecond)))
+		time.Sleep(time.Until(due))
+
+		fmt.Fprintln(out, prompt+entry.Command)
+		response, err := conn.ExecuteContext(context.Background(), entry.Command)
+		if closedByServer(err) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Replay error: ", err.Error())
+			continue
+		}
+
+		print(out, response)
+	}
+}
+
+// Whether entry was recorded by another session than prev, going by the
+// session ids or, in recordings without them, the offset going back
+func startsSession(prev RecordEntry, entry RecordEntry) bool {
+	if prev.Session != "" || entry.Session != "" {
+		return entry.Session != prev.Session
+	}
+	return entry.Offset < prev.Offset
+}
+
+// Entries recorded by the session with id
+func SessionEntries(entries []RecordEntry, id string) []RecordEntry {
+	var session []RecordEntry
+	for _, entry := range entries {
+		if entry.Session == id {
+			session = append(session, entry)
+		}
+	}
+	return session
+}
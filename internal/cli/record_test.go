@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAppendSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.rcon")
+	for _, server := range []string{"lobby:25575", "survival:25575"} {
+		rec, err := NewRecorder(path, server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rec.Record(time.Now(), "list", "ran list", nil); err != nil {
+			t.Fatal(err)
+		}
+		rec.Close()
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	entries, err := ReadRecording(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d entries, want 2", len(entries))
+	}
+	first, second := entries[0], entries[1]
+	if first.Session == "" || first.Session == second.Session {
+		t.Fatalf("sessions %q and %q", first.Session, second.Session)
+	}
+	if first.Started.IsZero() || second.Started.Before(first.Started) {
+		t.Errorf("sessions started at %v and %v", first.Started, second.Started)
+	}
+	if !startsSession(first, second) {
+		t.Error("second session not told apart from the first")
+	}
+	if got := SessionEntries(entries, second.Session); len(got) != 1 || got[0].Server != "survival:25575" {
+		t.Errorf("second session's entries %+v", got)
+	}
+}
+
+func TestStartsSession(t *testing.T) {
+	tests := []struct {
+		name        string
+		prev, entry RecordEntry
+		want        bool
+	}{
+		{"same session", RecordEntry{Session: "a", Offset: 500}, RecordEntry{Session: "a", Offset: 900}, false},
+		{"other session", RecordEntry{Session: "a", Offset: 500}, RecordEntry{Session: "b", Offset: 900}, true},
+		{"ids from then on", RecordEntry{Offset: 500}, RecordEntry{Session: "b", Offset: 900}, true},
+		{"no ids, offset goes on", RecordEntry{Offset: 500}, RecordEntry{Offset: 900}, false},
+		{"no ids, offset restarts", RecordEntry{Offset: 500}, RecordEntry{Offset: 0}, true},
+	}
+	for _, tt := range tests {
+		if got := startsSession(tt.prev, tt.entry); got != tt.want {
+			t.Errorf("%s: got %v", tt.name, got)
+		}
+	}
+}
+
+// Each session is timed from its own start, not fired at once after the
+// first
+func TestReplaySessionTiming(t *testing.T) {
+	server, commands := stoppingServer(t)
+	entries := []RecordEntry{
+		{Session: "a", Offset: 0, Command: "say a1"},
+		{Session: "a", Offset: 1000, Command: "say a2"},
+		{Session: "b", Offset: 0, Command: "say b1"},
+		{Session: "b", Offset: 1000, Command: "say b2"},
+	}
+
+	var out bytes.Buffer
+	start := time.Now()
+	Replay(server, &out, entries, 10)
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("replayed in %v, want at least 200ms", elapsed)
+	}
+	if got := strings.Join(commands(), ","); got != "say a1,say a2,say b1,say b2" {
+		t.Errorf("server received %s", got)
+	}
+	if n := strings.Count(out.String(), "# session "); n != 2 {
+		t.Errorf("%d session headers in\n%s", n, out.String())
+	}
+}
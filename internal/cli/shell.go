@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Interactive session settings
+type SessionOptions struct {
+	// Where commands are recorded from the start, nil for nowhere
+	Record *Recorder
+	// Server of a profile, for :server. Nil when there are no profiles.
+	Resolve func(profile string) (Upstream, error)
+}
+
+// Client-side commands of the shell
+const shellHelp = `:quit               end the session
+:reconnect          connect and log in again
+:server [profile]   switch to a server profile, or show the server
+:timeout [duration] time limit of each command, 0 for none
+:raw [on|off]       print responses with formatting codes as sent
+:history            list commands typed before
+:record [file|off]  append commands and responses to a file, or stop`
+
+// Interactive session on one server at a time
+type shell struct {
+	conn    conn.Client
+	server  Upstream
+	opts    SessionOptions
+	input   lineReader
+	out     io.Writer
+	rec     *Recorder
+	timeout time.Duration // zero for none
+	raw     bool
+}
+
+func (s *shell) run(in io.Reader) {
+	// Recorders started with :record are the session's to close
+	defer func() {
+		if s.rec != nil && s.rec != s.opts.Record {
+			s.rec.Close()
+		}
+	}()
+
+	// Input, edited with history on a terminal
+	s.input = newLineReader(in, s.out)
+	s.connected()
+	for {
+		cmd, err := s.input.ReadLine()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error from input:", err)
+			return
+		}
+
+		if strings.HasPrefix(cmd, ":") {
+			if !s.meta(cmd) {
+				return
+			}
+			continue
+		}
+		if len(cmd) > 0 && !s.execute(cmd) {
+			return
+		}
+	}
+}
+
+// Send a command, false when the server closed the connection
+func (s *shell) execute(cmd string) bool {
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	sent := time.Now()
+	response, err := s.conn.ExecuteContext(ctx, cmd)
+	if closedByServer(err) {
+		return false
+	}
+	if s.rec != nil {
+		if rerr := s.rec.Record(sent, cmd, response, err); rerr != nil {
+			fmt.Fprintln(os.Stderr, "Record error: ", rerr.Error())
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Run error: ", err.Error())
+		return true
+	}
+
+	if s.raw {
+		fmt.Fprintln(s.out, response)
+	} else {
+		print(s.out, response)
+	}
+	return true
+}
+
+// Run a :command, false to end the session
+func (s *shell) meta(line string) bool {
+	fields := strings.Fields(line)
+	name, arg := fields[0], strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+
+	switch name {
+	case ":quit", ":q", ":exit":
+		return false
+	case ":reconnect":
+		s.connect(s.server)
+	case ":server":
+		s.switchServer(arg)
+	case ":timeout":
+		s.setTimeout(arg)
+	case ":raw":
+		switch arg {
+		case "on":
+			s.raw = true
+		case "off":
+			s.raw = false
+		case "":
+		default:
+			fmt.Fprintln(os.Stderr, "Usage: :raw [on|off]")
+			return true
+		}
+		fmt.Fprintln(s.out, "Raw output", onOff(s.raw))
+	case ":history":
+		s.history()
+	case ":record":
+		s.rec = recordCommand(arg, s.rec, s.server.HostUri, s.out)
+	case ":help":
+		fmt.Fprintln(s.out, shellHelp)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown shell command %s, :help lists them\n", name)
+	}
+	return true
+}
+
+// Replace the connection with a new one, keeping the old on failure
+func (s *shell) connect(server Upstream) bool {
+	c, err := connect(server)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to connect to RCON server: ", err)
+		return false
+	}
+	s.conn.Close()
+	s.conn, s.server = c, server
+	s.connected()
+	fmt.Fprintln(s.out, "Connected to", server.HostUri)
+	return true
+}
+
+// Set up what depends on the connection
+func (s *shell) connected() {
+	if e, ok := s.input.(*editor); ok {
+		e.complete = newCompleter(s.conn).complete
+	}
+	if s.rec != nil {
+		s.rec.setServer(s.server.HostUri)
+	}
+}
+
+func (s *shell) switchServer(profile string) {
+	if profile == "" {
+		fmt.Fprintln(s.out, "Connected to", s.server.HostUri)
+		return
+	}
+	if s.opts.Resolve == nil {
+		fmt.Fprintln(os.Stderr, "No server profiles to switch to")
+		return
+	}
+	server, err := s.opts.Resolve(profile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	s.connect(server)
+}
+
+func (s *shell) setTimeout(arg string) {
+	switch arg {
+	case "":
+	case "0", "off":
+		s.timeout = 0
+	default:
+		d, err := time.ParseDuration(arg)
+		if err != nil || d < 0 {
+			fmt.Fprintln(os.Stderr, "Usage: :timeout [duration], like 30s, or 0 for none")
+			return
+		}
+		s.timeout = d
+	}
+	if s.timeout == 0 {
+		fmt.Fprintln(s.out, "No command timeout")
+	} else {
+		fmt.Fprintln(s.out, "Command timeout", s.timeout)
+	}
+}
+
+func (s *shell) history() {
+	e, ok := s.input.(*editor)
+	if !ok || len(e.history) == 0 {
+		fmt.Fprintln(s.out, "No history")
+		return
+	}
+	for i, line := range e.history {
+		fmt.Fprintf(s.out, "%5d  %s\n", i+1, line)
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
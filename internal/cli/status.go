@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/bedrock"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"github.com/StarForger/neb-mc-rcon/status"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Print the server's status, with performance, time and difficulty when
+// full is set, returns false if the status could not be read
+func Status(server Upstream, out io.Writer, full bool) bool {
+	c := dial(server)
+	defer c.Close()
+
+	status, err := mcapi.New(c).ServerStatus(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Status error: ", err.Error())
+		return false
+	}
+
+	v := status.Version
+	version := v.Brand
+	if v.MinecraftVersion != "" {
+		version += " " + v.MinecraftVersion
+	}
+	if v.Build != "" {
+		version += " (build " + v.Build + ")"
+	}
+	fmt.Fprintln(out, "Version:    ", version)
+	fmt.Fprintf(out, "Players:     %d/%d %s\n", status.Players.Online, status.Players.Max, strings.Join(status.Players.Names, ", "))
+
+	if full {
+		if perf := status.Performance; perf != nil {
+			for _, window := range sortedKeys(perf.TPS) {
+				fmt.Fprintf(out, "TPS %-8s %.2f\n", window+":", perf.TPS[window])
+			}
+			for _, window := range sortedKeys(perf.MSPT) {
+				t := perf.MSPT[window]
+				fmt.Fprintf(out, "MSPT %-7s %.1f avg, %.1f min, %.1f max\n", window+":", t.Avg, t.Min, t.Max)
+			}
+			for _, dim := range sortedKeys(perf.Dimensions) {
+				d := perf.Dimensions[dim]
+				fmt.Fprintf(out, "TPS %s: %.2f (%.1f ms/tick)\n", dim, d.TPS, d.MSPT)
+			}
+		}
+		if status.Errors["time"] == nil {
+			fmt.Fprintln(out, "Day time:   ", status.DayTime)
+			fmt.Fprintln(out, "Game time:  ", status.GameTime)
+		}
+		if status.Errors["difficulty"] == nil {
+			fmt.Fprintln(out, "Difficulty: ", status.Difficulty)
+		}
+	}
+
+	for _, part := range sortedKeys(status.Errors) {
+		if full || part == "version" || part == "players" {
+			fmt.Fprintf(out, "Unavailable: %s: %v\n", part, status.Errors[part])
+		}
+	}
+	return true
+}
+
+// Print the status the server list shows, fetched with a Server List Ping
+// and so without the password, returns false if the server didn't answer
+func PingStatus(address string, out io.Writer) bool {
+	s, err := status.Ping(context.Background(), address)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Status error: ", err.Error())
+		return false
+	}
+
+	var names []string
+	for _, p := range s.Players.Sample {
+		names = append(names, p.Name)
+	}
+	fmt.Fprintln(out, "MOTD:       ", s.Description.PlainText())
+	fmt.Fprintf(out, "Version:     %s (protocol %d)\n", s.Version.Name, s.Version.Protocol)
+	fmt.Fprintf(out, "Players:     %d/%d %s\n", s.Players.Online, s.Players.Max, strings.Join(names, ", "))
+	if s.Latency > 0 {
+		fmt.Fprintln(out, "Latency:    ", s.Latency.Round(time.Millisecond))
+	}
+	if s.Favicon != "" {
+		fmt.Fprintln(out, "Favicon:     yes")
+	}
+	return true
+}
+
+// Print the status of a Bedrock server, fetched with a RakNet unconnected
+// ping, returns false if the server didn't answer
+func BedrockStatus(address string, out io.Writer) bool {
+	s, err := bedrock.Ping(context.Background(), address)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Status error: ", err.Error())
+		return false
+	}
+
+	fmt.Fprintln(out, "Edition:    ", s.Edition)
+	fmt.Fprintln(out, "MOTD:       ", s.MOTD)
+	fmt.Fprintf(out, "Version:     %s (protocol %d)\n", s.Version, s.Protocol)
+	fmt.Fprintf(out, "Players:     %d/%d\n", s.Players, s.MaxPlayers)
+	if s.LevelName != "" {
+		fmt.Fprintln(out, "Level:      ", s.LevelName)
+	}
+	if s.GameMode != "" {
+		fmt.Fprintln(out, "Game mode:  ", s.GameMode)
+	}
+	fmt.Fprintln(out, "Latency:    ", s.Latency.Round(time.Millisecond))
+	return true
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]float64:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]mcapi.TickTimes:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]mcapi.DimensionPerformance:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]error:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,24 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package cli
+
+import "errors"
+
+// Line editing needs termios or a Windows console, elsewhere input is read a
+// line at a time
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (restore func() error, err error) {
+	return nil, errors.New("cli: raw terminal mode not supported")
+}
+
+func readPassword(fd int) ([]byte, error) {
+	return nil, errors.New("cli: hidden input not supported")
+}
+
+func terminalSize(fd int) (width int, height int, err error) {
+	return 0, 0, errors.New("cli: terminal size not supported")
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Console modes, from wincon.h
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableProcessedOutput           = 0x0001
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+func getConsoleMode(fd int) (uint32, error) {
+	var mode uint32
+	err := syscall.GetConsoleMode(syscall.Handle(fd), &mode)
+	return mode, err
+}
+
+func setConsoleMode(fd int, mode uint32) error {
+	r, _, err := procSetConsoleMode.Call(uintptr(fd), uintptr(mode))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+// Whether fd is a console
+func isTerminal(fd int) bool {
+	_, err := getConsoleMode(fd)
+	return err == nil
+}
+
+// Put the console into raw mode, reading a key at a time without echo, with
+// keys such as the arrows sent as the escape sequences a terminal would
+// send. Escape sequences written to stdout are interpreted, as the line
+// editor and dashboard write them.
+func makeRaw(fd int) (restore func() error, err error) {
+	old, err := getConsoleMode(fd)
+	if err != nil {
+		return nil, err
+	}
+	raw := old &^ (enableProcessedInput | enableLineInput | enableEchoInput)
+	raw |= enableVirtualTerminalInput
+	if err := setConsoleMode(fd, raw); err != nil {
+		return nil, err
+	}
+
+	out := int(os.Stdout.Fd())
+	oldOut, err := getConsoleMode(out)
+	if err != nil {
+		setConsoleMode(fd, old)
+		return nil, err
+	}
+	if err := setConsoleMode(out, oldOut|enableProcessedOutput|enableVirtualTerminalProcessing); err != nil {
+		setConsoleMode(fd, old)
+		return nil, err
+	}
+
+	return func() error {
+		setConsoleMode(out, oldOut)
+		return setConsoleMode(fd, old)
+	}, nil
+}
+
+// Read a line typed on the console without echoing it
+func readPassword(fd int) ([]byte, error) {
+	old, err := getConsoleMode(fd)
+	if err != nil {
+		return nil, err
+	}
+	mode := old&^enableEchoInput | enableProcessedInput | enableLineInput
+	if err := setConsoleMode(fd, mode); err != nil {
+		return nil, err
+	}
+	defer setConsoleMode(fd, old)
+
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := syscall.Read(syscall.Handle(fd), buf)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			if len(line) == 0 {
+				return nil, io.EOF
+			}
+			return line, nil
+		}
+		switch buf[0] {
+		case '\n':
+			return line, nil
+		case '\r':
+		default:
+			line = append(line, buf[0])
+		}
+	}
+}
+
+type consoleScreenBufferInfo struct {
+	size              struct{ x, y int16 }
+	cursorPosition    struct{ x, y int16 }
+	attributes        uint16
+	window            struct{ left, top, right, bottom int16 }
+	maximumWindowSize struct{ x, y int16 }
+}
+
+// Columns and rows of the console window showing stdout
+func terminalSize(fd int) (width int, height int, err error) {
+	var info consoleScreenBufferInfo
+	r, _, err := procGetConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 0, 0, err
+	}
+	return int(info.window.right-info.window.left) + 1, int(info.window.bottom-info.window.top) + 1, nil
+}
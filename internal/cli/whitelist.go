@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/mcapi"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Read player names from a server style whitelist.json or
+// banned-players.json, or a text file with one name per line ("#" starts a
+// comment)
+func ReadNames(in io.Reader) ([]string, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var entries []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if e.Name != "" {
+				names = append(names, e.Name)
+			}
+		}
+		return names, nil
+	}
+
+	var names []string
+	input := bufio.NewScanner(bytes.NewReader(data))
+	for input.Scan() {
+		line := input.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, input.Err()
+}
+
+// Apply desired, names or UUIDs, to the server's whitelist and report the
+// changes, returns false if anything failed. With a resolver UUIDs are
+// resolved and the names reported with theirs.
+func WhitelistSync(server Upstream, out io.Writer, desired []string, resolver mcapi.Resolver, dryRun bool) bool {
+	c := dial(server)
+	defer c.Close()
+
+	ctx := context.Background()
+	client := mcapi.New(c)
+	client.SetResolver(resolver)
+	whitelist := client.Whitelist()
+	label := labeller(ctx, client, resolver)
+
+	if dryRun {
+		add, remove, err := whitelist.Plan(ctx, desired)
+		if err != nil {
+			fmt.Fprintln(out, "Whitelist error: ", err.Error())
+			return false
+		}
+		for _, name := range add {
+			fmt.Fprintln(out, "would add", label(name))
+		}
+		for _, name := range remove {
+			fmt.Fprintln(out, "would remove", label(name))
+		}
+		fmt.Fprintf(out, "%d to add, %d to remove\n", len(add), len(remove))
+		return true
+	}
+
+	result, err := whitelist.Sync(ctx, desired)
+	if err != nil {
+		fmt.Fprintln(out, "Whitelist error: ", err.Error())
+		return false
+	}
+	for _, name := range result.Added {
+		fmt.Fprintln(out, "added", label(name))
+	}
+	for _, name := range result.Removed {
+		fmt.Fprintln(out, "removed", label(name))
+	}
+	for name, err := range result.Failed {
+		fmt.Fprintln(out, "failed", name+":", err)
+	}
+	fmt.Fprintf(out, "%d added, %d removed, %d failed\n", len(result.Added), len(result.Removed), len(result.Failed))
+	return len(result.Failed) == 0
+}
+
+// Formats player names for reports, with their UUIDs when there is a
+// resolver
+func labeller(ctx context.Context, client *mcapi.Client, resolver mcapi.Resolver) func(string) string {
+	return func(name string) string {
+		if resolver == nil {
+			return name
+		}
+		if p := client.Profiles(ctx, []string{name})[0]; p.UUID != "" {
+			return name + " (" + p.UUID + ")"
+		}
+		return name
+	}
+}
@@ -0,0 +1,31 @@
+// Package keyring keeps server passwords in the operating system's
+// credential store: the macOS keychain, the Secret Service (GNOME Keyring,
+// KWallet) through secret-tool, or the Windows Credential Manager.
+package keyring
+
+import (
+	"errors"
+)
+
+// Service the passwords are stored under, with the profile as account
+const Service = "neb-mc-rcon"
+
+var (
+	ErrorNotFound    = errors.New("keyring: password not found")
+	ErrorUnsupported = errors.New("keyring: no keyring available")
+)
+
+// Password stored for account
+func Get(account string) (string, error) {
+	return get(Service, account)
+}
+
+// Store password for account, replacing any stored before
+func Set(account string, password string) error {
+	return set(Service, account, password)
+}
+
+// Remove the password stored for account
+func Delete(account string) error {
+	return remove(Service, account)
+}
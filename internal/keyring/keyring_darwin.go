@@ -0,0 +1,52 @@
+package keyring
+
+import (
+	"encoding/hex"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// Exit status of security when no item matches
+const securityNotFound = 44
+
+func get(service string, account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func set(service string, account string, password string) error {
+	// Commands are read from stdin, keeping the password out of the
+	// process list, and the password passed hex encoded to avoid quoting
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader("add-generic-password -U -s " + quote(service) + " -a " + quote(account) +
+		" -X " + hex.EncodeToString([]byte(password)) + "\n")
+	if out, err := cmd.CombinedOutput(); err != nil || len(out) > 0 {
+		return errors.New("keyring: " + strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func remove(service string, account string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", service, "-a", account).Run()
+	return securityError(err)
+}
+
+func securityError(err error) error {
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == securityNotFound {
+		return ErrorNotFound
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrorUnsupported
+	}
+	return err
+}
+
+// Single quoted for security's command parser
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package keyring
+
+func get(service string, account string) (string, error) {
+	return "", ErrorUnsupported
+}
+
+func set(service string, account string, password string) error {
+	return ErrorUnsupported
+}
+
+func remove(service string, account string) error {
+	return ErrorUnsupported
+}
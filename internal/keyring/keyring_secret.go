@@ -0,0 +1,51 @@
+//go:build dragonfly || freebsd || linux || netbsd || openbsd
+// +build dragonfly freebsd linux netbsd openbsd
+
+package keyring
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// The Secret Service through libsecret's secret-tool, which looks items up
+// by attributes
+func get(service string, account string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && len(exit.Stderr) == 0 {
+			// Nothing found, or locked and dismissed
+			return "", ErrorNotFound
+		}
+		return "", secretToolError(err)
+	}
+	return string(out), nil
+}
+
+func set(service string, account string, password string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", service+" "+account, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(password)
+	_, err := cmd.Output()
+	return secretToolError(err)
+}
+
+func remove(service string, account string) error {
+	if _, err := get(service, account); err != nil {
+		return err
+	}
+	_, err := exec.Command("secret-tool", "clear", "service", service, "account", account).Output()
+	return secretToolError(err)
+}
+
+func secretToolError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrorUnsupported
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && len(exit.Stderr) > 0 {
+		return errors.New("keyring: " + strings.TrimSpace(string(exit.Stderr)))
+	}
+	return err
+}
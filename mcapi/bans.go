@@ -0,0 +1,278 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrorAlreadyBanned  = errors.New("mcapi: already banned")
+	ErrorNotBanned      = errors.New("mcapi: not banned")
+	ErrorInvalidAddress = errors.New("mcapi: invalid IP address or unknown player")
+)
+
+var (
+	banBanned     = regexp.MustCompile(`^Banned (?:player )?(\S+)(?:: (.*))?$`)
+	banBannedIP   = regexp.MustCompile(`^Banned IP (\S+)(?:: (.*))?$`)
+	banPardoned   = regexp.MustCompile(`^Unbanned (?:player )?(\S+)$`)
+	banPardonedIP = regexp.MustCompile(`^Unbanned IP (\S+)$`)
+	// "There are 2 ban(s):" followed by one entry per line, and before 1.13
+	// "There are 2 total banned players:" followed by the names
+	banListHeader = regexp.MustCompile(`^There (?:are|is) (\d+) (?:ban\(s\)|bans?|total banned (?:players|IP addresses)):`)
+	banListEmpty  = regexp.MustCompile(`^There are no bans`)
+	// Start of an entry, "Steve was banned by Server: Banned by an operator."
+	// Vanilla sends the entries without separators, so the target must not
+	// take in the end of the reason before it, which can't be told apart
+	// when the reason ends in a letter or digit.
+	banEntry = regexp.MustCompile(`(\d{1,3}(?:\.\d{1,3}){3}|[0-9a-fA-F]*:[0-9a-fA-F:]+|\w+) was banned by `)
+
+	banFailures = []failure{
+		{regexp.MustCompile(`(?i)already banned`), ErrorAlreadyBanned},
+		{regexp.MustCompile(`(?i)isn't banned|is not banned`), ErrorNotBanned},
+		{regexp.MustCompile(`(?i)Invalid IP address`), ErrorInvalidAddress},
+	}
+)
+
+// Entry of the server's ban list
+type Ban struct {
+	Raw            // the entry's line
+	Target  string // player name or IP address
+	UUID    string // the player's, when resolved
+	Source  string // who issued the ban, when reported
+	Reason  string
+	Expires time.Time // zero for permanent bans or when not reported
+}
+
+type Bans struct {
+	client *Client
+}
+
+func (c *Client) Bans() *Bans {
+	return &Bans{client: c}
+}
+
+// Ban a player, an empty reason uses the server's default
+func (b *Bans) Ban(ctx context.Context, name string, reason string) error {
+	return b.run(ctx, "ban", name, reason, banBanned)
+}
+
+// Ban an IP address, or the address of an online player
+func (b *Bans) BanIP(ctx context.Context, address string, reason string) error {
+	return b.run(ctx, "ban-ip", address, reason, banBannedIP)
+}
+
+func (b *Bans) Pardon(ctx context.Context, name string) error {
+	return b.run(ctx, "pardon", name, "", banPardoned)
+}
+
+func (b *Bans) PardonIP(ctx context.Context, address string) error {
+	return b.run(ctx, "pardon-ip", address, "", banPardonedIP)
+}
+
+// Banned players
+func (b *Bans) List(ctx context.Context) ([]Ban, error) {
+	return b.list(ctx, "banlist players")
+}
+
+// Banned IP addresses
+func (b *Bans) ListIPs(ctx context.Context) ([]Ban, error) {
+	return b.list(ctx, "banlist ips")
+}
+
+func (b *Bans) run(ctx context.Context, cmd string, target string, reason string, success *regexp.Regexp) error {
+	if err := checkWord(target); err != nil {
+		return err
+	}
+	if strings.ContainsAny(reason, "\r\n") {
+		return fmt.Errorf("%w: reason contains a line break", ErrorInvalidArgument)
+	}
+
+	line := cmd + " " + target
+	if reason != "" {
+		line += " " + reason
+	}
+	response, err := b.client.execute(ctx, line)
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, success, banFailures...)
+	return err
+}
+
+func (b *Bans) list(ctx context.Context, cmd string) ([]Ban, error) {
+	response, err := b.client.execute(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	v, err := b.client.parse(ctx, cmd, response)
+	if err != nil {
+		return nil, err
+	}
+	bans, ok := v.([]Ban)
+	if !ok {
+		return nil, wrongType(cmd, v)
+	}
+	return bans, nil
+}
+
+// Parse the output of banlist players or banlist ips
+func ParseBanList(response string) ([]Ban, error) {
+	response = strings.TrimSpace(StripFormatting(response))
+	if banListEmpty.MatchString(response) {
+		return []Ban{}, nil
+	}
+
+	m := banListHeader.FindStringIndex(response)
+	if m == nil {
+		_, err := matchResponse(response, banListHeader, banFailures...)
+		return nil, err
+	}
+
+	bans := []Ban{}
+	body := response[m[1]:]
+	entries := banEntry.FindAllStringSubmatchIndex(body, -1)
+	if len(entries) == 0 {
+		// Before 1.13, names only, comma separated
+		for _, line := range strings.Split(body, "\n") {
+			line = strings.TrimSpace(line)
+			for _, name := range splitNames(line) {
+				bans = append(bans, Ban{Raw: Raw{line, true}, Target: name})
+			}
+		}
+		return bans, nil
+	}
+
+	// Each entry runs up to the next, or on Paper its line break
+	for i, e := range entries {
+		end := len(body)
+		if i+1 < len(entries) {
+			end = entries[i+1][0]
+		}
+		ban := Ban{
+			Raw:    Raw{strings.TrimSpace(body[e[0]:end]), true},
+			Target: body[e[2]:e[3]],
+		}
+		ban.Source = strings.TrimSpace(body[e[1]:end])
+		if j := strings.Index(ban.Source, ": "); j >= 0 {
+			ban.Source, ban.Reason = ban.Source[:j], strings.TrimSpace(ban.Source[j+2:])
+		}
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
+
+// Outcome of banning or pardoning players in bulk
+type BanSync struct {
+	Banned   []string
+	Pardoned []string
+	Failed   map[string]error // by player name
+}
+
+// Names to ban and pardon to turn the current bans into desired, ignoring
+// case
+func DiffBans(current []Ban, desired []string) (ban []string, pardon []string) {
+	names := make([]string, len(current))
+	for i, b := range current {
+		names[i] = b.Target
+	}
+	return diffNames(names, desired)
+}
+
+// Banned players with their UUIDs, as far as the client's resolver finds
+// them
+func (b *Bans) Resolved(ctx context.Context) ([]Ban, error) {
+	bans, err := b.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(bans))
+	for i, ban := range bans {
+		names[i] = ban.Target
+	}
+	for i, p := range b.client.Profiles(ctx, names) {
+		bans[i].UUID = p.UUID
+	}
+	return bans, nil
+}
+
+// Changes Sync would make, without applying them. Entries of desired may be
+// names or UUIDs, which fail the plan if they can't be resolved.
+func (b *Bans) Plan(ctx context.Context, desired []string) (ban []string, pardon []string, err error) {
+	names, failed := b.client.resolveNames(ctx, desired)
+	if err := firstFailure(failed); err != nil {
+		return nil, nil, err
+	}
+	current, err := b.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ban, pardon = DiffBans(current, names)
+	return ban, pardon, nil
+}
+
+// Make the server's player ban list match desired, names or UUIDs, banning
+// newly listed players with reason (empty for the server's default) and
+// pardoning the rest. Per player failures, UUIDs that can't be resolved
+// among them, are collected in the result rather than stopping the sync;
+// err is only set when the ban list could not be read. While any UUID is
+// unresolved nobody is pardoned, as they may be that player.
+func (b *Bans) Sync(ctx context.Context, desired []string, reason string) (*BanSync, error) {
+	names, failed := b.client.resolveNames(ctx, desired)
+	current, err := b.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ban, pardon := DiffBans(current, names)
+	if len(failed) > 0 {
+		pardon = nil
+	}
+
+	result := &BanSync{Failed: failed}
+	b.banAll(ctx, ban, reason, result)
+	b.pardonAll(ctx, pardon, result)
+	return result, nil
+}
+
+// Ban every name or UUID, those already banned counting as banned
+func (b *Bans) BanAll(ctx context.Context, names []string, reason string) *BanSync {
+	names, failed := b.client.resolveNames(ctx, names)
+	result := &BanSync{Failed: failed}
+	b.banAll(ctx, names, reason, result)
+	return result
+}
+
+// Pardon every name or UUID, those not banned counting as pardoned
+func (b *Bans) PardonAll(ctx context.Context, names []string) *BanSync {
+	names, failed := b.client.resolveNames(ctx, names)
+	result := &BanSync{Failed: failed}
+	b.pardonAll(ctx, names, result)
+	return result
+}
+
+func (b *Bans) banAll(ctx context.Context, names []string, reason string, result *BanSync) {
+	for _, name := range names {
+		err := b.Ban(ctx, name, reason)
+		switch {
+		case err == nil, errors.Is(err, ErrorAlreadyBanned):
+			result.Banned = append(result.Banned, name)
+		default:
+			result.Failed[name] = err
+		}
+	}
+}
+
+func (b *Bans) pardonAll(ctx context.Context, names []string, result *BanSync) {
+	for _, name := range names {
+		err := b.Pardon(ctx, name)
+		switch {
+		case err == nil, errors.Is(err, ErrorNotBanned):
+			result.Pardoned = append(result.Pardoned, name)
+		default:
+			result.Failed[name] = err
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package mcapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBanList(t *testing.T) {
+	type entry struct{ Target, Source, Reason string }
+	tests := []struct {
+		name     string
+		response string
+		want     []entry
+	}{
+		{
+			"vanilla",
+			"There are 3 ban(s):Steve was banned by Server: Banned by an operator.Alex was banned by Rcon: griefing: twice!192.168.0.7 was banned by Notch: Banned by an operator.",
+			[]entry{
+				{"Steve", "Server", "Banned by an operator."},
+				{"Alex", "Rcon", "griefing: twice!"},
+				{"192.168.0.7", "Notch", "Banned by an operator."},
+			},
+		},
+		{
+			"paper",
+			"§6There are 2 ban(s):\n§cSteve§6 was banned by §cServer§6: §cBanned by an operator.\n§cjeb_§6 was banned by §cConsole§6: §cSpam\n",
+			[]entry{
+				{"Steve", "Server", "Banned by an operator."},
+				{"jeb_", "Console", "Spam"},
+			},
+		},
+		{
+			"one ban",
+			"There is 1 ban(s):Steve was banned by Server: Banned by an operator.",
+			[]entry{{"Steve", "Server", "Banned by an operator."}},
+		},
+		{
+			"ipv6",
+			"There are 1 ban(s):2001:db8::1 was banned by Server: Banned by an operator.",
+			[]entry{{"2001:db8::1", "Server", "Banned by an operator."}},
+		},
+		{
+			"before 1.13",
+			"There are 2 total banned players:\nSteve, Alex",
+			[]entry{{"Steve", "", ""}, {"Alex", "", ""}},
+		},
+		{
+			"empty",
+			"There are no bans",
+			[]entry{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bans, err := ParseBanList(test.response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []entry{}
+			for _, b := range bans {
+				got = append(got, entry{b.Target, b.Source, b.Reason})
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffBans(t *testing.T) {
+	current, err := ParseBanList("There are 2 ban(s):Steve was banned by Server: Banned by an operator.Alex was banned by Server: Banned by an operator.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ban, pardon := DiffBans(current, []string{"alex", "Notch"})
+	if !reflect.DeepEqual(ban, []string{"Notch"}) || !reflect.DeepEqual(pardon, []string{"Steve"}) {
+		t.Errorf("got ban %q, pardon %q", ban, pardon)
+	}
+}
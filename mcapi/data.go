@@ -0,0 +1,75 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/mcapi/snbt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrorNoData         = errors.New("mcapi: no data matches path")
+	ErrorNotBlockEntity = errors.New("mcapi: target block is not a block entity")
+)
+
+var (
+	// "Steve has the following entity data: {...}",
+	// "10, 64, 10 has the following block data: {...}",
+	// "Storage minecraft:x has the following contents: {...}"
+	dataResult = regexp.MustCompile(`(?s)^.+? has the following (?:entity data|block data|contents): (.*)$`)
+
+	dataFailures = []failure{
+		{regexp.MustCompile(`(?i)Found no elements matching`), ErrorNoData},
+		{regexp.MustCompile(`(?i)not a block entity`), ErrorNotBlockEntity},
+		{regexp.MustCompile(`(?i)No entity was found`), ErrorPlayerNotFound},
+	}
+)
+
+// What data get reads from: an entity, a block entity or command storage
+type DataTarget struct {
+	kind string
+	arg  string
+}
+
+// Entity data of a single player or entity selector
+func EntityData(target string) DataTarget {
+	return DataTarget{kind: "entity", arg: target}
+}
+
+// Block entity data at pos
+func BlockData(pos Position) DataTarget {
+	return DataTarget{kind: "block", arg: pos.String()}
+}
+
+// Command storage by resource location, "minecraft:example"
+func StorageData(id string) DataTarget {
+	return DataTarget{kind: "storage", arg: id}
+}
+
+// Read NBT data, optionally narrowed by an NBT path ("Inventory[0].id"),
+// and return it parsed from SNBT, see package snbt for the Go types used
+func (c *Client) DataGet(ctx context.Context, target DataTarget, path string) (interface{}, error) {
+	if target.kind == "entity" {
+		if err := checkTarget(target.arg); err != nil {
+			return nil, err
+		}
+	}
+	if target.arg == "" || strings.ContainsAny(target.arg+path, "\r\n") {
+		return nil, ErrorInvalidArgument
+	}
+
+	cmd := "data get " + target.kind + " " + target.arg
+	if path != "" {
+		cmd += " " + path
+	}
+	response, err := c.execute(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	m, err := matchResponse(response, dataResult, dataFailures...)
+	if err != nil {
+		return nil, err
+	}
+	return snbt.Parse(m[1])
+}
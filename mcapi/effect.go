@@ -0,0 +1,194 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrorUnknownEffect           = errors.New("mcapi: unknown effect")
+	ErrorEffectNotApplied        = errors.New("mcapi: effect could not be applied")
+	ErrorUnknownEnchantment      = errors.New("mcapi: unknown enchantment")
+	ErrorEnchantmentLevel        = errors.New("mcapi: enchantment level out of range")
+	ErrorIncompatibleEnchantment = errors.New("mcapi: item cannot support that enchantment")
+	ErrorNoItem                  = errors.New("mcapi: target is not holding an item")
+)
+
+const (
+	effectSecondsMax   = 1000000
+	effectAmplifierMax = 255
+)
+
+var (
+	effectApplied  = regexp.MustCompile(`^Applied effect (.+) to (.+)$`)
+	effectFailures = []failure{
+		{regexp.MustCompile(`(?i)Unknown effect`), ErrorUnknownEffect},
+		{regexp.MustCompile(`(?i)Unable to apply this effect`), ErrorEffectNotApplied},
+	}
+
+	enchantApplied  = regexp.MustCompile(`^Applied enchantment (.+) to (.+)$`)
+	enchantFailures = []failure{
+		{regexp.MustCompile(`(?i)Unknown enchantment`), ErrorUnknownEnchantment},
+		{regexp.MustCompile(`(?i)higher than the maximum level`), ErrorEnchantmentLevel},
+		{regexp.MustCompile(`(?i)cannot support that enchantment|can't be combined|is not compatible`), ErrorIncompatibleEnchantment},
+		{regexp.MustCompile(`(?i)is not holding any item|has no item`), ErrorNoItem},
+	}
+)
+
+// Builder for /effect give
+type EffectCommand struct {
+	client    *Client
+	target    string
+	effect    string
+	seconds   int
+	infinite  bool
+	amplifier int
+	hide      bool
+}
+
+// Give target an effect, by id with or without the "minecraft:" namespace
+func (c *Client) Effect(target string, effect string) *EffectCommand {
+	return &EffectCommand{client: c, target: target, effect: effect, seconds: -1}
+}
+
+// Duration, rounded to whole seconds (default 30s)
+func (e *EffectCommand) Duration(d time.Duration) *EffectCommand {
+	e.seconds = int((d + time.Second/2) / time.Second)
+	e.infinite = false
+	return e
+}
+
+// Never expire (1.19.4+)
+func (e *EffectCommand) Infinite() *EffectCommand {
+	e.infinite = true
+	return e
+}
+
+// Effect level minus one, so 1 gives Speed II
+func (e *EffectCommand) Amplifier(n int) *EffectCommand {
+	e.amplifier = n
+	return e
+}
+
+func (e *EffectCommand) HideParticles() *EffectCommand {
+	e.hide = true
+	return e
+}
+
+// The validated command line
+func (e *EffectCommand) Command() (string, error) {
+	if err := checkTarget(e.target); err != nil {
+		return "", err
+	}
+	if err := checkWord(e.effect); err != nil {
+		return "", err
+	}
+	if id, vanilla := vanillaId(e.effect); vanilla && !effectCatalog[id] {
+		return "", fmt.Errorf("%w: %q", ErrorUnknownEffect, e.effect)
+	}
+	if !e.infinite && e.seconds != -1 && (e.seconds < 1 || e.seconds > effectSecondsMax) {
+		return "", fmt.Errorf("%w: duration must be 1 to %d seconds", ErrorInvalidArgument, effectSecondsMax)
+	}
+	if e.amplifier < 0 || e.amplifier > effectAmplifierMax {
+		return "", fmt.Errorf("%w: amplifier must be 0 to %d", ErrorInvalidArgument, effectAmplifierMax)
+	}
+
+	args := []string{"effect", "give", e.target, e.effect}
+	switch {
+	case e.infinite:
+		args = append(args, "infinite")
+	case e.seconds != -1:
+		args = append(args, strconv.Itoa(e.seconds))
+	case e.amplifier != 0 || e.hide:
+		args = append(args, "30") // default duration, needed before later arguments
+	}
+	if e.amplifier != 0 || e.hide {
+		args = append(args, strconv.Itoa(e.amplifier))
+	}
+	if e.hide {
+		args = append(args, "true")
+	}
+	return strings.Join(args, " "), nil
+}
+
+func (e *EffectCommand) Give(ctx context.Context) error {
+	cmd, err := e.Command()
+	if err != nil {
+		return err
+	}
+	response, err := e.client.execute(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, effectApplied, effectFailures...)
+	return err
+}
+
+// Builder for /enchant
+type EnchantCommand struct {
+	client      *Client
+	target      string
+	enchantment string
+	level       int
+}
+
+// Enchant the item held by target, by id with or without the "minecraft:" namespace
+func (c *Client) Enchant(target string, enchantment string) *EnchantCommand {
+	return &EnchantCommand{client: c, target: target, enchantment: enchantment, level: 1}
+}
+
+func (e *EnchantCommand) Level(n int) *EnchantCommand {
+	e.level = n
+	return e
+}
+
+// The validated command line
+func (e *EnchantCommand) Command() (string, error) {
+	if err := checkTarget(e.target); err != nil {
+		return "", err
+	}
+	if err := checkWord(e.enchantment); err != nil {
+		return "", err
+	}
+	id, vanilla := vanillaId(e.enchantment)
+	max, known := enchantmentCatalog[id]
+	if vanilla && !known {
+		return "", fmt.Errorf("%w: %q", ErrorUnknownEnchantment, e.enchantment)
+	}
+	if e.level < 1 || (known && e.level > max) {
+		return "", fmt.Errorf("%w: %s supports levels 1 to %d", ErrorEnchantmentLevel, e.enchantment, max)
+	}
+
+	cmd := "enchant " + e.target + " " + e.enchantment
+	if e.level != 1 {
+		cmd += " " + strconv.Itoa(e.level)
+	}
+	return cmd, nil
+}
+
+func (e *EnchantCommand) Apply(ctx context.Context) error {
+	cmd, err := e.Command()
+	if err != nil {
+		return err
+	}
+	response, err := e.client.execute(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, enchantApplied, enchantFailures...)
+	return err
+}
+
+// Strip the "minecraft:" namespace, vanilla is false for other namespaces
+// (mods, datapacks) which are not checked against the catalogs
+func vanillaId(id string) (string, bool) {
+	if i := strings.IndexByte(id, ':'); i >= 0 {
+		return id[i+1:], id[:i] == "minecraft"
+	}
+	return id, true
+}
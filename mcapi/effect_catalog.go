@@ -0,0 +1,30 @@
+package mcapi
+
+// Vanilla status effect ids (1.21)
+var effectCatalog = map[string]bool{
+	"absorption": true, "bad_omen": true, "blindness": true, "conduit_power": true,
+	"darkness": true, "dolphins_grace": true, "fire_resistance": true, "glowing": true,
+	"haste": true, "health_boost": true, "hero_of_the_village": true, "hunger": true,
+	"infested": true, "instant_damage": true, "instant_health": true, "invisibility": true,
+	"jump_boost": true, "levitation": true, "luck": true, "mining_fatigue": true,
+	"nausea": true, "night_vision": true, "oozing": true, "poison": true,
+	"raid_omen": true, "regeneration": true, "resistance": true, "saturation": true,
+	"slow_falling": true, "slowness": true, "speed": true, "strength": true,
+	"trial_omen": true, "unluck": true, "water_breathing": true, "weakness": true,
+	"weaving": true, "wind_charged": true, "wither": true,
+}
+
+// Vanilla enchantment ids and their maximum levels (1.21)
+var enchantmentCatalog = map[string]int{
+	"aqua_affinity": 1, "bane_of_arthropods": 5, "binding_curse": 1, "blast_protection": 4,
+	"breach": 4, "channeling": 1, "density": 5, "depth_strider": 3,
+	"efficiency": 5, "feather_falling": 4, "fire_aspect": 2, "fire_protection": 4,
+	"flame": 1, "fortune": 3, "frost_walker": 2, "impaling": 5,
+	"infinity": 1, "knockback": 2, "looting": 3, "loyalty": 3,
+	"luck_of_the_sea": 3, "lure": 3, "mending": 1, "multishot": 1,
+	"piercing": 4, "power": 5, "projectile_protection": 4, "protection": 4,
+	"punch": 2, "quick_charge": 3, "respiration": 3, "riptide": 3,
+	"sharpness": 5, "silk_touch": 1, "smite": 5, "soul_speed": 3,
+	"sweeping_edge": 3, "swift_sneak": 3, "thorns": 3, "unbreaking": 3,
+	"vanishing_curse": 1, "wind_burst": 3,
+}
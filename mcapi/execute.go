@@ -0,0 +1,358 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrorExecuteOrder = errors.New("mcapi: invalid execute clause order")
+)
+
+type clauseKind int
+
+const (
+	clauseAs clauseKind = iota
+	clauseAt
+	clausePositioned
+	clausePositionedAs
+	clauseRotated
+	clauseRotatedAs
+	clauseFacing
+	clauseFacingEntity
+	clauseAnchored
+	clauseAlign
+	clauseIn
+	clauseIfEntity
+	clauseIfBlock
+)
+
+type clause struct {
+	kind   clauseKind
+	negate bool // unless instead of if
+	target string
+	pos    Position
+	arg    string
+}
+
+// Sets the execution position to absolute coordinates, so an earlier
+// clause that only set the position is silently discarded
+func (c clause) replacesPosition() bool {
+	return c.kind == clausePositioned && c.pos.Relative == 0 && !c.pos.Local
+}
+
+// At is not one of these, it sets the rotation and dimension too
+func (c clause) onlySetsPosition() bool {
+	switch c.kind {
+	case clausePositioned, clausePositionedAs, clauseAlign:
+		return true
+	}
+	return false
+}
+
+// Builder for /execute chains
+type ExecuteCommand struct {
+	client  *Client
+	clauses []clause
+	run     string
+	version string // Minecraft version to target, detected when empty
+	err     error  // first clause added after run
+}
+
+// Start an execute chain, finished with Run
+func (c *Client) Execute() *ExecuteCommand {
+	return &ExecuteCommand{client: c}
+}
+
+func (e *ExecuteCommand) add(cl clause) *ExecuteCommand {
+	if e.run != "" && e.err == nil {
+		e.err = fmt.Errorf("%w: clause after run", ErrorExecuteOrder)
+	}
+	e.clauses = append(e.clauses, cl)
+	return e
+}
+
+// Run as each target, keeping the current position
+func (e *ExecuteCommand) As(target string) *ExecuteCommand {
+	return e.add(clause{kind: clauseAs, target: target})
+}
+
+// Run at each target's position, rotation and dimension
+func (e *ExecuteCommand) At(target string) *ExecuteCommand {
+	return e.add(clause{kind: clauseAt, target: target})
+}
+
+func (e *ExecuteCommand) Positioned(pos Position) *ExecuteCommand {
+	return e.add(clause{kind: clausePositioned, pos: pos})
+}
+
+func (e *ExecuteCommand) PositionedAs(target string) *ExecuteCommand {
+	return e.add(clause{kind: clausePositionedAs, target: target})
+}
+
+func (e *ExecuteCommand) Rotated(yaw float32, pitch float32) *ExecuteCommand {
+	return e.add(clause{kind: clauseRotated, pos: Position{}.WithRotation(yaw, pitch)})
+}
+
+func (e *ExecuteCommand) RotatedAs(target string) *ExecuteCommand {
+	return e.add(clause{kind: clauseRotatedAs, target: target})
+}
+
+func (e *ExecuteCommand) Facing(pos Position) *ExecuteCommand {
+	return e.add(clause{kind: clauseFacing, pos: pos})
+}
+
+// Face target's "eyes" or "feet"
+func (e *ExecuteCommand) FacingEntity(target string, anchor string) *ExecuteCommand {
+	return e.add(clause{kind: clauseFacingEntity, target: target, arg: anchor})
+}
+
+// "eyes" or "feet", for local coordinates and facing
+func (e *ExecuteCommand) Anchored(anchor string) *ExecuteCommand {
+	return e.add(clause{kind: clauseAnchored, arg: anchor})
+}
+
+// Floor the position on the given axes
+func (e *ExecuteCommand) Align(axes Axes) *ExecuteCommand {
+	return e.add(clause{kind: clauseAlign, arg: axesString(axes)})
+}
+
+// Run in a dimension, e.g. "minecraft:the_nether"
+func (e *ExecuteCommand) In(dimension string) *ExecuteCommand {
+	return e.add(clause{kind: clauseIn, arg: dimension})
+}
+
+// Only continue if target matches at least one entity
+func (e *ExecuteCommand) IfEntity(target string) *ExecuteCommand {
+	return e.add(clause{kind: clauseIfEntity, target: target})
+}
+
+func (e *ExecuteCommand) UnlessEntity(target string) *ExecuteCommand {
+	return e.add(clause{kind: clauseIfEntity, target: target, negate: true})
+}
+
+// Only continue if the block at pos matches block, e.g. "minecraft:stone"
+func (e *ExecuteCommand) IfBlock(pos Position, block string) *ExecuteCommand {
+	return e.add(clause{kind: clauseIfBlock, pos: pos, arg: block})
+}
+
+func (e *ExecuteCommand) UnlessBlock(pos Position, block string) *ExecuteCommand {
+	return e.add(clause{kind: clauseIfBlock, pos: pos, arg: block, negate: true})
+}
+
+// The command to run, without a leading "/"; ends the chain
+func (e *ExecuteCommand) Run(cmd string) *ExecuteCommand {
+	if e.run != "" && e.err == nil {
+		e.err = fmt.Errorf("%w: run given twice", ErrorExecuteOrder)
+	}
+	e.run = strings.TrimPrefix(strings.TrimSpace(cmd), "/")
+	return e
+}
+
+// Target a Minecraft version ("1.12.2") instead of detecting the server's
+func (e *ExecuteCommand) Version(version string) *ExecuteCommand {
+	e.version = version
+	return e
+}
+
+// The validated command line, in 1.13+ syntax unless Version is older
+func (e *ExecuteCommand) Command() (string, error) {
+	if err := e.validate(); err != nil {
+		return "", err
+	}
+	if versionBefore(e.version, "1.13") {
+		return e.legacyCommand()
+	}
+
+	args := []string{"execute"}
+	for _, cl := range e.clauses {
+		args = append(args, cl.String())
+	}
+	args = append(args, "run", e.run)
+	return strings.Join(args, " "), nil
+}
+
+// Send the chain, returning the run command's response
+func (e *ExecuteCommand) Send(ctx context.Context) (string, error) {
+	if e.version == "" {
+		version, err := e.client.minecraftVersion(ctx)
+		if err != nil {
+			return "", err
+		}
+		e.version = version
+	}
+	cmd, err := e.Command()
+	if err != nil {
+		return "", err
+	}
+	response, err := e.client.execute(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	return response, CommandFailure(response)
+}
+
+func (e *ExecuteCommand) validate() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.run == "" {
+		return fmt.Errorf("%w: missing run", ErrorExecuteOrder)
+	}
+	if strings.ContainsAny(e.run, "\r\n") {
+		return fmt.Errorf("%w: %q", ErrorInvalidArgument, e.run)
+	}
+
+	for i, cl := range e.clauses {
+		if cl.target != "" || cl.kind == clauseAs || cl.kind == clauseAt {
+			if err := checkTarget(cl.target); err != nil {
+				return err
+			}
+		}
+		switch cl.kind {
+		case clauseFacingEntity, clauseAnchored:
+			if cl.arg != "eyes" && cl.arg != "feet" {
+				return fmt.Errorf("%w: anchor %q", ErrorInvalidArgument, cl.arg)
+			}
+		case clauseAlign:
+			if cl.arg == "" {
+				return fmt.Errorf("%w: align needs an axis", ErrorInvalidArgument)
+			}
+		case clauseIn, clauseIfBlock:
+			if err := checkWord(cl.arg); err != nil {
+				return err
+			}
+		}
+
+		if i > 0 && cl.replacesPosition() && e.clauses[i-1].onlySetsPosition() {
+			return fmt.Errorf("%w: %q is overridden by %q", ErrorExecuteOrder, e.clauses[i-1].String(), cl.String())
+		}
+	}
+	return nil
+}
+
+// 1.12 and older only have "execute <entity> <x y z> [detect ...] <command>",
+// which runs as and at the entity, so chains must be built from as/at pairs
+func (e *ExecuteCommand) legacyCommand() (string, error) {
+	var args []string
+	for i := 0; i < len(e.clauses); {
+		cl := e.clauses[i]
+		if cl.kind != clauseAs || i+1 >= len(e.clauses) || e.clauses[i+1].kind != clauseAt ||
+			(e.clauses[i+1].target != "@s" && e.clauses[i+1].target != cl.target) {
+			return "", fmt.Errorf("%w: %q before 1.13, which needs as <target> at @s pairs", ErrorUnsupported, cl.String())
+		}
+		i += 2
+
+		pos := "~ ~ ~"
+		if i < len(e.clauses) && e.clauses[i].kind == clausePositioned {
+			if e.clauses[i].pos.Local {
+				return "", fmt.Errorf("%w: local coordinates before 1.13", ErrorUnsupported)
+			}
+			pos = coords(e.clauses[i].pos)
+			i++
+		}
+		args = append(args, "execute", cl.target, pos)
+
+		if i < len(e.clauses) && e.clauses[i].kind == clauseIfBlock && !e.clauses[i].negate {
+			args = append(args, "detect", coords(e.clauses[i].pos), e.clauses[i].arg, "-1")
+			i++
+		}
+	}
+	if len(args) == 0 {
+		return e.run, nil
+	}
+	return strings.Join(append(args, e.run), " "), nil
+}
+
+// Clause in 1.13+ syntax
+func (c clause) String() string {
+	condition := "if"
+	if c.negate {
+		condition = "unless"
+	}
+	switch c.kind {
+	case clauseAs:
+		return "as " + c.target
+	case clauseAt:
+		return "at " + c.target
+	case clausePositioned:
+		return "positioned " + coords(c.pos)
+	case clausePositionedAs:
+		return "positioned as " + c.target
+	case clauseRotated:
+		return "rotated " + strconv.FormatFloat(float64(c.pos.Yaw), 'f', -1, 32) + " " +
+			strconv.FormatFloat(float64(c.pos.Pitch), 'f', -1, 32)
+	case clauseRotatedAs:
+		return "rotated as " + c.target
+	case clauseFacing:
+		return "facing " + coords(c.pos)
+	case clauseFacingEntity:
+		return "facing entity " + c.target + " " + c.arg
+	case clauseAnchored:
+		return "anchored " + c.arg
+	case clauseAlign:
+		return "align " + c.arg
+	case clauseIn:
+		return "in " + c.arg
+	case clauseIfEntity:
+		return condition + " entity " + c.target
+	case clauseIfBlock:
+		return condition + " block " + coords(c.pos) + " " + c.arg
+	}
+	return ""
+}
+
+// Position without rotation
+func coords(p Position) string {
+	p.Rotate = false
+	return p.String()
+}
+
+func axesString(axes Axes) string {
+	s := ""
+	for i, name := range "xyz" {
+		if axes&(AxisX<<uint(i)) != 0 {
+			s += string(name)
+		}
+	}
+	return s
+}
+
+// Whether version ("1.12.2") is older than want ("1.13", "1.20.5"). Versions
+// that can't be read (snapshots, unknown) are treated as current.
+func versionBefore(version string, want string) bool {
+	have, ok := versionParts(version)
+	if !ok {
+		return false
+	}
+	wanted, _ := versionParts(want)
+	for i := range wanted {
+		if have[i] != wanted[i] {
+			return have[i] < wanted[i]
+		}
+	}
+	return false
+}
+
+// Major, minor and patch numbers of a release version
+func versionParts(version string) ([3]int, bool) {
+	var parts [3]int
+	fields := strings.SplitN(version, ".", 3)
+	if len(fields) < 2 {
+		return parts, false
+	}
+	for i, f := range fields {
+		if i == 2 {
+			f = strings.SplitN(f, "-", 2)[0] // "4-R0.1-SNAPSHOT"
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
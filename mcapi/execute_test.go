@@ -0,0 +1,96 @@
+package mcapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExecuteOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain func(e *ExecuteCommand) *ExecuteCommand
+		want  string // empty for ErrorExecuteOrder
+	}{
+		{
+			"as at positioned",
+			func(e *ExecuteCommand) *ExecuteCommand {
+				return e.As("@a").At("@s").Positioned(At(1, 64, -2)).Run("say hi")
+			},
+			"execute as @a at @s positioned 1 64 -2 run say hi",
+		},
+		{
+			"at positioned keeps the dimension",
+			func(e *ExecuteCommand) *ExecuteCommand {
+				return e.At("@p").Positioned(At(0, 70, 0)).Run("/setblock ~ ~ ~ stone")
+			},
+			"execute at @p positioned 0 70 0 run setblock ~ ~ ~ stone",
+		},
+		{
+			"positioned relative after positioned",
+			func(e *ExecuteCommand) *ExecuteCommand {
+				return e.Positioned(At(0, 64, 0)).Positioned(Offset(0, 1, 0)).Run("say hi")
+			},
+			"execute positioned 0 64 0 positioned ~ ~1 ~ run say hi",
+		},
+		{
+			"align then positioned as",
+			func(e *ExecuteCommand) *ExecuteCommand {
+				return e.Align(AxisX | AxisZ).PositionedAs("@p").Run("say hi")
+			},
+			"execute align xz positioned as @p run say hi",
+		},
+		{
+			"positioned overridden by positioned",
+			func(e *ExecuteCommand) *ExecuteCommand {
+				return e.Positioned(Offset(0, 1, 0)).Positioned(At(0, 64, 0)).Run("say hi")
+			},
+			"",
+		},
+		{
+			"align overridden by positioned",
+			func(e *ExecuteCommand) *ExecuteCommand {
+				return e.Align(AllAxes).Positioned(At(0, 64, 0)).Run("say hi")
+			},
+			"",
+		},
+		{
+			"positioned as overridden by positioned",
+			func(e *ExecuteCommand) *ExecuteCommand {
+				return e.PositionedAs("@p").Positioned(At(0, 64, 0)).Run("say hi")
+			},
+			"",
+		},
+		{
+			"clause after run",
+			func(e *ExecuteCommand) *ExecuteCommand {
+				return e.Run("say hi").As("@a")
+			},
+			"",
+		},
+		{
+			"missing run",
+			func(e *ExecuteCommand) *ExecuteCommand {
+				return e.As("@a")
+			},
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.chain(New(nil).Execute().Version("1.20.4")).Command()
+			if test.want == "" {
+				if !errors.Is(err, ErrorExecuteOrder) {
+					t.Fatalf("got %q, %v, want ErrorExecuteOrder", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package mcapi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrorMacroLine = errors.New("mcapi: function macro lines need arguments")
+)
+
+// Command of an .mcfunction file
+type FunctionCommand struct {
+	Line    int // first line of the command in the file
+	Command string
+}
+
+// Outcome of one command of a function
+type FunctionResult struct {
+	FunctionCommand
+	Response string
+	Err      error
+}
+
+// Read the commands of an .mcfunction file, skipping blank lines and
+// comments and joining lines continued with a trailing "\"
+func ReadFunction(in io.Reader) ([]FunctionCommand, error) {
+	var commands []FunctionCommand
+	var pending []string
+	start := 0
+
+	input := bufio.NewScanner(in)
+	for n := 1; input.Scan(); n++ {
+		line := strings.TrimSpace(input.Text())
+		if len(pending) == 0 {
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			start = n
+		}
+		if strings.HasSuffix(line, "\\") {
+			pending = append(pending, strings.TrimSpace(strings.TrimSuffix(line, "\\")))
+			continue
+		}
+		pending = append(pending, line)
+		commands = append(commands, FunctionCommand{Line: start, Command: strings.Join(pending, " ")})
+		pending = nil
+	}
+	if len(pending) > 0 {
+		commands = append(commands, FunctionCommand{Line: start, Command: strings.Join(pending, " ")})
+	}
+	return commands, input.Err()
+}
+
+// Run each command in turn, stopping at the first failure if stopOnError
+// is set. A command fails when the connection does, or when the server
+// reports an unknown command, bad argument or missing player.
+func (c *Client) RunFunction(ctx context.Context, commands []FunctionCommand, stopOnError bool) []FunctionResult {
+	results := make([]FunctionResult, 0, len(commands))
+	for _, cmd := range commands {
+		result := FunctionResult{FunctionCommand: cmd}
+		line := strings.TrimPrefix(cmd.Command, "/")
+
+		if strings.HasPrefix(line, "$") {
+			result.Err = ErrorMacroLine
+		} else if response, err := c.execute(ctx, line); err != nil {
+			result.Err = err
+		} else {
+			result.Response = response
+			result.Err = CommandFailure(response)
+		}
+
+		results = append(results, result)
+		if result.Err != nil && (stopOnError || ctx.Err() != nil) {
+			break
+		}
+	}
+	return results
+}
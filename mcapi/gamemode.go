@@ -0,0 +1,137 @@
+package mcapi
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Gamemode int
+
+const (
+	Survival Gamemode = iota
+	Creative
+	Adventure
+	Spectator
+)
+
+var gamemodeNames = []string{"survival", "creative", "adventure", "spectator"}
+
+// Command argument form, "survival"
+func (g Gamemode) String() string {
+	if g < 0 || int(g) >= len(gamemodeNames) {
+		return fmt.Sprintf("Gamemode(%d)", int(g))
+	}
+	return gamemodeNames[g]
+}
+
+type Difficulty int
+
+const (
+	Peaceful Difficulty = iota
+	Easy
+	Normal
+	Hard
+)
+
+var difficultyNames = []string{"peaceful", "easy", "normal", "hard"}
+
+// Command argument form, "peaceful"
+func (d Difficulty) String() string {
+	if d < 0 || int(d) >= len(difficultyNames) {
+		return fmt.Sprintf("Difficulty(%d)", int(d))
+	}
+	return difficultyNames[d]
+}
+
+func ParseDifficulty(s string) (Difficulty, error) {
+	for i, name := range difficultyNames {
+		if strings.EqualFold(s, name) {
+			return Difficulty(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown difficulty %q", ErrorInvalidArgument, s)
+}
+
+func ParseGamemode(s string) (Gamemode, error) {
+	s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), " mode")
+	for i, name := range gamemodeNames {
+		if s == name {
+			return Gamemode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown gamemode %q", ErrorInvalidArgument, s)
+}
+
+var (
+	// "Set Steve's game mode to Creative Mode", "Set own game mode to Creative Mode"
+	// and before 1.13 "Steve's game mode has been updated to Creative Mode"
+	gamemodeSet = regexp.MustCompile(`^(?:Set (?:(\S+)'s|own) game mode to (\w+) Mode|(\S+)'s game mode has been updated to (\w+) Mode)`)
+	// "The difficulty has been set to Hard", "The difficulty did not change;
+	// it is already set to Hard" and before 1.13 "Set game difficulty to Hard"
+	difficultySet = regexp.MustCompile(`^(?:The difficulty has been set to|The difficulty did not change; it is already set to|Set game difficulty to) (\w+)`)
+	difficultyGet = regexp.MustCompile(`^The difficulty is (\w+)`)
+)
+
+// Set a player's (or selector's) game mode
+func (c *Client) SetGamemode(ctx context.Context, target string, mode Gamemode) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	if mode < Survival || mode > Spectator {
+		return fmt.Errorf("%w: %s", ErrorInvalidArgument, mode)
+	}
+
+	response, err := c.execute(ctx, "gamemode "+mode.String()+" "+target)
+	if err != nil {
+		return err
+	}
+	// No feedback when the target already had the mode
+	if strings.TrimSpace(response) == "" {
+		return nil
+	}
+	m, err := matchResponse(response, gamemodeSet)
+	if err != nil {
+		return err
+	}
+	if got, err := ParseGamemode(firstNonEmpty(m[2], m[4])); err != nil || got != mode {
+		return unexpected(response)
+	}
+	return nil
+}
+
+func (c *Client) SetDifficulty(ctx context.Context, d Difficulty) error {
+	if d < Peaceful || d > Hard {
+		return fmt.Errorf("%w: %s", ErrorInvalidArgument, d)
+	}
+
+	response, err := c.execute(ctx, "difficulty "+d.String())
+	if err != nil {
+		return err
+	}
+	m, err := matchResponse(response, difficultySet)
+	if err != nil {
+		return err
+	}
+	if got, err := ParseDifficulty(m[1]); err != nil || got != d {
+		return unexpected(response)
+	}
+	return nil
+}
+
+func (c *Client) GetDifficulty(ctx context.Context) (Difficulty, error) {
+	response, err := c.execute(ctx, "difficulty")
+	if err != nil {
+		return 0, err
+	}
+	m, err := matchResponse(response, difficultyGet)
+	if err != nil {
+		return 0, err
+	}
+	d, err := ParseDifficulty(m[1])
+	if err != nil {
+		return 0, unexpected(response)
+	}
+	return d, nil
+}
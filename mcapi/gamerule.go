@@ -0,0 +1,193 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrorGameruleValue = errors.New("mcapi: invalid gamerule value")
+)
+
+var (
+	// "Gamerule keepInventory is currently set to: false" and before 1.13
+	// "keepInventory = false"
+	gameruleValue = regexp.MustCompile(`^(?:Gamerule (\S+) is currently set to: (.*)|(\S+) = (.*))$`)
+	// "Gamerule keepInventory is now set to: true" and before 1.13
+	// "Game rule keepInventory has been updated to true"
+	gameruleSet = regexp.MustCompile(`^(?:Gamerule (\S+) is now set to: (.*)|Game rule (\S+) has been updated to (.*))$`)
+
+	gameruleFailures = []failure{
+		{regexp.MustCompile(`(?i)No game rule called|is not a valid|Expected (?:integer|bool)|Invalid (?:integer|boolean)`), ErrorGameruleValue},
+	}
+)
+
+type GameruleType int
+
+const (
+	GameruleUnknown GameruleType = iota
+	GameruleBool
+	GameruleInt
+)
+
+func (t GameruleType) String() string {
+	switch t {
+	case GameruleBool:
+		return "bool"
+	case GameruleInt:
+		return "int"
+	}
+	return "unknown"
+}
+
+type GameruleInfo struct {
+	Name string
+	Type GameruleType
+	Min  int // int rules only
+	Max  int
+}
+
+// Catalog entry for a known gamerule
+func LookupGamerule(name string) (GameruleInfo, bool) {
+	info, ok := gameruleCatalog[name]
+	info.Name = name
+	return info, ok
+}
+
+type Gamerules struct {
+	client *Client
+}
+
+func (c *Client) Gamerules() *Gamerules {
+	return &Gamerules{client: c}
+}
+
+// Current value, a bool or int for known rules (and unknown rules whose
+// value looks like one), otherwise a string
+func (g *Gamerules) Get(ctx context.Context, name string) (interface{}, error) {
+	if err := checkWord(name); err != nil {
+		return nil, err
+	}
+
+	response, err := g.client.execute(ctx, "gamerule "+name)
+	if err != nil {
+		return nil, err
+	}
+	m, err := matchResponse(response, gameruleValue, gameruleFailures...)
+	if err != nil {
+		return nil, err
+	}
+	return parseGameruleValue(name, firstNonEmpty(m[2], m[4]))
+}
+
+func (g *Gamerules) GetBool(ctx context.Context, name string) (bool, error) {
+	v, err := g.Get(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: %s is %v, not a bool", ErrorGameruleValue, name, v)
+	}
+	return b, nil
+}
+
+func (g *Gamerules) GetInt(ctx context.Context, name string) (int, error) {
+	v, err := g.Get(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := v.(int)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is %v, not an int", ErrorGameruleValue, name, v)
+	}
+	return i, nil
+}
+
+// Set a rule, validating the value first for known rules, and return the
+// effective value reported by the server
+func (g *Gamerules) Set(ctx context.Context, name string, value interface{}) (interface{}, error) {
+	if err := checkWord(name); err != nil {
+		return nil, err
+	}
+	arg, err := formatGameruleValue(name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := g.client.execute(ctx, "gamerule "+name+" "+arg)
+	if err != nil {
+		return nil, err
+	}
+	m, err := matchResponse(response, gameruleSet, gameruleFailures...)
+	if err != nil {
+		return nil, err
+	}
+	return parseGameruleValue(name, firstNonEmpty(m[2], m[4]))
+}
+
+func formatGameruleValue(name string, value interface{}) (string, error) {
+	info, known := LookupGamerule(name)
+
+	switch v := value.(type) {
+	case bool:
+		if known && info.Type != GameruleBool {
+			return "", fmt.Errorf("%w: %s takes an int", ErrorGameruleValue, name)
+		}
+		return strconv.FormatBool(v), nil
+	case int:
+		if known && info.Type != GameruleInt {
+			return "", fmt.Errorf("%w: %s takes a bool", ErrorGameruleValue, name)
+		}
+		if known && (v < info.Min || v > info.Max) {
+			return "", fmt.Errorf("%w: %s must be between %d and %d", ErrorGameruleValue, name, info.Min, info.Max)
+		}
+		return strconv.Itoa(v), nil
+	case string:
+		if known {
+			// Validate through the typed path
+			if parsed, err := parseGameruleValue(name, v); err == nil {
+				return formatGameruleValue(name, parsed)
+			}
+			return "", fmt.Errorf("%w: %q for %s", ErrorGameruleValue, v, name)
+		}
+		if err := checkWord(v); err != nil {
+			return "", err
+		}
+		return v, nil
+	}
+	return "", fmt.Errorf("%w: unsupported type %T", ErrorGameruleValue, value)
+}
+
+func parseGameruleValue(name string, s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	info, known := LookupGamerule(name)
+
+	if !known || info.Type == GameruleBool {
+		if b, err := strconv.ParseBool(s); err == nil && (s == "true" || s == "false") {
+			return b, nil
+		}
+	}
+	if !known || info.Type == GameruleInt {
+		if i, err := strconv.Atoi(s); err == nil {
+			return i, nil
+		}
+	}
+	if known {
+		return nil, fmt.Errorf("%w: %s reported %q", ErrorGameruleValue, name, s)
+	}
+	return s, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
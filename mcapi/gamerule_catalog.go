@@ -0,0 +1,70 @@
+package mcapi
+
+import (
+	"math"
+)
+
+// Vanilla gamerules up to 1.21. Rules missing here (modded, or newer than
+// this table) are still accepted, without validation.
+var gameruleCatalog = map[string]GameruleInfo{
+	"announceAdvancements":             boolRule(),
+	"blockExplosionDropDecay":          boolRule(),
+	"commandBlockOutput":               boolRule(),
+	"commandModificationBlockLimit":    intRule(1, math.MaxInt32),
+	"disableElytraMovementCheck":       boolRule(),
+	"disablePlayerMovementCheck":       boolRule(),
+	"disableRaids":                     boolRule(),
+	"doDaylightCycle":                  boolRule(),
+	"doEntityDrops":                    boolRule(),
+	"doFireTick":                       boolRule(),
+	"doImmediateRespawn":               boolRule(),
+	"doInsomnia":                       boolRule(),
+	"doLimitedCrafting":                boolRule(),
+	"doMobLoot":                        boolRule(),
+	"doMobSpawning":                    boolRule(),
+	"doPatrolSpawning":                 boolRule(),
+	"doTileDrops":                      boolRule(),
+	"doTraderSpawning":                 boolRule(),
+	"doVinesSpread":                    boolRule(),
+	"doWardenSpawning":                 boolRule(),
+	"doWeatherCycle":                   boolRule(),
+	"drowningDamage":                   boolRule(),
+	"enderPearlsVanishOnDeath":         boolRule(),
+	"fallDamage":                       boolRule(),
+	"fireDamage":                       boolRule(),
+	"forgiveDeadPlayers":               boolRule(),
+	"freezeDamage":                     boolRule(),
+	"globalSoundEvents":                boolRule(),
+	"keepInventory":                    boolRule(),
+	"lavaSourceConversion":             boolRule(),
+	"logAdminCommands":                 boolRule(),
+	"maxCommandChainLength":            intRule(0, math.MaxInt32),
+	"maxCommandForkCount":              intRule(0, math.MaxInt32),
+	"maxEntityCramming":                intRule(0, math.MaxInt32),
+	"mobExplosionDropDecay":            boolRule(),
+	"mobGriefing":                      boolRule(),
+	"naturalRegeneration":              boolRule(),
+	"playersNetherPortalCreativeDelay": intRule(0, math.MaxInt32),
+	"playersNetherPortalDefaultDelay":  intRule(0, math.MaxInt32),
+	"playersSleepingPercentage":        intRule(0, math.MaxInt32),
+	"projectilesCanBreakBlocks":        boolRule(),
+	"randomTickSpeed":                  intRule(0, math.MaxInt32),
+	"reducedDebugInfo":                 boolRule(),
+	"sendCommandFeedback":              boolRule(),
+	"showDeathMessages":                boolRule(),
+	"snowAccumulationHeight":           intRule(0, 8),
+	"spawnChunkRadius":                 intRule(0, 32),
+	"spawnRadius":                      intRule(0, math.MaxInt32),
+	"spectatorsGenerateChunks":         boolRule(),
+	"tntExplosionDropDecay":            boolRule(),
+	"universalAnger":                   boolRule(),
+	"waterSourceConversion":            boolRule(),
+}
+
+func boolRule() GameruleInfo {
+	return GameruleInfo{Type: GameruleBool}
+}
+
+func intRule(min int, max int) GameruleInfo {
+	return GameruleInfo{Type: GameruleInt, Min: min, Max: max}
+}
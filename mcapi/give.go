@@ -0,0 +1,168 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/mcapi/snbt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrorUnknownItem = errors.New("mcapi: unknown item")
+	ErrorStackSize   = errors.New("mcapi: too many items")
+	ErrorItemData    = errors.New("mcapi: invalid item data")
+)
+
+// The server refuses more than this many stacks in one give
+const giveStacksMax = 100
+
+var (
+	// "Gave 64 [Stone] to Steve", before 1.13 "Given [Stone] * 64 to Steve"
+	itemGiven    = regexp.MustCompile(`^(?:Gave (\d+) \[(.+)\]|Given \[(.+)\] \* (\d+)) to (.+)$`)
+	giveFailures = []failure{
+		{regexp.MustCompile(`(?i)Unknown item|no such item`), ErrorUnknownItem},
+		{regexp.MustCompile(`(?i)Can't give more than`), ErrorStackSize},
+		{regexp.MustCompile(`(?i)Data tag parsing failed|Malformed|Unknown item component|Invalid NBT|Expected (?:value|key)`), ErrorItemData},
+	}
+)
+
+// Builder for /give
+type GiveCommand struct {
+	client     *Client
+	target     string
+	item       string
+	count      int
+	nbt        map[string]interface{}
+	components map[string]interface{}
+	version    string // Minecraft version to target, detected when empty
+}
+
+// Give target count of an item, by id with or without the "minecraft:" namespace
+func (c *Client) Give(target string, item string, count int) *GiveCommand {
+	return &GiveCommand{client: c, target: target, item: item, count: count}
+}
+
+// Item NBT, for servers before 1.20.5 ("diamond_sword{Damage:10}")
+func (g *GiveCommand) NBT(tag map[string]interface{}) *GiveCommand {
+	g.nbt = tag
+	return g
+}
+
+// Data components, for 1.20.5 and later ("diamond_sword[damage=10]"). Values
+// are formatted as SNBT.
+func (g *GiveCommand) Components(components map[string]interface{}) *GiveCommand {
+	g.components = components
+	return g
+}
+
+// Target a Minecraft version ("1.20.4") instead of detecting the server's
+func (g *GiveCommand) Version(version string) *GiveCommand {
+	g.version = version
+	return g
+}
+
+// The validated command line
+func (g *GiveCommand) Command() (string, error) {
+	if err := checkTarget(g.target); err != nil {
+		return "", err
+	}
+	if err := checkWord(g.item); err != nil {
+		return "", err
+	}
+
+	id, vanilla := vanillaId(g.item)
+	if added, known := itemCatalog[id]; vanilla && known && versionBefore(g.version, added) {
+		return "", fmt.Errorf("%w: %q was added in %s", ErrorUnknownItem, g.item, added)
+	}
+	max := giveStacksMax * 64
+	if vanilla {
+		max = giveStacksMax * maxStack(id)
+	}
+	if g.count < 1 || g.count > max {
+		return "", fmt.Errorf("%w: count must be 1 to %d for %s", ErrorStackSize, max, g.item)
+	}
+
+	item := g.item
+	switch {
+	case g.nbt != nil && g.components != nil:
+		return "", fmt.Errorf("%w: both NBT and components given", ErrorItemData)
+	case g.nbt != nil:
+		if !versionBefore(g.version, "1.20.5") {
+			return "", fmt.Errorf("%w: item NBT was replaced by components in 1.20.5", ErrorUnsupported)
+		}
+		tag, err := snbt.Format(g.nbt)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrorItemData, err)
+		}
+		item += tag
+	case g.components != nil:
+		if versionBefore(g.version, "1.20.5") {
+			return "", fmt.Errorf("%w: item components need 1.20.5", ErrorUnsupported)
+		}
+		components, err := formatComponents(g.components)
+		if err != nil {
+			return "", err
+		}
+		item += components
+	}
+
+	if versionBefore(g.version, "1.13") {
+		// give <player> <item> [amount] [data] [dataTag]
+		cmd := "give " + g.target + " " + g.item + " " + strconv.Itoa(g.count)
+		if len(item) > len(g.item) {
+			cmd += " 0 " + item[len(g.item):]
+		}
+		return cmd, nil
+	}
+	return "give " + g.target + " " + item + " " + strconv.Itoa(g.count), nil
+}
+
+// Send the command, returning the number of items given to each target
+func (g *GiveCommand) Send(ctx context.Context) (int, error) {
+	if g.version == "" {
+		version, err := g.client.minecraftVersion(ctx)
+		if err != nil {
+			return 0, err
+		}
+		g.version = version
+	}
+	cmd, err := g.Command()
+	if err != nil {
+		return 0, err
+	}
+	response, err := g.client.execute(ctx, cmd)
+	if err != nil {
+		return 0, err
+	}
+	m, err := matchResponse(response, itemGiven, giveFailures...)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(firstNonEmpty(m[1], m[4]))
+}
+
+// "[name=value,...]" with components in a stable order
+func formatComponents(components map[string]interface{}) (string, error) {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		if err := checkWord(name); err != nil {
+			return "", err
+		}
+		value, err := snbt.Format(components[name])
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %v", ErrorItemData, name, err)
+		}
+		parts = append(parts, name+"="+value)
+	}
+	return "[" + strings.Join(parts, ",") + "]", nil
+}
@@ -0,0 +1,94 @@
+package mcapi
+
+import "strings"
+
+// Release that added items introduced after the 1.13 flattening, checked
+// when giving to an older server. Items not listed are left to the server.
+var itemCatalog = map[string]string{
+	// 1.14
+	"barrel": "1.14", "bell": "1.14", "blast_furnace": "1.14", "campfire": "1.14",
+	"cartography_table": "1.14", "crossbow": "1.14", "fletching_table": "1.14", "grindstone": "1.14",
+	"lantern": "1.14", "lectern": "1.14", "leather_horse_armor": "1.14", "scaffolding": "1.14",
+	"smithing_table": "1.14", "smoker": "1.14", "stonecutter": "1.14", "suspicious_stew": "1.14",
+	"sweet_berries": "1.14",
+	// 1.15
+	"bee_nest": "1.15", "beehive": "1.15", "honey_block": "1.15", "honey_bottle": "1.15",
+	"honeycomb": "1.15", "honeycomb_block": "1.15",
+	// 1.16
+	"ancient_debris": "1.16", "blackstone": "1.16", "crimson_planks": "1.16", "lodestone": "1.16",
+	"music_disc_pigstep": "1.16", "netherite_axe": "1.16", "netherite_block": "1.16", "netherite_boots": "1.16",
+	"netherite_chestplate": "1.16", "netherite_helmet": "1.16", "netherite_hoe": "1.16", "netherite_ingot": "1.16",
+	"netherite_leggings": "1.16", "netherite_pickaxe": "1.16", "netherite_scrap": "1.16", "netherite_shovel": "1.16",
+	"netherite_sword": "1.16", "respawn_anchor": "1.16", "soul_campfire": "1.16", "soul_lantern": "1.16",
+	"target": "1.16", "warped_fungus_on_a_stick": "1.16", "warped_planks": "1.16",
+	// 1.17
+	"amethyst_shard": "1.17", "axolotl_bucket": "1.17", "calcite": "1.17", "candle": "1.17",
+	"copper_ingot": "1.17", "deepslate": "1.17", "dripstone_block": "1.17", "glow_ink_sac": "1.17",
+	"glow_item_frame": "1.17", "lightning_rod": "1.17", "pointed_dripstone": "1.17", "powder_snow_bucket": "1.17",
+	"raw_copper": "1.17", "raw_gold": "1.17", "raw_iron": "1.17", "spyglass": "1.17",
+	"tuff": "1.17",
+	// 1.19
+	"disc_fragment_5": "1.19", "echo_shard": "1.19", "goat_horn": "1.19", "mangrove_boat": "1.19",
+	"mangrove_planks": "1.19", "mud": "1.19", "music_disc_5": "1.19", "oak_chest_boat": "1.19",
+	"recovery_compass": "1.19", "sculk": "1.19", "sculk_catalyst": "1.19", "sculk_shrieker": "1.19",
+	"tadpole_bucket": "1.19",
+	// 1.20
+	"bamboo_planks": "1.20", "brush": "1.20", "calibrated_sculk_sensor": "1.20", "cherry_planks": "1.20",
+	"decorated_pot": "1.20", "netherite_upgrade_smithing_template": "1.20", "pitcher_pod": "1.20", "sniffer_egg": "1.20",
+	"torchflower_seeds": "1.20",
+	// 1.20.5
+	"armadillo_scute": "1.20.5", "wolf_armor": "1.20.5",
+	// 1.21
+	"breeze_rod": "1.21", "copper_bulb": "1.21", "crafter": "1.21", "heavy_core": "1.21",
+	"mace": "1.21", "ominous_bottle": "1.21", "ominous_trial_key": "1.21", "trial_key": "1.21",
+	"trial_spawner": "1.21", "tuff_bricks": "1.21", "vault": "1.21", "wind_charge": "1.21",
+	// 1.21.2
+	"bundle": "1.21.2",
+	// 1.21.4
+	"pale_oak_planks": "1.21.4", "resin_clump": "1.21.4",
+}
+
+var (
+	unstackableItems = map[string]bool{
+		"bow": true, "cake": true, "carrot_on_a_stick": true, "crossbow": true,
+		"debug_stick": true, "elytra": true, "enchanted_book": true, "fishing_rod": true,
+		"flint_and_steel": true, "goat_horn": true, "knowledge_book": true, "mace": true,
+		"minecart": true, "potion": true, "saddle": true, "shears": true,
+		"shield": true, "shulker_box": true, "splash_potion": true, "lingering_potion": true,
+		"spyglass": true, "brush": true, "totem_of_undying": true, "trident": true,
+		"warped_fungus_on_a_stick": true, "writable_book": true, "bundle": true, "wolf_armor": true,
+		"mushroom_stew": true, "rabbit_stew": true, "beetroot_soup": true, "suspicious_stew": true,
+	}
+	unstackableSuffixes = []string{
+		"_sword", "_pickaxe", "_axe", "_shovel", "_hoe",
+		"_helmet", "_chestplate", "_leggings", "_boots", "_horse_armor",
+		"_boat", "_raft", "_minecart", "_bucket", "_shulker_box", "_bed", "_bundle",
+	}
+	smallStackItems = map[string]bool{
+		"armor_stand": true, "blue_egg": true, "brown_egg": true, "bucket": true,
+		"egg": true, "ender_pearl": true, "honey_bottle": true, "snowball": true,
+		"written_book": true,
+	}
+	smallStackSuffixes = []string{"_sign", "_banner"}
+)
+
+// Vanilla maximum stack size of an item id without namespace
+func maxStack(id string) int {
+	if smallStackItems[id] {
+		return 16
+	}
+	if unstackableItems[id] || strings.HasPrefix(id, "music_disc_") {
+		return 1
+	}
+	for _, suffix := range unstackableSuffixes {
+		if strings.HasSuffix(id, suffix) {
+			return 1
+		}
+	}
+	for _, suffix := range smallStackSuffixes {
+		if strings.HasSuffix(id, suffix) {
+			return 16
+		}
+	}
+	return 64
+}
@@ -0,0 +1,41 @@
+package mcapi
+
+import (
+	"context"
+	"github.com/StarForger/neb-mc-rcon/mcapi/text"
+	"regexp"
+	"strings"
+)
+
+// "Kicked Steve: Kicked by an operator"
+var kicked = regexp.MustCompile(`^Kicked (\S+)(?:: (.*))?$`)
+
+// Disconnect target with a reason, an empty reason uses the server's
+// default. ErrorPlayerNotFound is returned when nobody matched.
+func (c *Client) Kick(ctx context.Context, target string, reason string) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+
+	cmd := "kick " + target
+	if reason = singleLine(reason); reason != "" {
+		cmd += " " + reason
+	}
+	response, err := c.execute(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, kicked)
+	return err
+}
+
+// Kick with a text component reason. The kick command takes a plain message,
+// so only the component's text is sent.
+func (c *Client) KickComponent(ctx context.Context, target string, reason text.Component) error {
+	return c.Kick(ctx, target, reason.PlainText())
+}
+
+// Message arguments run to the end of the command, line breaks would end it
+func singleLine(s string) string {
+	return strings.TrimSpace(strings.Join(strings.Fields(s), " "))
+}
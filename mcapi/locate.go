@@ -0,0 +1,82 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrorNotLocated       = errors.New("mcapi: none found nearby")
+	ErrorUnknownStructure = errors.New("mcapi: unknown structure")
+	ErrorUnknownBiome     = errors.New("mcapi: unknown biome")
+)
+
+var (
+	// "The nearest minecraft:village_plains is at [-192, ~, 48] (246 blocks away)",
+	// before 1.13 "Located Village at -192 (y?) 48"
+	located        = regexp.MustCompile(`^(?:The nearest \S+ is at \[([^\]]+)\]|Located \S+ at (-?\d+) \(y\?\) (-?\d+))`)
+	locateFailures = []failure{
+		{regexp.MustCompile(`(?i)Could not find|Unable to find`), ErrorNotLocated},
+		{regexp.MustCompile(`(?i)no structure with type|Unknown structure|Invalid structure`), ErrorUnknownStructure},
+		{regexp.MustCompile(`(?i)no biome with type|Unknown biome|Invalid biome`), ErrorUnknownBiome},
+	}
+)
+
+// Nearest structure, by id with or without the "minecraft:" namespace, or
+// a "#" tag. Y is relative ("~") when the server does not report a height.
+func (c *Client) Locate(ctx context.Context, structure string) (Position, error) {
+	if err := checkWord(structure); err != nil {
+		return Position{}, err
+	}
+	version, err := c.minecraftVersion(ctx)
+	if err != nil {
+		return Position{}, err
+	}
+	cmd := "locate structure " + structure
+	if versionBefore(version, "1.19") {
+		cmd = "locate " + structure
+	}
+	return c.locate(ctx, cmd)
+}
+
+// Nearest biome, by id with or without the "minecraft:" namespace
+func (c *Client) LocateBiome(ctx context.Context, biome string) (Position, error) {
+	if err := checkWord(biome); err != nil {
+		return Position{}, err
+	}
+	version, err := c.minecraftVersion(ctx)
+	if err != nil {
+		return Position{}, err
+	}
+	if versionBefore(version, "1.16") {
+		return Position{}, ErrorUnsupported
+	}
+	cmd := "locate biome " + biome
+	if versionBefore(version, "1.19") {
+		cmd = "locatebiome " + biome
+	}
+	return c.locate(ctx, cmd)
+}
+
+func (c *Client) locate(ctx context.Context, cmd string) (Position, error) {
+	response, err := c.execute(ctx, cmd)
+	if err != nil {
+		return Position{}, err
+	}
+	m, err := matchResponse(response, located, locateFailures...)
+	if err != nil {
+		return Position{}, err
+	}
+
+	coords := m[1]
+	if coords == "" {
+		coords = m[2] + " ~ " + m[3]
+	}
+	pos, err := ParsePosition(strings.TrimSpace(coords))
+	if err != nil {
+		return Position{}, unexpected(response)
+	}
+	return pos, nil
+}
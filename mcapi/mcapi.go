@@ -0,0 +1,176 @@
+// Package mcapi provides typed wrappers for Minecraft server commands sent
+// over an RCON connection, parsing the server's text responses.
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrorUnexpectedResponse = errors.New("mcapi: unexpected response")
+	ErrorUnknownCommand     = errors.New("mcapi: unknown or incomplete command")
+	ErrorInvalidArgument    = errors.New("mcapi: incorrect argument for command")
+	ErrorPlayerNotFound     = errors.New("mcapi: player not found")
+)
+
+// Server messages that report a failure, and the error each maps to
+type failure struct {
+	pattern *regexp.Regexp
+	err     error
+}
+
+// Failures any command can report
+var commonFailures = []failure{
+	{regexp.MustCompile(`(?i)^Unknown (?:or incomplete )?command`), ErrorUnknownCommand},
+	{regexp.MustCompile(`(?i)^Incorrect argument for command`), ErrorInvalidArgument},
+	{regexp.MustCompile(`(?i)No player was found|That player does not exist|Player not found`), ErrorPlayerNotFound},
+}
+
+var colorCode = regexp.MustCompile(`§[0-9a-fk-orA-FK-ORxX]`)
+
+// Response a result was parsed from, embedded in result types. Parsed is
+// false when the response was not in a known format (a modded server, say),
+// leaving Response to fall back on.
+type Raw struct {
+	Response string
+	Parsed   bool
+}
+
+// Safe for concurrent use, which runs commands at once over connections
+// that multiplex them, once SetParsers and SetResolver, if used, have been
+// called
+type Client struct {
+	conn     conn.Client
+	parsers  *Parsers // DefaultParsers when nil
+	resolver Resolver // for player UUIDs, none when nil
+
+	lock    sync.Mutex // guards flavor and version
+	flavor  Flavor     // detected on first use
+	version string     // Minecraft version, detected on first use
+	detect  sync.Mutex // held while parse detects the flavor and version
+}
+
+// Marks the context of commands sent to detect the flavor and version, so
+// parsing their responses does not detect again
+type detectingKey struct{}
+
+func New(c conn.Client) *Client {
+	return &Client{conn: c}
+}
+
+// Send a command and return its response with formatting codes removed
+func (c *Client) execute(ctx context.Context, cmd string) (string, error) {
+	response, err := c.conn.ExecuteContext(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	return StripFormatting(response), nil
+}
+
+// The server's Minecraft version, empty when it could not be determined
+func (c *Client) minecraftVersion(ctx context.Context) (string, error) {
+	if _, version := c.detected(); version != "" {
+		return version, nil
+	}
+	v, err := c.ServerVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.version = v.MinecraftVersion
+	// Saves DetectFlavor asking again, it probes further for these
+	if flavor := v.Flavor(); flavor != FlavorUnknown && flavor != FlavorVanilla {
+		c.flavor = flavor
+	}
+	return c.version, nil
+}
+
+// Flavor and version detected so far
+func (c *Client) detected() (Flavor, string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.flavor, c.version
+}
+
+func (c *Client) setFlavor(flavor Flavor) Flavor {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.flavor = flavor
+	return flavor
+}
+
+// Remove § color and formatting codes
+func StripFormatting(s string) string {
+	return colorCode.ReplaceAllString(s, "")
+}
+
+// Check response against the command's success message and the known
+// failures, returning the success message's submatches
+func matchResponse(response string, success *regexp.Regexp, failures ...failure) ([]string, error) {
+	response = strings.TrimSpace(response)
+	if m := success.FindStringSubmatch(response); m != nil {
+		return m, nil
+	}
+	for _, f := range append(failures, commonFailures...) {
+		if f.pattern.MatchString(response) {
+			return nil, fmt.Errorf("%w: %q", f.err, response)
+		}
+	}
+	return nil, unexpected(response)
+}
+
+// Error for a response reporting one of the failures any command can have:
+// an unknown command, a bad argument or a missing player. Nil for others.
+func CommandFailure(response string) error {
+	response = strings.TrimSpace(response)
+	for _, f := range commonFailures {
+		if f.pattern.MatchString(response) {
+			return fmt.Errorf("%w: %q", f.err, response)
+		}
+	}
+	return nil
+}
+
+// Reject single word arguments (names, ids) that would break the command
+func checkWord(arg string) error {
+	if arg == "" || strings.ContainsAny(arg, " \t\r\n") {
+		return fmt.Errorf("%w: %q", ErrorInvalidArgument, arg)
+	}
+	return nil
+}
+
+// Reject player names or selectors that would break the command. Selectors
+// may contain spaces inside their brackets ("@a[team=red, tag=vip]").
+func checkTarget(target string) error {
+	depth := 0
+	for _, r := range target {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '\r', '\n':
+			return fmt.Errorf("%w: %q", ErrorInvalidArgument, target)
+		case ' ', '\t':
+			if depth == 0 {
+				return fmt.Errorf("%w: %q", ErrorInvalidArgument, target)
+			}
+		}
+	}
+	if target == "" || depth != 0 {
+		return fmt.Errorf("%w: %q", ErrorInvalidArgument, target)
+	}
+	return nil
+}
+
+func unexpected(response string) error {
+	return fmt.Errorf("%w: %q", ErrorUnexpectedResponse, strings.TrimSpace(response))
+}
@@ -0,0 +1,51 @@
+package mcapi
+
+import (
+	"context"
+	"regexp"
+)
+
+var (
+	opGranted = regexp.MustCompile(`^(?:Made (\S+) a server operator|Opped (\S+))`)
+	opRevoked = regexp.MustCompile(`^(?:Made (\S+) no longer a server operator|De-?opped (\S+))`)
+	// "Nothing changed. The player already is an operator"
+	opUnchanged = regexp.MustCompile(`^Nothing changed`)
+)
+
+// Operator management. Servers do not expose the op list over RCON, it is
+// only available from ops.json on the server host.
+type Ops struct {
+	client *Client
+}
+
+func (c *Client) Ops() *Ops {
+	return &Ops{client: c}
+}
+
+// Make name an operator, changed is false if it already was one
+func (o *Ops) Grant(ctx context.Context, name string) (changed bool, err error) {
+	return o.run(ctx, "op", name, opGranted)
+}
+
+// Remove name's operator status, changed is false if it was not an operator
+func (o *Ops) Revoke(ctx context.Context, name string) (changed bool, err error) {
+	return o.run(ctx, "deop", name, opRevoked)
+}
+
+func (o *Ops) run(ctx context.Context, cmd string, name string, success *regexp.Regexp) (bool, error) {
+	if err := checkWord(name); err != nil {
+		return false, err
+	}
+
+	response, err := o.client.execute(ctx, cmd+" "+name)
+	if err != nil {
+		return false, err
+	}
+	if opUnchanged.MatchString(response) {
+		return false, nil
+	}
+	if _, err := matchResponse(response, success); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,177 @@
+package mcapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Parses a command's response, already stripped of formatting codes
+type Parser func(response string) (interface{}, error)
+
+type parserEntry struct {
+	flavor     Flavor // FlavorUnknown for any
+	minVersion string // empty for any
+	parse      Parser
+}
+
+// Response parsers by command, server flavor and version. Commands are
+// matched by their leading words, so a parser for "forge tps" is used for
+// "forge tps minecraft:overworld".
+//
+// mcapi parses through the registry too, and a parser registered for a
+// command it uses must return the same type as the built in one: "list"
+// PlayerList, "version" and "about" ServerVersion, "tps" and "forge tps"
+// *Performance, "mspt" map[string]TickTimes, "banlist" []Ban and "seed"
+// int64.
+type Parsers struct {
+	lock    sync.RWMutex
+	entries map[string][]parserEntry
+}
+
+// Registry used by clients unless SetParsers is called. Registering on it
+// affects every such client.
+var DefaultParsers = NewParsers()
+
+// Registry holding the built in parsers
+func NewParsers() *Parsers {
+	p := &Parsers{entries: map[string][]parserEntry{}}
+	p.Register("list", FlavorUnknown, "", func(response string) (interface{}, error) {
+		return ParsePlayerList(response)
+	})
+	p.Register("version", FlavorUnknown, "", parseVersion)
+	p.Register("about", FlavorUnknown, "", parseVersion)
+	p.Register("tps", FlavorUnknown, "", func(response string) (interface{}, error) {
+		return ParsePaperTPS(response)
+	})
+	p.Register("mspt", FlavorUnknown, "", func(response string) (interface{}, error) {
+		return ParsePaperMSPT(response)
+	})
+	p.Register("forge tps", FlavorUnknown, "", func(response string) (interface{}, error) {
+		return ParseForgeTPS(response)
+	})
+	p.Register("banlist", FlavorUnknown, "", func(response string) (interface{}, error) {
+		return ParseBanList(response)
+	})
+	p.Register("seed", FlavorUnknown, "", func(response string) (interface{}, error) {
+		return ParseSeed(response)
+	})
+	return p
+}
+
+// Register parse for command on servers of flavor (FlavorUnknown for any)
+// running minVersion or later (empty for any). The most specific parser
+// wins, and of equally specific ones the last registered.
+func (p *Parsers) Register(command string, flavor Flavor, minVersion string, parse Parser) {
+	key := strings.Join(strings.Fields(strings.ToLower(command)), " ")
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.entries[key] = append(p.entries[key], parserEntry{flavor: flavor, minVersion: minVersion, parse: parse})
+}
+
+// Parser for a command line sent to a server of flavor and version, which
+// may be empty when not known
+func (p *Parsers) Lookup(command string, flavor Flavor, version string) (Parser, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	entries, _ := p.find(command)
+	var best *parserEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := &entries[i]
+		if e.flavor != FlavorUnknown && e.flavor != flavor {
+			continue
+		}
+		if e.minVersion != "" && (version == "" || versionBefore(version, e.minVersion)) {
+			continue
+		}
+		if best == nil || moreSpecific(e, best) {
+			best = e
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	return best.parse, true
+}
+
+// Entries for the longest registered prefix of command's words, and
+// whether any depend on the server's flavor or version
+func (p *Parsers) find(command string) ([]parserEntry, bool) {
+	words := strings.Fields(strings.ToLower(command))
+	for n := len(words); n > 0; n-- {
+		if entries, ok := p.entries[strings.Join(words[:n], " ")]; ok {
+			specific := false
+			for _, e := range entries {
+				specific = specific || e.flavor != FlavorUnknown || e.minVersion != ""
+			}
+			return entries, specific
+		}
+	}
+	return nil, false
+}
+
+func moreSpecific(a *parserEntry, b *parserEntry) bool {
+	if (a.flavor != FlavorUnknown) != (b.flavor != FlavorUnknown) {
+		return a.flavor != FlavorUnknown
+	}
+	if b.minVersion == "" {
+		return a.minVersion != ""
+	}
+	return a.minVersion != "" && versionBefore(b.minVersion, a.minVersion)
+}
+
+// Use parsers instead of DefaultParsers
+func (c *Client) SetParsers(parsers *Parsers) {
+	c.parsers = parsers
+}
+
+// Send a command and parse its response with the registered parser
+func (c *Client) Parse(ctx context.Context, cmd string) (interface{}, error) {
+	response, err := c.execute(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return c.parse(ctx, cmd, response)
+}
+
+// Parse response to cmd, detecting the server's flavor and version only
+// when a registered parser depends on them
+func (c *Client) parse(ctx context.Context, cmd string, response string) (interface{}, error) {
+	parsers := c.parsers
+	if parsers == nil {
+		parsers = DefaultParsers
+	}
+
+	parsers.lock.RLock()
+	_, specific := parsers.find(cmd)
+	parsers.lock.RUnlock()
+
+	// Detection parses responses too, which must not detect again
+	if specific && ctx.Value(detectingKey{}) == nil {
+		c.detect.Lock()
+		detecting := context.WithValue(ctx, detectingKey{}, true)
+		// Failures leave the generic parsers to be used
+		c.minecraftVersion(detecting)
+		c.DetectFlavor(detecting)
+		c.detect.Unlock()
+	}
+
+	flavor, version := c.detected()
+	parse, ok := parsers.Lookup(cmd, flavor, version)
+	if !ok {
+		return nil, fmt.Errorf("%w: no parser for %q", ErrorUnsupported, cmd)
+	}
+	return parse(response)
+}
+
+// Error for a registered parser that returned the wrong type
+func wrongType(cmd string, v interface{}) error {
+	return fmt.Errorf("%w: parser for %q returned %T", ErrorUnexpectedResponse, cmd, v)
+}
+
+func parseVersion(response string) (interface{}, error) {
+	return ParseServerVersion(response), nil
+}
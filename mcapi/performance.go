@@ -0,0 +1,228 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrorUnsupported = errors.New("mcapi: not supported by this server")
+)
+
+type Flavor string
+
+const (
+	FlavorUnknown Flavor = "unknown"
+	FlavorVanilla Flavor = "vanilla"
+	FlavorPaper   Flavor = "paper" // includes Spigot/Bukkit derivatives with a tps command
+	FlavorForge   Flavor = "forge"
+	FlavorFabric  Flavor = "fabric"
+)
+
+var (
+	// "TPS from last 1m, 5m, 15m: 20.0, *20.0, 19.98"
+	paperTPS = regexp.MustCompile(`TPS from last ([^:]+):\s*(.+)`)
+	// "Server tick times (avg/min/max) from last 5s, 10s, 1m:" followed by
+	// "◴ 1.2/0.5/3.4, 1.1/0.4/5.0, 1.3/0.3/9.0"
+	paperMSPTHeader = regexp.MustCompile(`Server tick times \(avg/min/max\) from last ([^:]+):`)
+	paperMSPTValue  = regexp.MustCompile(`([\d.]+)/([\d.]+)/([\d.]+)`)
+	// "Dim minecraft:overworld (minecraft:overworld): Mean tick time: 0.456 ms. Mean TPS: 20.000",
+	// "Overall: Mean tick time: 0.789 ms. Mean TPS: 20.000" and newer
+	// "minecraft:overworld: 20.000 TPS (0.456 ms/tick)"
+	forgeOld = regexp.MustCompile(`(?m)^\s*(?:Dim\s+)?(.+?)(?:\s+\([^)]*\))?:\s*Mean tick time:\s*([\d.]+)\s*ms\.?\s*Mean TPS:\s*([\d.]+)`)
+	forgeNew = regexp.MustCompile(`(?m)^\s*(.+?):\s*([\d.]+)\s*TPS\s*\(([\d.]+)\s*ms/tick\)`)
+)
+
+// Tick time statistics in milliseconds
+type TickTimes struct {
+	Avg, Min, Max float64
+}
+
+type DimensionPerformance struct {
+	TPS  float64
+	MSPT float64
+}
+
+// Response is the tps (Paper) or forge tps output
+type Performance struct {
+	Raw
+	Flavor Flavor
+	// TPS by averaging window ("1m", "5m", "15m"), Paper
+	TPS map[string]float64
+	// Tick times by window ("5s", "10s", "1m"), Paper
+	MSPT map[string]TickTimes
+	// Per dimension ("minecraft:overworld") and "Overall", Forge
+	Dimensions map[string]DimensionPerformance
+}
+
+// Find the server flavor from its version, or by probing the performance
+// commands it supports
+func (c *Client) DetectFlavor(ctx context.Context) (Flavor, error) {
+	if flavor, _ := c.detected(); flavor != "" {
+		return flavor, nil
+	}
+
+	version, err := c.ServerVersion(ctx)
+	if err != nil {
+		return FlavorUnknown, err
+	}
+	if flavor := version.Flavor(); flavor != FlavorUnknown && flavor != FlavorVanilla {
+		return c.setFlavor(flavor), nil
+	}
+
+	// Forge and older servers have no version command, probe instead
+	response, err := c.execute(ctx, "tps")
+	if err != nil {
+		return FlavorUnknown, err
+	}
+	if paperTPS.MatchString(response) {
+		return c.setFlavor(FlavorPaper), nil
+	}
+
+	response, err = c.execute(ctx, "forge tps")
+	if err != nil {
+		return FlavorUnknown, err
+	}
+	if forgeOld.MatchString(response) || forgeNew.MatchString(response) {
+		return c.setFlavor(FlavorForge), nil
+	}
+
+	return c.setFlavor(FlavorVanilla), nil
+}
+
+// Server tick rate and tick times, from tps and mspt (Paper) or forge tps
+func (c *Client) Performance(ctx context.Context) (*Performance, error) {
+	flavor, err := c.DetectFlavor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch flavor {
+	case FlavorPaper:
+		response, err := c.execute(ctx, "tps")
+		if err != nil {
+			return nil, err
+		}
+		perf, err := c.parsePerformance(ctx, "tps", response)
+		if err != nil {
+			return perf, err
+		}
+		response, err = c.execute(ctx, "mspt")
+		if err != nil {
+			return nil, err
+		}
+		// mspt is Paper only, Spigot lacks it
+		if v, err := c.parse(ctx, "mspt", response); err == nil {
+			if mspt, ok := v.(map[string]TickTimes); ok {
+				perf.MSPT = mspt
+			}
+		}
+		return perf, nil
+
+	case FlavorForge:
+		response, err := c.execute(ctx, "forge tps")
+		if err != nil {
+			return nil, err
+		}
+		return c.parsePerformance(ctx, "forge tps", response)
+	}
+
+	return nil, ErrorUnsupported
+}
+
+func (c *Client) parsePerformance(ctx context.Context, cmd string, response string) (*Performance, error) {
+	v, err := c.parse(ctx, cmd, response)
+	perf, ok := v.(*Performance)
+	if !ok && err == nil {
+		return nil, wrongType(cmd, v)
+	}
+	return perf, err
+}
+
+// Parse Paper/Spigot tps output. When the output is not recognised the
+// result holds only the response, along with the error.
+func ParsePaperTPS(response string) (*Performance, error) {
+	response = StripFormatting(response)
+	unparsed := &Performance{Raw: Raw{Response: response}, Flavor: FlavorPaper}
+	m := paperTPS.FindStringSubmatch(response)
+	if m == nil {
+		return unparsed, unexpected(response)
+	}
+
+	windows := splitList(m[1])
+	values := splitList(m[2])
+	if len(windows) != len(values) {
+		return unparsed, unexpected(response)
+	}
+
+	perf := &Performance{Raw: Raw{response, true}, Flavor: FlavorPaper, TPS: map[string]float64{}}
+	for i, w := range windows {
+		// "*20.0" marks a value capped at 20
+		v, err := strconv.ParseFloat(strings.TrimPrefix(values[i], "*"), 64)
+		if err != nil {
+			return unparsed, unexpected(response)
+		}
+		perf.TPS[w] = v
+	}
+	return perf, nil
+}
+
+// Parse Paper mspt output
+func ParsePaperMSPT(response string) (map[string]TickTimes, error) {
+	response = StripFormatting(response)
+	m := paperMSPTHeader.FindStringSubmatchIndex(response)
+	if m == nil {
+		return nil, unexpected(response)
+	}
+
+	windows := splitList(response[m[2]:m[3]])
+	values := paperMSPTValue.FindAllStringSubmatch(response[m[1]:], -1)
+	if len(values) != len(windows) {
+		return nil, unexpected(response)
+	}
+
+	mspt := map[string]TickTimes{}
+	for i, w := range windows {
+		avg, _ := strconv.ParseFloat(values[i][1], 64)
+		min, _ := strconv.ParseFloat(values[i][2], 64)
+		max, _ := strconv.ParseFloat(values[i][3], 64)
+		mspt[w] = TickTimes{Avg: avg, Min: min, Max: max}
+	}
+	return mspt, nil
+}
+
+// Parse forge tps (or neoforge tps) output. When the output is not
+// recognised the result holds only the response, along with the error.
+func ParseForgeTPS(response string) (*Performance, error) {
+	response = StripFormatting(response)
+	perf := &Performance{Raw: Raw{response, true}, Flavor: FlavorForge, Dimensions: map[string]DimensionPerformance{}}
+
+	for _, m := range forgeOld.FindAllStringSubmatch(response, -1) {
+		mspt, _ := strconv.ParseFloat(m[2], 64)
+		tps, _ := strconv.ParseFloat(m[3], 64)
+		perf.Dimensions[strings.TrimSpace(m[1])] = DimensionPerformance{TPS: tps, MSPT: mspt}
+	}
+	for _, m := range forgeNew.FindAllStringSubmatch(response, -1) {
+		tps, _ := strconv.ParseFloat(m[2], 64)
+		mspt, _ := strconv.ParseFloat(m[3], 64)
+		perf.Dimensions[strings.TrimSpace(m[1])] = DimensionPerformance{TPS: tps, MSPT: mspt}
+	}
+
+	if len(perf.Dimensions) == 0 {
+		return &Performance{Raw: Raw{Response: response}, Flavor: FlavorForge}, unexpected(response)
+	}
+	return perf, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
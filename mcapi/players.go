@@ -0,0 +1,152 @@
+package mcapi
+
+import (
+	"context"
+	"github.com/StarForger/neb-mc-rcon/mojang"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Formats of the list command's header line:
+//
+//	There are 2 of a max of 20 players online: Steve, Alex    (1.13+, Paper)
+//	There are 2/20 players online:Steve, Alex                  (before 1.13)
+//	There are 2 out of maximum 20 players online.              (Essentials)
+//	There are 2/3 out of maximum 20 players online.            (Essentials, hidden players)
+//
+// list uuids follows each name with its UUID in brackets.
+var listHeaders = []*regexp.Regexp{
+	regexp.MustCompile(`There are (\d+) of a max(?: of)? (\d+) players online[:.]?`),
+	regexp.MustCompile(`There are (\d+)/(\d+) players online[:.]?`),
+	regexp.MustCompile(`There are (\d+)(?:/\d+)? out of maximum (\d+) players online\.?`),
+}
+
+var (
+	// "Steve (8667ba71-b85a-4004-af54-457a9734eed7)" from list uuids, the
+	// UUID dashed or not
+	listUUID = regexp.MustCompile(`\s*\(([0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12})\)`)
+	// Essentials status tags, "[AFK]Steve"
+	listTag = regexp.MustCompile(`\[(?i:AFK|HIDDEN)\]`)
+)
+
+// Output of the list command
+type PlayerList struct {
+	Raw
+	Online  int
+	Max     int
+	Names   []string
+	Players []Player // the names again, with UUIDs from list uuids
+}
+
+// Player online
+type Player struct {
+	Name string
+	UUID string // dashed and lower case, empty unless listed with list uuids
+}
+
+// Online and maximum player counts and the names of online players
+func (c *Client) Players(ctx context.Context) (online int, max int, names []string, err error) {
+	list, err := c.playerList(ctx)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return list.Online, list.Max, list.Names, nil
+}
+
+// Online players with their UUIDs, from list uuids (1.13+). Servers
+// without it list names only, leaving the UUIDs empty.
+func (c *Client) PlayerUUIDs(ctx context.Context) ([]Player, error) {
+	list, err := c.listCommand(ctx, "list uuids")
+	if err != nil {
+		return nil, err
+	}
+	return list.Players, nil
+}
+
+func (c *Client) playerList(ctx context.Context) (PlayerList, error) {
+	return c.listCommand(ctx, "list")
+}
+
+func (c *Client) listCommand(ctx context.Context, cmd string) (PlayerList, error) {
+	response, err := c.execute(ctx, cmd)
+	if err != nil {
+		return PlayerList{}, err
+	}
+	v, err := c.parse(ctx, cmd, response)
+	list, ok := v.(PlayerList)
+	if !ok && err == nil {
+		return PlayerList{}, wrongType(cmd, v)
+	}
+	return list, err
+}
+
+// Parse the output of list (or list uuids)
+func ParsePlayerList(response string) (PlayerList, error) {
+	response = StripFormatting(response)
+	online, max, players, err := parseList(response)
+	if err != nil {
+		return PlayerList{Raw: Raw{Response: response}}, err
+	}
+	return PlayerList{Raw{response, true}, online, max, playerNames(players), players}, nil
+}
+
+// Parse the output of list (or list uuids)
+func ParseList(response string) (online int, max int, names []string, err error) {
+	online, max, players, err := parseList(StripFormatting(response))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return online, max, playerNames(players), nil
+}
+
+func parseList(response string) (online int, max int, players []Player, err error) {
+	for _, header := range listHeaders {
+		m := header.FindStringSubmatchIndex(response)
+		if m == nil {
+			continue
+		}
+		online, _ = strconv.Atoi(response[m[2]:m[3]])
+		max, _ = strconv.Atoi(response[m[4]:m[5]])
+		players = parsePlayers(response[m[1]:])
+		return online, max, players, nil
+	}
+
+	return 0, 0, nil, unexpected(response)
+}
+
+// Names follow the header, either on the same line or, for Essentials, one
+// line per group as "group: name, name"
+func parsePlayers(s string) []Player {
+	players := []Player{}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if i := strings.Index(line, ": "); i >= 0 && !strings.Contains(line[:i], ",") {
+			line = line[i+2:]
+		}
+		for _, entry := range strings.Split(line, ",") {
+			var p Player
+			if m := listUUID.FindStringSubmatch(entry); m != nil {
+				p.UUID = strings.ToLower(mojang.FormatUUID(m[1]))
+				entry = listUUID.ReplaceAllString(entry, "")
+			}
+			entry = listTag.ReplaceAllString(entry, "")
+			p.Name = strings.TrimSpace(entry)
+			if p.Name != "" {
+				players = append(players, p)
+			}
+		}
+	}
+	return players
+}
+
+func playerNames(players []Player) []string {
+	names := make([]string, len(players))
+	for i, p := range players {
+		names[i] = p.Name
+	}
+	return names
+}
@@ -0,0 +1,72 @@
+package mcapi
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+// Each testdata/list_*.txt holds a list response as a server sends it, and
+// the .golden file beside it the parse
+func TestParsePlayerListGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/list_*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no testdata")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(name, func(t *testing.T) {
+			response, err := ioutil.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			list, err := ParsePlayerList(string(response))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := formatPlayerList(list)
+
+			golden := strings.TrimSuffix(input, ".txt") + ".golden"
+			if *update {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestParsePlayerListUnexpected(t *testing.T) {
+	if _, err := ParsePlayerList("Unknown command"); err == nil {
+		t.Error("parsed an unknown command's response")
+	}
+}
+
+func formatPlayerList(list PlayerList) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "online %d\nmax %d\n", list.Online, list.Max)
+	for _, p := range list.Players {
+		if p.UUID == "" {
+			fmt.Fprintln(&b, p.Name)
+		} else {
+			fmt.Fprintln(&b, p.Name, p.UUID)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package mcapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Axes set of a Position given relative to the executing entity ("~")
+type Axes uint8
+
+const (
+	AxisX Axes = 1 << iota
+	AxisY
+	AxisZ
+
+	AllAxes = AxisX | AxisY | AxisZ
+)
+
+// World coordinates with optional rotation. Axes in Relative are offsets
+// from the target ("~1"), and Local makes X, Y, Z left, up and forward
+// offsets ("^1").
+type Position struct {
+	X, Y, Z    float64
+	Yaw, Pitch float32
+	Relative   Axes
+	Local      bool
+	Rotate     bool // include Yaw and Pitch
+}
+
+// Absolute block coordinates
+func At(x, y, z float64) Position {
+	return Position{X: x, Y: y, Z: z}
+}
+
+// Offset from the target's position
+func Offset(dx, dy, dz float64) Position {
+	return Position{X: dx, Y: dy, Z: dz, Relative: AllAxes}
+}
+
+// Left, up, forward offset from the target's position and facing
+func LocalOffset(left, up, forward float64) Position {
+	return Position{X: left, Y: up, Z: forward, Local: true}
+}
+
+func (p Position) WithRotation(yaw, pitch float32) Position {
+	p.Yaw = yaw
+	p.Pitch = pitch
+	p.Rotate = true
+	return p
+}
+
+// Command argument form, "10.5 ~ -3" or "^ ^1 ^2"
+func (p Position) String() string {
+	coords := []string{
+		formatCoord(p.X, p.Local, p.Relative&AxisX != 0),
+		formatCoord(p.Y, p.Local, p.Relative&AxisY != 0),
+		formatCoord(p.Z, p.Local, p.Relative&AxisZ != 0),
+	}
+	if p.Rotate {
+		coords = append(coords,
+			strconv.FormatFloat(float64(p.Yaw), 'f', -1, 32),
+			strconv.FormatFloat(float64(p.Pitch), 'f', -1, 32))
+	}
+	return strings.Join(coords, " ")
+}
+
+func formatCoord(v float64, local bool, relative bool) string {
+	prefix := ""
+	switch {
+	case local:
+		prefix = "^"
+	case relative:
+		prefix = "~"
+	default:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if v == 0 {
+		return prefix
+	}
+	return prefix + strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Parse "x y z" (with optional "~" and "^" prefixes) as printed by the
+// server or as used in commands; separators may be spaces or commas
+func ParsePosition(s string) (Position, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) != 3 && len(fields) != 5 {
+		return Position{}, fmt.Errorf("%w: position %q", ErrorInvalidArgument, s)
+	}
+
+	var p Position
+	coords := []*float64{&p.X, &p.Y, &p.Z}
+	for i, f := range fields[:3] {
+		switch {
+		case strings.HasPrefix(f, "^"):
+			p.Local = true
+			f = f[1:]
+		case strings.HasPrefix(f, "~"):
+			p.Relative |= AxisX << uint(i)
+			f = f[1:]
+		}
+		if f == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return Position{}, fmt.Errorf("%w: position %q", ErrorInvalidArgument, s)
+		}
+		*coords[i] = v
+	}
+
+	if len(fields) == 5 {
+		yaw, err1 := strconv.ParseFloat(fields[3], 32)
+		pitch, err2 := strconv.ParseFloat(fields[4], 32)
+		if err1 != nil || err2 != nil {
+			return Position{}, fmt.Errorf("%w: rotation %q", ErrorInvalidArgument, s)
+		}
+		p = p.WithRotation(float32(yaw), float32(pitch))
+	}
+	return p, nil
+}
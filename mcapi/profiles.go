@@ -0,0 +1,66 @@
+package mcapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/mojang"
+)
+
+// Looks up player profiles by name or UUID, as *mojang.Client does
+type Resolver interface {
+	Lookup(ctx context.Context, nameOrUUID string) (mojang.Profile, error)
+}
+
+// Resolve UUIDs given to the whitelist and ban list tooling, and annotate
+// names with UUIDs. Without a resolver, UUIDs are refused and names left
+// unannotated. Like SetParsers, call it before the client is shared.
+func (c *Client) SetResolver(r Resolver) {
+	c.resolver = r
+}
+
+// Profiles for names, each with an empty UUID when no resolver is set or
+// the lookup failed
+func (c *Client) Profiles(ctx context.Context, names []string) []mojang.Profile {
+	profiles := make([]mojang.Profile, len(names))
+	for i, name := range names {
+		profiles[i].Name = name
+		if c.resolver == nil {
+			continue
+		}
+		if p, err := c.resolver.Lookup(ctx, name); err == nil {
+			profiles[i].UUID = p.UUID
+		}
+	}
+	return profiles
+}
+
+// Player names for entries that may be UUIDs, those that can't be resolved
+// reported in failed by entry
+func (c *Client) resolveNames(ctx context.Context, entries []string) (names []string, failed map[string]error) {
+	failed = map[string]error{}
+	for _, entry := range entries {
+		if !mojang.IsUUID(entry) {
+			names = append(names, entry)
+			continue
+		}
+		if c.resolver == nil {
+			failed[entry] = fmt.Errorf("mcapi: no resolver for uuid %s", entry)
+			continue
+		}
+		p, err := c.resolver.Lookup(ctx, entry)
+		if err != nil {
+			failed[entry] = err
+			continue
+		}
+		names = append(names, p.Name)
+	}
+	return names, failed
+}
+
+// First of the resolve failures, for the calls that return a single error
+func firstFailure(failed map[string]error) error {
+	for entry, err := range failed {
+		return fmt.Errorf("%s: %w", entry, err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package mcapi
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/StarForger/neb-mc-rcon/mojang"
+	"github.com/StarForger/neb-mc-rcon/rcontest"
+)
+
+const notchUUID = "069a79f4-44e9-4726-a5be-fca90e38aaf5"
+
+// Resolver knowing only Notch
+type fakeResolver struct{}
+
+func (fakeResolver) Lookup(ctx context.Context, nameOrUUID string) (mojang.Profile, error) {
+	if strings.EqualFold(nameOrUUID, "Notch") || strings.ReplaceAll(nameOrUUID, "-", "") == strings.ReplaceAll(notchUUID, "-", "") {
+		return mojang.Profile{Name: "Notch", UUID: notchUUID}, nil
+	}
+	return mojang.Profile{}, mojang.ErrorNotFound
+}
+
+// Server whose whitelist holds names, recording the commands it runs
+func whitelistServer(t *testing.T, names ...string) (*Client, func() []string) {
+	var lock sync.Mutex
+	var cmds []string
+	server := rcontest.NewServer(t, rcontest.Options{
+		Handler: func(cmd string) string {
+			lock.Lock()
+			defer lock.Unlock()
+			cmds = append(cmds, cmd)
+			switch {
+			case cmd == "whitelist list":
+				return "There are 1 whitelisted player(s): " + strings.Join(names, ", ")
+			case strings.HasPrefix(cmd, "whitelist add "):
+				return "Added " + cmd[len("whitelist add "):] + " to the whitelist"
+			case strings.HasPrefix(cmd, "whitelist remove "):
+				return "Removed " + cmd[len("whitelist remove "):] + " from the whitelist"
+			}
+			return "Unknown command"
+		},
+	})
+	commands := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), cmds...)
+	}
+	return New(server.Dial()), commands
+}
+
+func TestWhitelistSyncUUIDs(t *testing.T) {
+	client, commands := whitelistServer(t, "Steve")
+	client.SetResolver(fakeResolver{})
+
+	result, err := client.Whitelist().Sync(context.Background(), []string{notchUUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"Notch"}) || !reflect.DeepEqual(result.Removed, []string{"Steve"}) || len(result.Failed) != 0 {
+		t.Fatalf("result %+v", result)
+	}
+	want := []string{"whitelist list", "whitelist add Notch", "whitelist remove Steve"}
+	if got := commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands %q, want %q", got, want)
+	}
+}
+
+// An unresolved UUID fails, and holds back removals as it may be one of
+// the players listed
+func TestWhitelistSyncUnresolved(t *testing.T) {
+	unknown := "00000000-0000-0000-0000-000000000000"
+	tests := []struct {
+		name     string
+		resolver Resolver
+	}{
+		{"no resolver", nil},
+		{"not found", fakeResolver{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, commands := whitelistServer(t, "Steve")
+			client.SetResolver(tt.resolver)
+
+			result, err := client.Whitelist().Sync(context.Background(), []string{unknown, "Alex"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := result.Failed[unknown]; !ok || len(result.Failed) != 1 {
+				t.Fatalf("failed %v", result.Failed)
+			}
+			if !reflect.DeepEqual(result.Added, []string{"Alex"}) || len(result.Removed) != 0 {
+				t.Fatalf("result %+v", result)
+			}
+			for _, cmd := range commands() {
+				if strings.HasPrefix(cmd, "whitelist remove") {
+					t.Errorf("sent %q", cmd)
+				}
+			}
+
+			if _, _, err := client.Whitelist().Plan(context.Background(), []string{unknown}); err == nil {
+				t.Error("plan with an unresolved uuid")
+			}
+		})
+	}
+}
+
+func TestWhitelistResolved(t *testing.T) {
+	client, _ := whitelistServer(t, "Notch", "Steve")
+	client.SetResolver(fakeResolver{})
+
+	profiles, err := client.Whitelist().Resolved(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	want := []mojang.Profile{{Name: "Notch", UUID: notchUUID}, {Name: "Steve"}}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("got %+v, want %+v", profiles, want)
+	}
+}
+
+func TestBansResolved(t *testing.T) {
+	server := rcontest.NewServer(t, rcontest.Options{
+		Responses: map[string]string{
+			"banlist players": "There are 2 ban(s):Notch was banned by Server: Banned by an operator.Steve was banned by Server: Spam",
+		},
+	})
+	client := New(server.Dial())
+	client.SetResolver(fakeResolver{})
+
+	bans, err := client.Bans().Resolved(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bans) != 2 || bans[0].UUID != notchUUID || bans[1].UUID != "" {
+		t.Errorf("got %+v", bans)
+	}
+}
@@ -0,0 +1,81 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Used when the context passed to SaveAll has no deadline. Flushing a large
+// world can take minutes.
+const DefaultSaveTimeout = 5 * time.Minute
+
+var (
+	ErrorSaveFailed      = errors.New("mcapi: saving failed")
+	ErrorSaveUnconfirmed = errors.New("mcapi: save started but completion was not confirmed")
+)
+
+var (
+	saveStarted = regexp.MustCompile(`Saving the game`)
+	saveDone    = regexp.MustCompile(`Saved the game`)
+	// "Automatic saving is now disabled", "Saving is already turned off" and
+	// before 1.13 "Turned off world auto-saving"
+	saveOff = regexp.MustCompile(`^(?:Automatic saving is now disabled|Saving is already turned off|Turned off world auto-saving)`)
+	saveOn  = regexp.MustCompile(`^(?:Automatic saving is now enabled|Saving is already turned on|Turned on world auto-saving)`)
+
+	saveFailures = []failure{
+		{regexp.MustCompile(`(?i)Unable to save|Saving failed`), ErrorSaveFailed},
+	}
+)
+
+// Save all worlds, flush also waits for chunks to be written to disk. It
+// returns once the server confirmed "Saved the game", so backups can start.
+func (c *Client) SaveAll(ctx context.Context, flush bool) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultSaveTimeout)
+		defer cancel()
+	}
+
+	cmd := "save-all"
+	if flush {
+		cmd += " flush"
+	}
+	response, err := c.execute(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	if saveDone.MatchString(response) {
+		return nil
+	}
+	// Servers that save asynchronously only report the start
+	if saveStarted.MatchString(response) {
+		return fmt.Errorf("%w: %q", ErrorSaveUnconfirmed, strings.TrimSpace(response))
+	}
+	_, err = matchResponse(response, saveDone, saveFailures...)
+	return err
+}
+
+// Disable automatic saving, typically before copying the world
+func (c *Client) SaveOff(ctx context.Context) error {
+	response, err := c.execute(ctx, "save-off")
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, saveOff, saveFailures...)
+	return err
+}
+
+// Re-enable automatic saving
+func (c *Client) SaveOn(ctx context.Context) error {
+	response, err := c.execute(ctx, "save-on")
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, saveOn, saveFailures...)
+	return err
+}
@@ -0,0 +1,20 @@
+package mcapi
+
+import (
+	"context"
+)
+
+// Broadcast a message to every player, shown as from the server ("[Rcon]"
+// in vanilla). Line breaks are sent as spaces.
+func (c *Client) Say(ctx context.Context, message string) error {
+	message = singleLine(message)
+	if message == "" {
+		return ErrorInvalidArgument
+	}
+	response, err := c.execute(ctx, "say "+message)
+	if err != nil {
+		return err
+	}
+	// Vanilla answers with nothing, plugins may echo the message
+	return CommandFailure(response)
+}
@@ -0,0 +1,40 @@
+package mcapi
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+)
+
+// "Seed: [-4172144997902289642]" and before 1.13 "Seed: -4172144997902289642"
+var seedValue = regexp.MustCompile(`^Seed: \[?(-?\d+)\]?$`)
+
+// The world seed
+func (c *Client) Seed(ctx context.Context) (int64, error) {
+	response, err := c.execute(ctx, "seed")
+	if err != nil {
+		return 0, err
+	}
+	v, err := c.parse(ctx, "seed", response)
+	if err != nil {
+		return 0, err
+	}
+	seed, ok := v.(int64)
+	if !ok {
+		return 0, wrongType("seed", v)
+	}
+	return seed, nil
+}
+
+// Parse the output of seed
+func ParseSeed(response string) (int64, error) {
+	m, err := matchResponse(StripFormatting(response), seedValue)
+	if err != nil {
+		return 0, err
+	}
+	seed, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, unexpected(response)
+	}
+	return seed, nil
+}
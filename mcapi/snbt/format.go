@@ -0,0 +1,119 @@
+package snbt
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format a value as SNBT, the inverse of Parse. Besides the types Parse
+// returns, int is written as an int and bool as a byte. Compound keys are
+// sorted so the output is stable.
+func Format(v interface{}) (string, error) {
+	var b strings.Builder
+	if err := format(&b, v); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func format(b *strings.Builder, v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(formatString(k))
+			b.WriteByte(':')
+			if err := format(b, v[k]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case []interface{}:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := format(b, e); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case []int8:
+		b.WriteString("[B;")
+		for i, n := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.FormatInt(int64(n), 10) + "b")
+		}
+		b.WriteByte(']')
+	case []int32:
+		b.WriteString("[I;")
+		for i, n := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.FormatInt(int64(n), 10))
+		}
+		b.WriteByte(']')
+	case []int64:
+		b.WriteString("[L;")
+		for i, n := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.FormatInt(n, 10) + "L")
+		}
+		b.WriteByte(']')
+	case bool:
+		if v {
+			b.WriteString("1b")
+		} else {
+			b.WriteString("0b")
+		}
+	case int8:
+		b.WriteString(strconv.FormatInt(int64(v), 10) + "b")
+	case int16:
+		b.WriteString(strconv.FormatInt(int64(v), 10) + "s")
+	case int32:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10) + "L")
+	case float32:
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32) + "f")
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'g', -1, 64) + "d")
+	case string:
+		b.WriteString(formatString(v))
+	default:
+		return fmt.Errorf("%w: cannot format %T", ErrorSyntax, v)
+	}
+	return nil
+}
+
+// Quote strings that would not read back as the same string unquoted
+func formatString(s string) string {
+	plain := s != ""
+	for i := 0; i < len(s) && plain; i++ {
+		plain = isUnquoted(s[i])
+	}
+	if plain {
+		if _, isString := parseScalar(s).(string); isString {
+			return s
+		}
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
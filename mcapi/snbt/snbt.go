@@ -0,0 +1,312 @@
+// Package snbt parses stringified NBT, the text form of NBT data printed by
+// commands such as data get.
+//
+// Values map to Go types as follows:
+//
+//	compound          map[string]interface{}
+//	list              []interface{}
+//	[B; ...]          []int8
+//	[I; ...]          []int32
+//	[L; ...]          []int64
+//	1b, true, false   int8
+//	1s                int16
+//	1                 int32
+//	1L                int64
+//	1.5f              float32
+//	1.5d, 1.5         float64
+//	"text", 'text'    string
+package snbt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrorSyntax = errors.New("snbt: syntax error")
+)
+
+// Parse a complete SNBT value
+func Parse(s string) (interface{}, error) {
+	p := &parser{s: s}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	if p.pos != len(p.s) {
+		return nil, p.errorf("trailing data")
+	}
+	return v, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrorSyntax, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) space() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) expect(c byte) error {
+	p.space()
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) value() (interface{}, error) {
+	p.space()
+	switch c := p.peek(); {
+	case c == '{':
+		return p.compound()
+	case c == '[':
+		return p.list()
+	case c == '"' || c == '\'':
+		return p.quoted()
+	case c == 0:
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	word := p.unquoted()
+	if word == "" {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return parseScalar(word), nil
+}
+
+func (p *parser) compound() (interface{}, error) {
+	p.pos++ // {
+	m := map[string]interface{}{}
+
+	p.space()
+	if p.peek() == '}' {
+		p.pos++
+		return m, nil
+	}
+	for {
+		p.space()
+		var key string
+		if c := p.peek(); c == '"' || c == '\'' {
+			k, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			key = k
+		} else {
+			key = p.unquoted()
+			if key == "" {
+				return nil, p.errorf("expected key")
+			}
+		}
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+
+		p.space()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return m, nil
+		default:
+			return nil, p.errorf("expected ',' or '}'")
+		}
+	}
+}
+
+func (p *parser) list() (interface{}, error) {
+	p.pos++ // [
+
+	// Typed arrays, [B; 1b, 2b]
+	if p.pos+1 < len(p.s) && p.s[p.pos+1] == ';' && strings.IndexByte("BIL", p.s[p.pos]) >= 0 {
+		kind := p.s[p.pos]
+		p.pos += 2
+		return p.array(kind)
+	}
+
+	list := []interface{}{}
+	p.space()
+	if p.peek() == ']' {
+		p.pos++
+		return list, nil
+	}
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+
+		p.space()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return list, nil
+		default:
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *parser) array(kind byte) (interface{}, error) {
+	var bytes []int8
+	var ints []int32
+	var longs []int64
+
+	p.space()
+	for p.peek() != ']' {
+		p.space()
+		word := p.unquoted()
+		if word == "" {
+			return nil, p.errorf("expected number")
+		}
+		trimmed := strings.TrimRight(word, "bBlL")
+		n, err := strconv.ParseInt(trimmed, 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid array element %q", word)
+		}
+		switch kind {
+		case 'B':
+			bytes = append(bytes, int8(n))
+		case 'I':
+			ints = append(ints, int32(n))
+		case 'L':
+			longs = append(longs, n)
+		}
+
+		p.space()
+		if p.peek() == ',' {
+			p.pos++
+			p.space()
+		} else if p.peek() != ']' {
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+	p.pos++ // ]
+
+	switch kind {
+	case 'B':
+		if bytes == nil {
+			bytes = []int8{}
+		}
+		return bytes, nil
+	case 'I':
+		if ints == nil {
+			ints = []int32{}
+		}
+		return ints, nil
+	}
+	if longs == nil {
+		longs = []int64{}
+	}
+	return longs, nil
+}
+
+func (p *parser) quoted() (string, error) {
+	quote := p.s[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch c {
+		case '\\':
+			if p.pos >= len(p.s) {
+				return "", p.errorf("unterminated escape")
+			}
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		case quote:
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) unquoted() string {
+	start := p.pos
+	for p.pos < len(p.s) && isUnquoted(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func isUnquoted(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '.' || c == '+'
+}
+
+// Numbers by suffix, booleans as bytes, anything else is a string
+func parseScalar(word string) interface{} {
+	switch word {
+	case "true":
+		return int8(1)
+	case "false":
+		return int8(0)
+	}
+
+	last := word[len(word)-1]
+	body := word[:len(word)-1]
+	switch last {
+	case 'b', 'B':
+		if n, err := strconv.ParseInt(body, 10, 8); err == nil {
+			return int8(n)
+		}
+	case 's', 'S':
+		if n, err := strconv.ParseInt(body, 10, 16); err == nil {
+			return int16(n)
+		}
+	case 'l', 'L':
+		if n, err := strconv.ParseInt(body, 10, 64); err == nil {
+			return n
+		}
+	case 'f', 'F':
+		if f, err := strconv.ParseFloat(body, 32); err == nil {
+			return float32(f)
+		}
+	case 'd', 'D':
+		if f, err := strconv.ParseFloat(body, 64); err == nil {
+			return f
+		}
+	}
+
+	if n, err := strconv.ParseInt(word, 10, 32); err == nil {
+		return int32(n)
+	}
+	if strings.ContainsAny(word, ".eE") {
+		if f, err := strconv.ParseFloat(word, 64); err == nil {
+			return f
+		}
+	}
+	return word
+}
@@ -0,0 +1,253 @@
+package snbt
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseScalars(t *testing.T) {
+	tests := []struct {
+		in   string
+		want interface{}
+	}{
+		{"1b", int8(1)},
+		{"-128B", int8(-128)},
+		{"true", int8(1)},
+		{"false", int8(0)},
+		{"300s", int16(300)},
+		{"-2S", int16(-2)},
+		{"42", int32(42)},
+		{"-2147483648", int32(math.MinInt32)},
+		{"2147483648L", int64(2147483648)},
+		{"-7l", int64(-7)},
+		{"1.5f", float32(1.5)},
+		{"-0.25F", float32(-0.25)},
+		{"1.5d", 1.5},
+		{"2D", 2.0},
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{"2.5E-1", 0.25},
+		{" 7 ", int32(7)},
+
+		// Out of range for the suffix, or not numbers, are strings
+		{"300b", "300b"},
+		{"2147483648", "2147483648"},
+		{"abc", "abc"},
+		{"minecraft.stone", "minecraft.stone"},
+		{"1.2.3", "1.2.3"},
+		{"x1b", "x1b"},
+		{"TRUE", "TRUE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStrings(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`"hello world"`, "hello world"},
+		{`'hello world'`, "hello world"},
+		{`""`, ""},
+		{`"say \"hi\""`, `say "hi"`},
+		{`'it\'s'`, "it's"},
+		{`'say "hi"'`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"12"`, "12"},
+		{`"true"`, "true"},
+		{`"§cred"`, "§cred"},
+		{"\"two\nlines\"", "two\nlines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArrays(t *testing.T) {
+	tests := []struct {
+		in   string
+		want interface{}
+	}{
+		{"[B;1b,2b,-3b]", []int8{1, 2, -3}},
+		{"[B; 1B, 2]", []int8{1, 2}},
+		{"[B;]", []int8{}},
+		{"[I;1,2,3]", []int32{1, 2, 3}},
+		{"[I; -2147483648, 2147483647 ]", []int32{math.MinInt32, math.MaxInt32}},
+		{"[I;]", []int32{}},
+		{"[L;1L,-2l,9223372036854775807L]", []int64{1, -2, math.MaxInt64}},
+		{"[L;]", []int64{}},
+		// UUIDs as data get prints them
+		{"[I;-1,0,1,2]", []int32{-1, 0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCompound(t *testing.T) {
+	in := `{Health: 20.0f, "custom name": 'Bob', id: "minecraft:zombie", Pos: [1.5d, 64.0d, -3.25d],
+		Tags: [], Motion: [0.0d,0.0d,0.0d], UUID: [I; 1, 2, 3, 4], Inventory: [{Slot: 0b, Count: 1b, id: "minecraft:stone"}],
+		empty: {}, OnGround: 1b}`
+	want := map[string]interface{}{
+		"Health":      float32(20),
+		"custom name": "Bob",
+		"id":          "minecraft:zombie",
+		"Pos":         []interface{}{1.5, 64.0, -3.25},
+		"Tags":        []interface{}{},
+		"Motion":      []interface{}{0.0, 0.0, 0.0},
+		"UUID":        []int32{1, 2, 3, 4},
+		"Inventory": []interface{}{
+			map[string]interface{}{"Slot": int8(0), "Count": int8(1), "id": "minecraft:stone"},
+		},
+		"empty":    map[string]interface{}{},
+		"OnGround": int8(1),
+	}
+	got, err := Parse(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"   ",
+		"{",
+		"{a:1",
+		"{a 1}",
+		"{:1}",
+		"{a:1,}",
+		"[1,2",
+		"[1 2]",
+		"[B;1b,x]",
+		"[I;1 2]",
+		"[L;1L",
+		`"unterminated`,
+		`'escape at end\`,
+		"1 2",
+		"{a:1}}",
+		"@",
+	} {
+		t.Run(in, func(t *testing.T) {
+			v, err := Parse(in)
+			if !errors.Is(err, ErrorSyntax) {
+				t.Fatalf("got %#v, %v, want ErrorSyntax", v, err)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want string
+	}{
+		{"byte", int8(-1), "-1b"},
+		{"bool", true, "1b"},
+		{"short", int16(5), "5s"},
+		{"int", int32(5), "5"},
+		{"go int", 5, "5"},
+		{"long", int64(5), "5L"},
+		{"float", float32(0.5), "0.5f"},
+		{"double", 0.5, "0.5d"},
+		{"whole double", 64.0, "64d"},
+		{"plain string", "stone", "stone"},
+		{"empty string", "", `""`},
+		{"spaces", "a b", `"a b"`},
+		{"numeric string", "12", `"12"`},
+		{"boolean string", "false", `"false"`},
+		{"suffixed string", "3b", `"3b"`},
+		{"quotes", `say "hi" \o/`, `"say \"hi\" \\o/"`},
+		{"namespaced", "minecraft:stone", `"minecraft:stone"`},
+		{"byte array", []int8{1, -2}, "[B;1b,-2b]"},
+		{"int array", []int32{1, -2}, "[I;1,-2]"},
+		{"long array", []int64{1, -2}, "[L;1L,-2L]"},
+		{"empty array", []int32{}, "[I;]"},
+		{"list", []interface{}{int32(1), "a b"}, `[1,"a b"]`},
+		{"compound", map[string]interface{}{"b": int8(1), "a": "x", "c d": int16(2), "1": int32(3)}, `{"1":3,a:x,b:1b,"c d":2s}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Format(map[string]interface{}{"a": struct{}{}}); !errors.Is(err, ErrorSyntax) {
+		t.Errorf("unsupported type: %v", err)
+	}
+}
+
+// Whatever Format writes, Parse reads back as the same value
+func TestRoundTrip(t *testing.T) {
+	values := []interface{}{
+		int8(-128), int16(math.MaxInt16), int32(math.MinInt32), int64(math.MaxInt64),
+		float32(0.1), float32(-3.4e38), 0.1, 1e21, -2.5e-10, 64.0,
+		"", "stone", "a b", "12", "1.5", "true", "1b", "it's", `"quoted"`, `back\slash`, "§cred", "two\nlines", "ünïcode",
+		[]int8{}, []int8{-1, 0, 127},
+		[]int32{}, []int32{math.MinInt32, 0, math.MaxInt32},
+		[]int64{}, []int64{math.MinInt64, math.MaxInt64},
+		[]interface{}{},
+		[]interface{}{int32(1), int32(2)},
+		[]interface{}{[]interface{}{"nested"}, map[string]interface{}{}},
+		map[string]interface{}{
+			"id":          "minecraft:player",
+			"Pos":         []interface{}{1.5, 64.0, -3.25},
+			"UUID":        []int32{1, 2, 3, 4},
+			"custom name": "Bob",
+			"":            int8(0),
+			"1":           int16(1),
+			"Inventory":   []interface{}{map[string]interface{}{"Slot": int8(0), "tag": map[string]interface{}{"Damage": int32(3)}}},
+		},
+	}
+	for _, v := range values {
+		s, err := Format(v)
+		if err != nil {
+			t.Fatalf("%#v: %v", v, err)
+		}
+		got, err := Parse(s)
+		if err != nil {
+			t.Fatalf("%#v formatted as %s: %v", v, s, err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("%#v formatted as %s, parsed back as %#v", v, s, got)
+		}
+	}
+}
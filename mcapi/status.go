@@ -0,0 +1,82 @@
+package mcapi
+
+import (
+	"context"
+	"sync"
+)
+
+// Overview of a server. Parts that could not be read are left zero, with
+// the reason in Errors keyed by "version", "players", "performance",
+// "time" or "difficulty".
+type ServerStatus struct {
+	Version     ServerVersion
+	Players     PlayerList
+	Performance *Performance // nil when the server has no tps command
+	DayTime     int64
+	GameTime    int64
+	Difficulty  Difficulty
+	Errors      map[string]error
+}
+
+// Gather the server's version, players, performance, time and difficulty,
+// sending the queries at once. err is only set when the context ended or
+// nothing could be read.
+func (c *Client) ServerStatus(ctx context.Context) (*ServerStatus, error) {
+	status := &ServerStatus{Errors: map[string]error{}}
+
+	// Each query sets its own fields, and records its error under the lock
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	query := func(part string, run func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := run()
+			lock.Lock()
+			defer lock.Unlock()
+			status.record(part, err)
+		}()
+	}
+
+	query("version", func() (err error) {
+		status.Version, err = c.ServerVersion(ctx)
+		return err
+	})
+	query("players", func() (err error) {
+		status.Players, err = c.playerList(ctx)
+		return err
+	})
+	query("performance", func() (err error) {
+		status.Performance, err = c.Performance(ctx)
+		if err == ErrorUnsupported {
+			status.Performance, err = nil, nil
+		}
+		return err
+	})
+	query("time", func() (err error) {
+		status.DayTime, err = c.DayTime(ctx)
+		if err == nil {
+			status.GameTime, err = c.GameTime(ctx)
+		}
+		return err
+	})
+	query("difficulty", func() (err error) {
+		status.Difficulty, err = c.GetDifficulty(ctx)
+		return err
+	})
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return status, err
+	}
+	if len(status.Errors) == 5 {
+		return status, status.Errors["version"]
+	}
+	return status, nil
+}
+
+func (s *ServerStatus) record(part string, err error) {
+	if err != nil {
+		s.Errors[part] = err
+	}
+}
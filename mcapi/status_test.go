@@ -0,0 +1,46 @@
+package mcapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/StarForger/neb-mc-rcon/rcontest"
+)
+
+func TestServerStatus(t *testing.T) {
+	server := rcontest.NewServer(t, rcontest.Options{
+		Responses: map[string]string{
+			"version":             "This server is running Paper version git-Paper-196 (MC: 1.20.4) (Implementing API version 1.20.4-R0.1-SNAPSHOT)",
+			"list":                "There are 1 of a max of 20 players online: Steve",
+			"tps":                 "§6TPS from last 1m, 5m, 15m: §a20.0, §a19.5, §a19.9",
+			"mspt":                "§6Server tick times §e(§7avg§e/§7min§e/§7max§e)§6 from last 5s§7,§6 10s§7,§6 1m§e:\n§6◴ §a3.1§7/§a1.2§7/§a9.8§e, §a3.0§7/§a1.1§7/§a9.9§e, §a3.2§7/§a1.0§7/§a12.4",
+			"time query daytime":  "The time is 1000",
+			"time query gametime": "The time is 24000",
+			"difficulty":          "The difficulty is Normal",
+		},
+	})
+	client := New(server.Dial())
+
+	status, err := client.ServerStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Errors) != 0 {
+		t.Fatalf("errors: %v", status.Errors)
+	}
+	if status.Players.Online != 1 || len(status.Players.Names) != 1 {
+		t.Errorf("players: %+v", status.Players)
+	}
+	if status.DayTime != 1000 || status.GameTime != 24000 {
+		t.Errorf("time: %d %d", status.DayTime, status.GameTime)
+	}
+	if status.Difficulty != Normal {
+		t.Errorf("difficulty: %v", status.Difficulty)
+	}
+	if status.Performance == nil {
+		t.Error("no performance")
+	}
+	if flavor, _ := client.detected(); flavor != FlavorPaper {
+		t.Errorf("detected %v", flavor)
+	}
+}
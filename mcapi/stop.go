@@ -0,0 +1,26 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"io"
+	"regexp"
+)
+
+// "Stopping the server", "Stopping server" before 1.13
+var stopping = regexp.MustCompile(`^Stopping (?:the )?server`)
+
+// Shut the server down, saving the worlds first. The server may close the
+// connection before answering, which counts as stopped.
+func (c *Client) Stop(ctx context.Context) error {
+	response, err := c.execute(ctx, "stop")
+	if err == io.EOF || errors.Is(err, conn.ErrorClosed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, stopping)
+	return err
+}
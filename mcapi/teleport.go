@@ -0,0 +1,51 @@
+package mcapi
+
+import (
+	"context"
+	"regexp"
+)
+
+var (
+	// "Teleported Steve to 10.500000, 64.000000, 10.500000",
+	// "Teleported 3 entities to Alex" and before 1.13 "Teleported Steve to Alex"
+	teleportedTo = regexp.MustCompile(`^Teleported (.+?) to (.+)$`)
+)
+
+// Teleport target (player or selector) to pos, returning the destination
+// reported by the server
+func (c *Client) Teleport(ctx context.Context, target string, pos Position) (Position, error) {
+	if err := checkTarget(target); err != nil {
+		return Position{}, err
+	}
+
+	response, err := c.execute(ctx, "tp "+target+" "+pos.String())
+	if err != nil {
+		return Position{}, err
+	}
+	m, err := matchResponse(response, teleportedTo)
+	if err != nil {
+		return Position{}, err
+	}
+	dest, err := ParsePosition(m[2])
+	if err != nil {
+		return Position{}, unexpected(response)
+	}
+	return dest, nil
+}
+
+// Teleport target to another player's position
+func (c *Client) TeleportToPlayer(ctx context.Context, target string, destination string) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	if err := checkTarget(destination); err != nil {
+		return err
+	}
+
+	response, err := c.execute(ctx, "tp "+target+" "+destination)
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, teleportedTo)
+	return err
+}
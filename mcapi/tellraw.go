@@ -0,0 +1,19 @@
+package mcapi
+
+import (
+	"context"
+	"github.com/StarForger/neb-mc-rcon/mcapi/text"
+)
+
+// Send a formatted chat message to a player or selector
+func (c *Client) Tellraw(ctx context.Context, target string, message text.Component) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	response, err := c.execute(ctx, "tellraw "+target+" "+message.String())
+	if err != nil {
+		return err
+	}
+	// Vanilla answers with nothing, or why no player was found
+	return CommandFailure(response)
+}
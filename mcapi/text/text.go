@@ -0,0 +1,244 @@
+// Package text builds Minecraft JSON text components, as used by tellraw,
+// title and other commands that display formatted text.
+//
+//	msg := text.New("Server restarting ").Color(text.Gold).
+//		Append(text.New("in 5 minutes").Color(text.Red).Bold())
+//
+//	link := text.New("the wiki").Underlined().
+//		OpenURL("https://minecraft.wiki").Hover(text.New("Open in a browser"))
+package text
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type Color string
+
+const (
+	Black       Color = "black"
+	DarkBlue    Color = "dark_blue"
+	DarkGreen   Color = "dark_green"
+	DarkAqua    Color = "dark_aqua"
+	DarkRed     Color = "dark_red"
+	DarkPurple  Color = "dark_purple"
+	Gold        Color = "gold"
+	Gray        Color = "gray"
+	DarkGray    Color = "dark_gray"
+	Blue        Color = "blue"
+	Green       Color = "green"
+	Aqua        Color = "aqua"
+	Red         Color = "red"
+	LightPurple Color = "light_purple"
+	Yellow      Color = "yellow"
+	White       Color = "white"
+)
+
+// What clicking a component does
+type ClickAction string
+
+const (
+	OpenURL         ClickAction = "open_url"
+	RunCommand      ClickAction = "run_command"
+	SuggestCommand  ClickAction = "suggest_command"
+	CopyToClipboard ClickAction = "copy_to_clipboard" // 1.15+
+	ChangePage      ClickAction = "change_page"       // books only
+)
+
+type ClickEvent struct {
+	Action ClickAction `json:"action"`
+	Value  string      `json:"value"`
+}
+
+// Component is immutable, every method returns a modified copy
+type Component struct {
+	text          string
+	color         Color
+	bold          bool
+	italic        bool
+	underlined    bool
+	strikethrough bool
+	obfuscated    bool
+	click         *ClickEvent
+	hover         *Component
+	extra         []Component
+}
+
+// Plain text component
+func New(s string) Component {
+	return Component{text: s}
+}
+
+// Color name or "#rrggbb" (1.16+)
+func (c Component) Color(color Color) Component {
+	c.color = color
+	return c
+}
+
+func (c Component) Bold() Component {
+	c.bold = true
+	return c
+}
+
+func (c Component) Italic() Component {
+	c.italic = true
+	return c
+}
+
+func (c Component) Underlined() Component {
+	c.underlined = true
+	return c
+}
+
+func (c Component) Strikethrough() Component {
+	c.strikethrough = true
+	return c
+}
+
+func (c Component) Obfuscated() Component {
+	c.obfuscated = true
+	return c
+}
+
+// Do action with value when clicked
+func (c Component) OnClick(action ClickAction, value string) Component {
+	c.click = &ClickEvent{Action: action, Value: value}
+	return c
+}
+
+// Open url in the browser when clicked, http and https only
+func (c Component) OpenURL(url string) Component {
+	return c.OnClick(OpenURL, url)
+}
+
+// Run a command as the player when clicked, with the leading slash
+func (c Component) RunCommand(cmd string) Component {
+	return c.OnClick(RunCommand, cmd)
+}
+
+// Put cmd in the player's chat box when clicked
+func (c Component) SuggestCommand(cmd string) Component {
+	return c.OnClick(SuggestCommand, cmd)
+}
+
+// Copy s to the clipboard when clicked (1.15+)
+func (c Component) CopyToClipboard(s string) Component {
+	return c.OnClick(CopyToClipboard, s)
+}
+
+// Show tooltip when the mouse is over the text (1.16+)
+func (c Component) Hover(tooltip Component) Component {
+	c.hover = &tooltip
+	return c
+}
+
+// Add children, which inherit this component's formatting
+func (c Component) Append(children ...Component) Component {
+	extra := make([]Component, 0, len(c.extra)+len(children))
+	c.extra = append(append(extra, c.extra...), children...)
+	return c
+}
+
+type component struct {
+	Text          string      `json:"text"`
+	Color         Color       `json:"color,omitempty"`
+	Bold          bool        `json:"bold,omitempty"`
+	Italic        bool        `json:"italic,omitempty"`
+	Underlined    bool        `json:"underlined,omitempty"`
+	Strikethrough bool        `json:"strikethrough,omitempty"`
+	Obfuscated    bool        `json:"obfuscated,omitempty"`
+	ClickEvent    *ClickEvent `json:"clickEvent,omitempty"`
+	HoverEvent    *hoverEvent `json:"hoverEvent,omitempty"`
+	Extra         []Component `json:"extra,omitempty"`
+}
+
+// Only show_text is built, other actions are dropped when read
+type hoverEvent struct {
+	Action   string          `json:"action"`
+	Contents json.RawMessage `json:"contents,omitempty"`
+	Value    json.RawMessage `json:"value,omitempty"` // before 1.16
+}
+
+func (c Component) MarshalJSON() ([]byte, error) {
+	v := component{
+		Text:          c.text,
+		Color:         c.color,
+		Bold:          c.bold,
+		Italic:        c.italic,
+		Underlined:    c.underlined,
+		Strikethrough: c.strikethrough,
+		Obfuscated:    c.obfuscated,
+		ClickEvent:    c.click,
+		Extra:         c.extra,
+	}
+	if c.hover != nil {
+		contents, err := json.Marshal(*c.hover)
+		if err != nil {
+			return nil, err
+		}
+		v.HoverEvent = &hoverEvent{Action: "show_text", Contents: contents}
+	}
+	return json.Marshal(v)
+}
+
+func (c *Component) UnmarshalJSON(data []byte) error {
+	// A bare string is a plain text component
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*c = New(s)
+		return nil
+	}
+
+	var v component
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = Component{
+		text:          v.Text,
+		color:         v.Color,
+		bold:          v.Bold,
+		italic:        v.Italic,
+		underlined:    v.Underlined,
+		strikethrough: v.Strikethrough,
+		obfuscated:    v.Obfuscated,
+		click:         v.ClickEvent,
+		extra:         v.Extra,
+	}
+	if h := v.HoverEvent; h != nil && h.Action == "show_text" {
+		contents := h.Contents
+		if contents == nil {
+			contents = h.Value
+		}
+		if contents != nil {
+			var tooltip Component
+			if err := json.Unmarshal(contents, &tooltip); err != nil {
+				return err
+			}
+			c.hover = &tooltip
+		}
+	}
+	return nil
+}
+
+// Text content without formatting, including children
+func (c Component) PlainText() string {
+	var b strings.Builder
+	c.plainText(&b)
+	return b.String()
+}
+
+func (c Component) plainText(b *strings.Builder) {
+	b.WriteString(c.text)
+	for _, child := range c.extra {
+		child.plainText(b)
+	}
+}
+
+// JSON form, as used in commands
+func (c Component) String() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return `{"text":""}`
+	}
+	return string(data)
+}
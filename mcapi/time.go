@@ -0,0 +1,36 @@
+package mcapi
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+)
+
+// "The time is 1000", before 1.13 "Time is 1000"
+var timeValue = regexp.MustCompile(`^(?:The )?[Tt]ime is (-?\d+)`)
+
+// Ticks since the start of the current day, 0 to 23999 on most servers
+func (c *Client) DayTime(ctx context.Context) (int64, error) {
+	return c.queryTime(ctx, "daytime")
+}
+
+// Ticks the world has run for
+func (c *Client) GameTime(ctx context.Context) (int64, error) {
+	return c.queryTime(ctx, "gametime")
+}
+
+func (c *Client) queryTime(ctx context.Context, query string) (int64, error) {
+	response, err := c.execute(ctx, "time query "+query)
+	if err != nil {
+		return 0, err
+	}
+	m, err := matchResponse(response, timeValue)
+	if err != nil {
+		return 0, err
+	}
+	ticks, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, unexpected(response)
+	}
+	return ticks, nil
+}
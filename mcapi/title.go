@@ -0,0 +1,97 @@
+package mcapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/mcapi/text"
+	"regexp"
+	"time"
+)
+
+var (
+	// "Showing new title for Steve", "Showing new actionbar title for 3 players"
+	titleShown   = regexp.MustCompile(`^Showing new (?:title|subtitle|actionbar title) for (.+)$`)
+	titleTimes   = regexp.MustCompile(`^Changing title times for (.+)$`)
+	titleCleared = regexp.MustCompile(`^(?:Clearing|Resetting) titles? (?:options )?for (.+)$`)
+)
+
+const tick = 50 * time.Millisecond
+
+// Title fade in, stay and fade out durations, rounded to game ticks
+type TitleTimes struct {
+	FadeIn  time.Duration
+	Stay    time.Duration
+	FadeOut time.Duration
+}
+
+// On screen titles for a player or selector
+type Title struct {
+	client *Client
+	target string
+}
+
+func (c *Client) Title(target string) *Title {
+	return &Title{client: c, target: target}
+}
+
+// Show title with an optional subtitle (nil to omit) and display
+// times (nil keeps the current times)
+func (t *Title) Show(ctx context.Context, title text.Component, subtitle *text.Component, times *TitleTimes) error {
+	if err := checkTarget(t.target); err != nil {
+		return err
+	}
+
+	if times != nil {
+		cmd := fmt.Sprintf("title %s times %d %d %d", t.target, ticks(times.FadeIn), ticks(times.Stay), ticks(times.FadeOut))
+		if err := t.run(ctx, cmd, titleTimes); err != nil {
+			return err
+		}
+	}
+
+	// The subtitle is displayed with the next title
+	if subtitle != nil {
+		if err := t.run(ctx, "title "+t.target+" subtitle "+subtitle.String(), titleShown); err != nil {
+			return err
+		}
+	}
+
+	return t.run(ctx, "title "+t.target+" title "+title.String(), titleShown)
+}
+
+// Remove the title currently on screen
+func (t *Title) Clear(ctx context.Context) error {
+	if err := checkTarget(t.target); err != nil {
+		return err
+	}
+	return t.run(ctx, "title "+t.target+" clear", titleCleared)
+}
+
+// Clear the title and restore default times
+func (t *Title) Reset(ctx context.Context) error {
+	if err := checkTarget(t.target); err != nil {
+		return err
+	}
+	return t.run(ctx, "title "+t.target+" reset", titleCleared)
+}
+
+// Show a message above the hotbar
+func (c *Client) ActionBar(ctx context.Context, target string, message text.Component) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	t := &Title{client: c, target: target}
+	return t.run(ctx, "title "+target+" actionbar "+message.String(), titleShown)
+}
+
+func (t *Title) run(ctx context.Context, cmd string, success *regexp.Regexp) error {
+	response, err := t.client.execute(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, success)
+	return err
+}
+
+func ticks(d time.Duration) int {
+	return int((d + tick/2) / tick)
+}
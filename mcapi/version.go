@@ -0,0 +1,143 @@
+package mcapi
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+const BrandUnknown = "unknown"
+
+var (
+	// "This server is running Paper version git-Paper-196 (MC: 1.20.1) (Implementing API version 1.20.1-R0.1-SNAPSHOT)"
+	// "This server is running Paper version 1.20.4-496-master@7ac24a1 (2024-05-03T14:00:35Z) (Implementing API version 1.20.4-R0.1-SNAPSHOT)"
+	bukkitVersion = regexp.MustCompile(`running (\S+) version (\S+)(?: \(MC: ([^)]+)\))?`)
+	bukkitAPI     = regexp.MustCompile(`Implementing API version ([^)\s]+)`)
+	// Paper builds: "git-Paper-196", "1.20.4-496-master@7ac24a1"
+	paperBuild = regexp.MustCompile(`^(?:git-\w+-(\d+)|([\d.]+)-(\d+)-)`)
+	// Vanilla 1.21.6+: "Server version info:" followed by "id = 1.21.6" etc.
+	vanillaVersion = regexp.MustCompile(`(?m)^\s*id = (\S+)`)
+	vanillaName    = regexp.MustCompile(`(?m)^\s*name = (.+)$`)
+	vanillaData    = regexp.MustCompile(`(?m)^\s*data = (\d+)`)
+	// Fabric has no version command of its own, mods that add one print
+	// e.g. "Fabric Loader 0.15.11 (MC: 1.20.4)"
+	fabricVersion = regexp.MustCompile(`(?i)\bfabric\b(?: loader)?(?: version)? v?([\d.]+)?`)
+	mcVersion     = regexp.MustCompile(`MC:? ?([\d.]+)`)
+)
+
+// Server software and version. Fields that could not be determined are
+// empty, and Brand is BrandUnknown when the output was not recognised.
+type ServerVersion struct {
+	Raw
+	Brand            string // "paper", "purpur", "spigot", "vanilla", "fabric"
+	MinecraftVersion string
+	Build            string
+	APIVersion       string // Bukkit API version
+}
+
+// Identify the server from the version command, falling back to
+// BrandUnknown rather than failing when the output is not recognised
+func (c *Client) ServerVersion(ctx context.Context) (ServerVersion, error) {
+	response, err := c.execute(ctx, "version")
+	if err != nil {
+		return ServerVersion{Brand: BrandUnknown}, err
+	}
+	v, err := c.parseVersion(ctx, "version", response)
+	if err != nil {
+		return v, err
+	}
+
+	// Bukkit servers also answer "about"
+	if !v.Parsed {
+		if response, err := c.execute(ctx, "about"); err == nil {
+			if about, err := c.parseVersion(ctx, "about", response); err == nil && about.Parsed {
+				v = about
+			}
+		}
+	}
+	return v, nil
+}
+
+func (c *Client) parseVersion(ctx context.Context, cmd string, response string) (ServerVersion, error) {
+	v, err := c.parse(ctx, cmd, response)
+	if err != nil {
+		return ServerVersion{Brand: BrandUnknown}, err
+	}
+	version, ok := v.(ServerVersion)
+	if !ok {
+		return ServerVersion{Brand: BrandUnknown}, wrongType(cmd, v)
+	}
+	return version, nil
+}
+
+// Parse version or about output
+func ParseServerVersion(response string) ServerVersion {
+	response = StripFormatting(response)
+	v := parseServerVersion(response)
+	v.Raw = Raw{response, v.Brand != BrandUnknown}
+	return v
+}
+
+func parseServerVersion(response string) ServerVersion {
+
+	if m := bukkitVersion.FindStringSubmatch(response); m != nil {
+		v := ServerVersion{
+			Brand:            strings.ToLower(m[1]),
+			Build:            m[2],
+			MinecraftVersion: m[3],
+		}
+		if api := bukkitAPI.FindStringSubmatch(response); api != nil {
+			v.APIVersion = api[1]
+		}
+		if v.Brand == "craftbukkit" && strings.Contains(v.Build, "Spigot") {
+			v.Brand = "spigot"
+		}
+		if b := paperBuild.FindStringSubmatch(v.Build); b != nil {
+			v.Build = firstNonEmpty(b[1], b[3])
+			if v.MinecraftVersion == "" {
+				v.MinecraftVersion = b[2]
+			}
+		}
+		if v.MinecraftVersion == "" && v.APIVersion != "" {
+			v.MinecraftVersion = strings.SplitN(v.APIVersion, "-", 2)[0]
+		}
+		return v
+	}
+
+	if m := fabricVersion.FindStringSubmatch(response); m != nil {
+		v := ServerVersion{Brand: "fabric", Build: m[1]}
+		if mc := mcVersion.FindStringSubmatch(response); mc != nil {
+			v.MinecraftVersion = mc[1]
+		}
+		return v
+	}
+
+	if m := vanillaVersion.FindStringSubmatch(response); m != nil {
+		v := ServerVersion{Brand: "vanilla", MinecraftVersion: m[1]}
+		if n := vanillaName.FindStringSubmatch(response); n != nil {
+			v.MinecraftVersion = strings.TrimSpace(n[1])
+		}
+		if d := vanillaData.FindStringSubmatch(response); d != nil {
+			v.Build = d[1]
+		}
+		return v
+	}
+
+	return ServerVersion{Brand: BrandUnknown}
+}
+
+// Flavor for a brand, FlavorUnknown when the brand says nothing about the
+// commands available
+func (v ServerVersion) Flavor() Flavor {
+	switch v.Brand {
+	case "paper", "purpur", "pufferfish", "folia", "spigot", "craftbukkit":
+		return FlavorPaper
+	case "vanilla":
+		return FlavorVanilla
+	case "fabric":
+		return FlavorFabric
+	case "forge", "neoforge":
+		return FlavorForge
+	}
+	return FlavorUnknown
+}
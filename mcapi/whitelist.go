@@ -0,0 +1,228 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/mojang"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrorAlreadyWhitelisted  = errors.New("mcapi: player is already whitelisted")
+	ErrorNotWhitelisted      = errors.New("mcapi: player is not whitelisted")
+	ErrorWhitelistAlreadyOn  = errors.New("mcapi: whitelist is already turned on")
+	ErrorWhitelistAlreadyOff = errors.New("mcapi: whitelist is already turned off")
+)
+
+var (
+	whitelistAdded    = regexp.MustCompile(`^Added (\S+) to the whitelist`)
+	whitelistRemoved  = regexp.MustCompile(`^Removed (\S+) from the whitelist`)
+	whitelistOn       = regexp.MustCompile(`^(?:Whitelist is now turned on|Turned on the whitelist)`)
+	whitelistOff      = regexp.MustCompile(`^(?:Whitelist is now turned off|Turned off the whitelist)`)
+	whitelistReloaded = regexp.MustCompile(`^Reloaded the whitelist`)
+	// "There are 2 whitelisted players: a, b", "There are 2 whitelisted player(s): a, b"
+	// and before 1.13 "There are 2 (out of 5 seen) whitelisted players:\na, b"
+	whitelistList  = regexp.MustCompile(`(?s)^There (?:are|is) (\d+)(?: \(out of \d+ seen\))? whitelisted player(?:s|\(s\))?:\s*(.*)$`)
+	whitelistEmpty = regexp.MustCompile(`^There are no whitelisted players`)
+
+	whitelistFailures = []failure{
+		{regexp.MustCompile(`(?i)already whitelisted`), ErrorAlreadyWhitelisted},
+		{regexp.MustCompile(`(?i)not whitelisted`), ErrorNotWhitelisted},
+		{regexp.MustCompile(`(?i)Whitelist is already turned on`), ErrorWhitelistAlreadyOn},
+		{regexp.MustCompile(`(?i)Whitelist is already turned off`), ErrorWhitelistAlreadyOff},
+	}
+)
+
+type Whitelist struct {
+	client *Client
+}
+
+func (c *Client) Whitelist() *Whitelist {
+	return &Whitelist{client: c}
+}
+
+func (w *Whitelist) Add(ctx context.Context, name string) error {
+	if err := checkWord(name); err != nil {
+		return err
+	}
+	return w.run(ctx, "whitelist add "+name, whitelistAdded)
+}
+
+func (w *Whitelist) Remove(ctx context.Context, name string) error {
+	if err := checkWord(name); err != nil {
+		return err
+	}
+	return w.run(ctx, "whitelist remove "+name, whitelistRemoved)
+}
+
+func (w *Whitelist) On(ctx context.Context) error {
+	return w.run(ctx, "whitelist on", whitelistOn)
+}
+
+func (w *Whitelist) Off(ctx context.Context) error {
+	return w.run(ctx, "whitelist off", whitelistOff)
+}
+
+func (w *Whitelist) Reload(ctx context.Context) error {
+	return w.run(ctx, "whitelist reload", whitelistReloaded)
+}
+
+// Names of the whitelisted players
+func (w *Whitelist) List(ctx context.Context) ([]string, error) {
+	response, err := w.client.execute(ctx, "whitelist list")
+	if err != nil {
+		return nil, err
+	}
+	if whitelistEmpty.MatchString(strings.TrimSpace(response)) {
+		return []string{}, nil
+	}
+
+	m, err := matchResponse(response, whitelistList, whitelistFailures...)
+	if err != nil {
+		return nil, err
+	}
+	return splitNames(m[2]), nil
+}
+
+func (w *Whitelist) run(ctx context.Context, cmd string, success *regexp.Regexp) error {
+	response, err := w.client.execute(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	_, err = matchResponse(response, success, whitelistFailures...)
+	return err
+}
+
+// Split a comma (or newline) separated list of names
+func splitNames(s string) []string {
+	names := []string{}
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Outcome of a whitelist sync
+type WhitelistSync struct {
+	Added   []string
+	Removed []string
+	Failed  map[string]error // by player name
+}
+
+// Names to add and remove to turn current into desired, ignoring case
+func DiffWhitelist(current []string, desired []string) (add []string, remove []string) {
+	return diffNames(current, desired)
+}
+
+func diffNames(current []string, desired []string) (add []string, remove []string) {
+	have := map[string]bool{}
+	for _, name := range current {
+		have[strings.ToLower(name)] = true
+	}
+	want := map[string]bool{}
+	for _, name := range desired {
+		key := strings.ToLower(name)
+		if !want[key] && !have[key] {
+			add = append(add, name)
+		}
+		want[key] = true
+	}
+	for _, name := range current {
+		if !want[strings.ToLower(name)] {
+			remove = append(remove, name)
+		}
+	}
+	return add, remove
+}
+
+// Whitelisted players with their UUIDs, as far as the client's resolver
+// finds them
+func (w *Whitelist) Resolved(ctx context.Context) ([]mojang.Profile, error) {
+	names, err := w.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return w.client.Profiles(ctx, names), nil
+}
+
+// Changes Sync would make, without applying them. Entries of desired may be
+// names or UUIDs, which fail the plan if they can't be resolved.
+func (w *Whitelist) Plan(ctx context.Context, desired []string) (add []string, remove []string, err error) {
+	names, failed := w.client.resolveNames(ctx, desired)
+	if err := firstFailure(failed); err != nil {
+		return nil, nil, err
+	}
+	current, err := w.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	add, remove = DiffWhitelist(current, names)
+	return add, remove, nil
+}
+
+// Make the server's whitelist match desired, names or UUIDs, sending only
+// the necessary add and remove commands. Per player failures, UUIDs that
+// can't be resolved among them, are collected in the result rather than
+// stopping the sync; err is only set when the whitelist could not be read.
+// While any UUID is unresolved nobody is removed, as they may be that
+// player.
+func (w *Whitelist) Sync(ctx context.Context, desired []string) (*WhitelistSync, error) {
+	names, failed := w.client.resolveNames(ctx, desired)
+	current, err := w.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	add, remove := DiffWhitelist(current, names)
+	if len(failed) > 0 {
+		remove = nil
+	}
+
+	result := &WhitelistSync{Failed: failed}
+	w.addAll(ctx, add, result)
+	w.removeAll(ctx, remove, result)
+	return result, nil
+}
+
+// Whitelist every name or UUID, those already whitelisted counting as added
+func (w *Whitelist) AddAll(ctx context.Context, names []string) *WhitelistSync {
+	names, failed := w.client.resolveNames(ctx, names)
+	result := &WhitelistSync{Failed: failed}
+	w.addAll(ctx, names, result)
+	return result
+}
+
+// Remove every name or UUID from the whitelist, those not on it counting as
+// removed
+func (w *Whitelist) RemoveAll(ctx context.Context, names []string) *WhitelistSync {
+	names, failed := w.client.resolveNames(ctx, names)
+	result := &WhitelistSync{Failed: failed}
+	w.removeAll(ctx, names, result)
+	return result
+}
+
+func (w *Whitelist) addAll(ctx context.Context, names []string, result *WhitelistSync) {
+	for _, name := range names {
+		err := w.Add(ctx, name)
+		switch {
+		case err == nil, errors.Is(err, ErrorAlreadyWhitelisted):
+			result.Added = append(result.Added, name)
+		default:
+			result.Failed[name] = err
+		}
+	}
+}
+
+func (w *Whitelist) removeAll(ctx context.Context, names []string, result *WhitelistSync) {
+	for _, name := range names {
+		err := w.Remove(ctx, name)
+		switch {
+		case err == nil, errors.Is(err, ErrorNotWhitelisted):
+			result.Removed = append(result.Removed, name)
+		default:
+			result.Failed[name] = err
+		}
+	}
+}
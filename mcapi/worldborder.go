@@ -0,0 +1,139 @@
+package mcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrorBorderRange = errors.New("mcapi: world border out of range")
+)
+
+const borderNumber = `(-?[\d,]+(?:\.\d+)?)`
+
+var (
+	// "The world border is currently 59999968 block(s) wide", before 1.13
+	// "Border is currently 60000000 blocks wide"
+	borderSize = regexp.MustCompile(`(?i)^(?:The world )?border is currently ` + borderNumber + ` blocks? ?(?:\(s\) )?wide`)
+	// "Set the world border to 100 block(s) wide", "Shrinking the world
+	// border to 100 block(s) wide over 60 second(s)"
+	borderSet     = regexp.MustCompile(`(?i)^(?:Set|Growing|Shrinking) the world border to ` + borderNumber + ` block`)
+	borderCenter  = regexp.MustCompile(`(?i)^Set the center of the world border to ` + borderNumber + `, ` + borderNumber)
+	borderDamage  = regexp.MustCompile(`(?i)^Set the world border damage (?:to|buffer to) ` + borderNumber)
+	borderWarning = regexp.MustCompile(`(?i)^Set the world border warning (?:distance|time) to ` + borderNumber)
+	// "Nothing changed. The world border is already that size"
+	borderUnchanged = regexp.MustCompile(`^Nothing changed`)
+	borderFailures  = []failure{
+		{regexp.MustCompile(`(?i)cannot be (?:smaller|bigger|further)`), ErrorBorderRange},
+	}
+)
+
+// World border of the overworld, or of the executing dimension
+type WorldBorder struct {
+	client *Client
+}
+
+func (c *Client) WorldBorder() *WorldBorder {
+	return &WorldBorder{client: c}
+}
+
+// Current diameter in blocks
+func (w *WorldBorder) Get(ctx context.Context) (float64, error) {
+	response, err := w.client.execute(ctx, "worldborder get")
+	if err != nil {
+		return 0, err
+	}
+	m, err := matchResponse(response, borderSize, borderFailures...)
+	if err != nil {
+		return 0, err
+	}
+	return parseNumber(m[1], response)
+}
+
+// Resize to size blocks wide, moving gradually when over is non-zero.
+// changed is false if the border already had that size.
+func (w *WorldBorder) Set(ctx context.Context, size float64, over time.Duration) (changed bool, err error) {
+	return w.run(ctx, "worldborder set "+formatNumber(size)+seconds(over), borderSet)
+}
+
+// Grow by delta blocks (shrink when negative), moving gradually when over
+// is non-zero
+func (w *WorldBorder) Add(ctx context.Context, delta float64, over time.Duration) (changed bool, err error) {
+	return w.run(ctx, "worldborder add "+formatNumber(delta)+seconds(over), borderSet)
+}
+
+func (w *WorldBorder) Center(ctx context.Context, x float64, z float64) (changed bool, err error) {
+	return w.run(ctx, "worldborder center "+formatNumber(x)+" "+formatNumber(z), borderCenter)
+}
+
+// Damage per second for each block a player is beyond buffer blocks
+// outside the border
+func (w *WorldBorder) Damage(ctx context.Context, perBlock float64, buffer float64) (changed bool, err error) {
+	if perBlock < 0 || buffer < 0 {
+		return false, fmt.Errorf("%w: damage must not be negative", ErrorInvalidArgument)
+	}
+	amount, err := w.run(ctx, "worldborder damage amount "+formatNumber(perBlock), borderDamage)
+	if err != nil {
+		return amount, err
+	}
+	distance, err := w.run(ctx, "worldborder damage buffer "+formatNumber(buffer), borderDamage)
+	return amount || distance, err
+}
+
+// Warn players within distance blocks of the border, or that a moving
+// border will reach them within before
+func (w *WorldBorder) Warning(ctx context.Context, distance int, before time.Duration) (changed bool, err error) {
+	if distance < 0 || before < 0 {
+		return false, fmt.Errorf("%w: warning must not be negative", ErrorInvalidArgument)
+	}
+	d, err := w.run(ctx, "worldborder warning distance "+strconv.Itoa(distance), borderWarning)
+	if err != nil {
+		return d, err
+	}
+	t, err := w.run(ctx, "worldborder warning time "+strconv.Itoa(wholeSeconds(before)), borderWarning)
+	return d || t, err
+}
+
+func (w *WorldBorder) run(ctx context.Context, cmd string, success *regexp.Regexp) (bool, error) {
+	response, err := w.client.execute(ctx, cmd)
+	if err != nil {
+		return false, err
+	}
+	if borderUnchanged.MatchString(response) {
+		return false, nil
+	}
+	if _, err := matchResponse(response, success, borderFailures...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// " <seconds>", or nothing for zero
+func seconds(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return " " + strconv.Itoa(wholeSeconds(d))
+}
+
+func wholeSeconds(d time.Duration) int {
+	return int((d + time.Second/2) / time.Second)
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Number from a response, which may use thousands separators
+func parseNumber(s string, response string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.Replace(s, ",", "", -1), 64)
+	if err != nil {
+		return 0, unexpected(response)
+	}
+	return v, nil
+}
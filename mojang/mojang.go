@@ -0,0 +1,288 @@
+package mojang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/clock"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolves player names to UUIDs (and back) via the Mojang API.
+//
+// Lookups are cached, in memory and optionally in a JSON file, and API
+// requests are spaced at least MinInterval apart to stay under Mojang's
+// rate limit (600 requests per 10 minutes).
+
+const (
+	ProfileURL = "https://api.mojang.com/users/profiles/minecraft/"
+	SessionURL = "https://sessionserver.mojang.com/session/minecraft/profile/"
+
+	DefaultTTL         = 24 * time.Hour
+	DefaultMinInterval = 1 * time.Second
+)
+
+var (
+	ErrorNotFound    = errors.New("mojang: profile not found")
+	ErrorRateLimited = errors.New("mojang: rate limited")
+	ErrorInvalidName = errors.New("mojang: invalid player name")
+	ErrorInvalidUUID = errors.New("mojang: invalid uuid")
+)
+
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+var (
+	nameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]{1,16}$`)
+	uuidRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+type Profile struct {
+	Name string `json:"name"`
+	UUID string `json:"id"` // dashed form
+}
+
+type cacheEntry struct {
+	Profile Profile   `json:"profile"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// The zero value is ready to use, with the defaults NewClient sets
+type Client struct {
+	HTTPClient  *http.Client  // with a 10 second timeout when nil
+	CacheFile   string        // persistent cache, optional
+	TTL         time.Duration // how long cached profiles are trusted, DefaultTTL when zero
+	MinInterval time.Duration // minimum spacing between API requests, DefaultMinInterval when zero and none when negative
+	Logger      logging.Logger
+	Clock       clock.Clock // for cache expiry and request spacing
+	ProfileAPI  string      // ProfileURL when empty
+	SessionAPI  string      // SessionURL when empty
+
+	byName map[string]cacheEntry // lower case name
+	byUUID map[string]cacheEntry // undashed uuid
+	last   time.Time
+	lock   sync.Mutex // guards cache
+	wait   sync.Mutex // serialises API requests
+}
+
+func NewClient() *Client {
+	return &Client{
+		HTTPClient:  defaultHTTPClient,
+		TTL:         DefaultTTL,
+		MinInterval: DefaultMinInterval,
+		Logger:      logging.Nop,
+		Clock:       clock.Real,
+	}
+}
+
+// Resolve a player name to its profile
+func (c *Client) UUID(ctx context.Context, name string) (Profile, error) {
+	if !nameRegexp.MatchString(name) {
+		return Profile{}, ErrorInvalidName
+	}
+
+	if p, ok := c.cached(c.byName, strings.ToLower(name)); ok {
+		return p, nil
+	}
+
+	p, err := c.fetch(ctx, or(c.ProfileAPI, ProfileURL)+url.PathEscape(name))
+	if err != nil {
+		return Profile{}, err
+	}
+	c.store(p)
+	return p, nil
+}
+
+// Resolve a UUID (dashed or not) to its profile
+func (c *Client) Name(ctx context.Context, uuid string) (Profile, error) {
+	id := strings.ToLower(strings.ReplaceAll(uuid, "-", ""))
+	if !uuidRegexp.MatchString(id) {
+		return Profile{}, ErrorInvalidUUID
+	}
+
+	if p, ok := c.cached(c.byUUID, id); ok {
+		return p, nil
+	}
+
+	p, err := c.fetch(ctx, or(c.SessionAPI, SessionURL)+id)
+	if err != nil {
+		return Profile{}, err
+	}
+	c.store(p)
+	return p, nil
+}
+
+// Resolve either a name or a UUID
+func (c *Client) Lookup(ctx context.Context, nameOrUUID string) (Profile, error) {
+	if IsUUID(nameOrUUID) {
+		return c.Name(ctx, nameOrUUID)
+	}
+	return c.UUID(ctx, nameOrUUID)
+}
+
+// Read the persistent cache, a missing file is not an error
+func (c *Client) Load() error {
+	if c.CacheFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(c.CacheFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entries []cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("mojang: cache %s: %w", c.CacheFile, err)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, e := range entries {
+		c.index(e)
+	}
+	return nil
+}
+
+// Write the persistent cache
+func (c *Client) Save() error {
+	if c.CacheFile == "" {
+		return nil
+	}
+
+	c.lock.Lock()
+	entries := make([]cacheEntry, 0, len(c.byUUID))
+	for _, e := range c.byUUID {
+		entries = append(entries, e)
+	}
+	c.lock.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.CacheFile, data, 0600)
+}
+
+func (c *Client) cached(index map[string]cacheEntry, key string) (Profile, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ttl := c.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	e, ok := index[key]
+	if !ok || clock.Or(c.Clock).Since(e.Fetched) > ttl {
+		return Profile{}, false
+	}
+	return e.Profile, true
+}
+
+func (c *Client) store(p Profile) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.index(cacheEntry{Profile: p, Fetched: clock.Or(c.Clock).Now()})
+}
+
+// Add e to the cache, the lock held
+func (c *Client) index(e cacheEntry) {
+	if c.byName == nil {
+		c.byName = map[string]cacheEntry{}
+		c.byUUID = map[string]cacheEntry{}
+	}
+	c.byName[strings.ToLower(e.Profile.Name)] = e
+	c.byUUID[strings.ReplaceAll(e.Profile.UUID, "-", "")] = e
+}
+
+func (c *Client) fetch(ctx context.Context, uri string) (Profile, error) {
+	log := logging.Or(c.Logger)
+	clk := clock.Or(c.Clock)
+
+	// Rate limit
+	c.wait.Lock()
+	defer c.wait.Unlock()
+	interval := c.MinInterval
+	if interval == 0 {
+		interval = DefaultMinInterval
+	}
+	if delay := c.last.Add(interval).Sub(clk.Now()); delay > 0 {
+		log.Debug("mojang: rate limit wait", "delay", delay)
+		t := clk.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return Profile{}, ctx.Err()
+		case <-t.C():
+		}
+	}
+	defer func() { c.last = clk.Now() }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return Profile{}, err
+	}
+	log.Debug("mojang: request", "url", uri)
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		log.Warn("mojang: request failed", "url", uri, "error", err)
+		return Profile{}, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent, http.StatusNotFound:
+		return Profile{}, ErrorNotFound
+	case http.StatusTooManyRequests:
+		log.Warn("mojang: rate limited by API", "url", uri)
+		return Profile{}, ErrorRateLimited
+	default:
+		return Profile{}, fmt.Errorf("mojang: unexpected status %s", res.Status)
+	}
+
+	var p Profile
+	if err := json.NewDecoder(res.Body).Decode(&p); err != nil {
+		return Profile{}, err
+	}
+	if !uuidRegexp.MatchString(p.UUID) {
+		return Profile{}, ErrorInvalidUUID
+	}
+	p.UUID = FormatUUID(p.UUID)
+	return p, nil
+}
+
+func or(s string, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// Insert dashes into an undashed UUID
+func FormatUUID(id string) string {
+	id = strings.ReplaceAll(id, "-", "")
+	if len(id) != 32 {
+		return id
+	}
+	return id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:]
+}
+
+func IsUUID(s string) bool {
+	return uuidRegexp.MatchString(strings.ToLower(strings.ReplaceAll(s, "-", "")))
+}
@@ -0,0 +1,237 @@
+package mojang
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/StarForger/neb-mc-rcon/clock"
+)
+
+const (
+	notchUUID   = "069a79f444e94726a5befca90e38aaf5"
+	notchDashed = "069a79f4-44e9-4726-a5be-fca90e38aaf5"
+)
+
+// Fake of the profile and session APIs knowing Notch, with status
+// overriding the answer for paths it lists
+type fakeAPI struct {
+	*httptest.Server
+	status map[string]int
+
+	lock     sync.Mutex // guards requests
+	requests []string
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	t.Helper()
+	api := &fakeAPI{status: map[string]int{}}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.lock.Lock()
+		api.requests = append(api.requests, r.URL.Path)
+		api.lock.Unlock()
+
+		if status, ok := api.status[r.URL.Path]; ok {
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"id":"zzz","name":"Broken"}`)
+			return
+		}
+		switch strings.ToLower(r.URL.Path) {
+		case "/users/profiles/minecraft/notch", "/session/minecraft/profile/" + notchUUID:
+			fmt.Fprintf(w, `{"id":%q,"name":"Notch"}`, notchUUID)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func (api *fakeAPI) client(c *Client) *Client {
+	c.ProfileAPI = api.URL + "/users/profiles/minecraft/"
+	c.SessionAPI = api.URL + "/session/minecraft/profile/"
+	return c
+}
+
+func (api *fakeAPI) count() int {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+	return len(api.requests)
+}
+
+func TestLookup(t *testing.T) {
+	api := newFakeAPI(t)
+	api.status["/users/profiles/minecraft/Busy"] = http.StatusTooManyRequests
+	api.status["/users/profiles/minecraft/Gone"] = http.StatusNotFound
+	api.status["/users/profiles/minecraft/Down"] = http.StatusInternalServerError
+	api.status["/users/profiles/minecraft/Broken"] = http.StatusOK
+
+	tests := []struct {
+		name  string
+		query string
+		want  Profile
+		err   error
+	}{
+		{"by name", "Notch", Profile{"Notch", notchDashed}, nil},
+		{"by name, other case", "notch", Profile{"Notch", notchDashed}, nil},
+		{"by uuid", notchUUID, Profile{"Notch", notchDashed}, nil},
+		{"by dashed uuid", strings.ToUpper(notchDashed), Profile{"Notch", notchDashed}, nil},
+		{"no content", "Nobody", Profile{}, ErrorNotFound},
+		{"not found", "Gone", Profile{}, ErrorNotFound},
+		{"rate limited", "Busy", Profile{}, ErrorRateLimited},
+		{"bad uuid in reply", "Broken", Profile{}, ErrorInvalidUUID},
+		{"invalid name", "not a name", Profile{}, ErrorInvalidName},
+		{"too long", strings.Repeat("x", 17), Profile{}, ErrorInvalidName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := api.client(&Client{MinInterval: -1})
+			p, err := c.Lookup(context.Background(), tt.query)
+			if err != tt.err {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if p != tt.want {
+				t.Errorf("got %+v, want %+v", p, tt.want)
+			}
+		})
+	}
+
+	c := api.client(&Client{MinInterval: -1})
+	if _, err := c.Lookup(context.Background(), "Down"); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("server error: %v", err)
+	}
+	if _, err := c.Name(context.Background(), "not-a-uuid"); err != ErrorInvalidUUID {
+		t.Errorf("invalid uuid: %v", err)
+	}
+}
+
+// A zero Client works, caching by name and UUID alike
+func TestZeroClient(t *testing.T) {
+	api := newFakeAPI(t)
+	c := api.client(&Client{})
+
+	if p, err := c.UUID(context.Background(), "Notch"); err != nil || p.UUID != notchDashed {
+		t.Fatalf("got %+v, %v", p, err)
+	}
+	if p, err := c.Name(context.Background(), notchDashed); err != nil || p.Name != "Notch" {
+		t.Fatalf("got %+v, %v", p, err)
+	}
+	if n := api.count(); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	api := newFakeAPI(t)
+	mock := clock.NewMock(time.Unix(0, 0))
+	c := api.client(&Client{TTL: time.Hour, MinInterval: -1, Clock: mock})
+
+	lookup := func() {
+		t.Helper()
+		if _, err := c.UUID(context.Background(), "Notch"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lookup()
+	mock.Advance(time.Hour)
+	lookup()
+	if n := api.count(); n != 1 {
+		t.Fatalf("%d requests within the TTL, want 1", n)
+	}
+	mock.Advance(time.Second)
+	lookup()
+	if n := api.count(); n != 2 {
+		t.Fatalf("%d requests after the TTL, want 2", n)
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	api := newFakeAPI(t)
+	mock := clock.NewMock(time.Unix(0, 0))
+	c := api.client(&Client{MinInterval: time.Second, Clock: mock})
+
+	if _, err := c.UUID(context.Background(), "Notch"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Name(context.Background(), "00000000000000000000000000000000")
+		done <- err
+	}()
+
+	// The second request waits out the interval
+	deadline := time.Now().Add(5 * time.Second)
+	for mock.Pending() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("second request did not wait")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := api.count(); n != 1 {
+		t.Fatalf("%d requests before the interval passed", n)
+	}
+	mock.Advance(time.Second)
+	if err := <-done; err != ErrorNotFound {
+		t.Fatalf("got %v, want ErrorNotFound", err)
+	}
+	if n := api.count(); n != 2 {
+		t.Fatalf("%d requests, want 2", n)
+	}
+
+	// Cancelled while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.UUID(ctx, "Nobody"); err != context.Canceled {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	api := newFakeAPI(t)
+	file := filepath.Join(t.TempDir(), "cache.json")
+
+	c := api.client(&Client{CacheFile: file})
+	if err := c.Load(); err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+	if _, err := c.UUID(context.Background(), "Notch"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := api.client(&Client{CacheFile: file})
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := loaded.Name(context.Background(), notchUUID); err != nil || p.Name != "Notch" {
+		t.Fatalf("got %+v, %v", p, err)
+	}
+	if n := api.count(); n != 1 {
+		t.Errorf("%d requests, want the loaded cache used", n)
+	}
+}
+
+func TestFormatUUID(t *testing.T) {
+	if got := FormatUUID(notchUUID); got != notchDashed {
+		t.Errorf("got %s", got)
+	}
+	if got := FormatUUID(notchDashed); got != notchDashed {
+		t.Errorf("dashed: got %s", got)
+	}
+	if got := FormatUUID("short"); got != "short" {
+		t.Errorf("short: got %s", got)
+	}
+	for s, want := range map[string]bool{notchUUID: true, strings.ToUpper(notchDashed): true, "Notch": false, "": false} {
+		if IsUUID(s) != want {
+			t.Errorf("IsUUID(%q) = %v", s, !want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package packet
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// Buffers sized for the largest standard packet, shared between decoders
+// so idle ones hold none
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, SizeMax)
+		return &b
+	},
+}
+
+// Reads packets from a stream, however it was split into reads: a packet
+// arriving over several reads, several packets in one read, or a partial
+// packet left for the next call.
+type Decoder struct {
+	r      io.Reader
+	method string
+	// Largest payload accepted, larger packets are skipped
+	MaxPayload int
+	head       [8]byte
+	pooled     *[]byte // buffer of the last packet, from bufferPool
+	packet     Packet
+}
+
+// Decoder of responses, as a client reads
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, method: "response", MaxPayload: PayloadResponseMax}
+}
+
+// Decoder of requests, as a server reads
+func NewRequestDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, method: "request", MaxPayload: PayloadRequestMax}
+}
+
+// Read the next packet, which is not verified. It is reused by the next
+// Decode, and its encoded bytes are valid until then or Release.
+//
+// A packet with a payload over MaxPayload is skipped, returning
+// ErrorMaxLength with a packet holding only its length and request id so
+// the caller can fail the request. After ErrorMinLength, or a read error,
+// the stream can't be followed further.
+func (d *Decoder) Decode() (*Packet, error) {
+	d.Release()
+	d.packet = Packet{method: d.method}
+
+	if _, err := io.ReadFull(d.r, d.head[:4]); err != nil {
+		return nil, err
+	}
+	length := int(int32(binary.LittleEndian.Uint32(d.head[:4])))
+	size := 4 + length
+
+	switch {
+	case length < LengthMin:
+		return nil, ErrorMinLength
+	case length > LengthMin+d.MaxPayload:
+		// Skip the packet so the next read starts on a boundary
+		if _, err := io.ReadFull(d.r, d.head[4:]); err != nil {
+			return nil, err
+		}
+		if _, err := io.CopyN(ioutil.Discard, d.r, int64(length-4)); err != nil {
+			return nil, err
+		}
+		d.packet.decode(d.head[:])
+		return &d.packet, ErrorMaxLength
+	}
+
+	var data []byte
+	if size <= SizeMax {
+		d.pooled = bufferPool.Get().(*[]byte)
+		data = (*d.pooled)[:size]
+	} else {
+		data = make([]byte, size)
+	}
+	copy(data, d.head[:4])
+	if _, err := io.ReadFull(d.r, data[4:]); err != nil {
+		return nil, err
+	}
+	d.packet.decode(data)
+	return &d.packet, nil
+}
+
+// Give up the last packet's buffer, after which its encoded bytes are no
+// longer valid
+func (d *Decoder) Release() {
+	if d.pooled != nil {
+		bufferPool.Put(d.pooled)
+		d.pooled = nil
+	}
+}
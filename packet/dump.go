@@ -0,0 +1,81 @@
+package packet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Payload bytes shown by String
+const previewMax = 32
+
+var _ fmt.Stringer = (*Packet)(nil)
+
+// One line summary: id, type, length and the start of the payload. The
+// password of a login request is never shown.
+func (p *Packet) String() string {
+	return fmt.Sprintf("id=%d type=%s length=%d payload=%s", p.requestId, p.typeName(), p.length, p.preview())
+}
+
+// Annotated hex dump of the encoded packet, a field per line and then the
+// payload, for debugging interop problems. As String, the password of a
+// login request is left out.
+func (p *Packet) Dump() string {
+	data := p.encoded
+	if data == nil {
+		data, _ = p.MarshalBinary()
+	}
+
+	var b strings.Builder
+	field := func(name string, from, to int, note string) {
+		if from >= len(data) {
+			return
+		}
+		if to > len(data) {
+			to = len(data)
+		}
+		line := fmt.Sprintf("%-8s %-12s  %s", name, hex.EncodeToString(data[from:to]), note)
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	field("length", 0, 4, strconv.Itoa(int(p.length)))
+	field("id", 4, 8, strconv.Itoa(int(p.requestId)))
+	field("type", 8, 12, p.typeName())
+	if len(data) <= 12 {
+		return b.String()
+	}
+
+	if p.redacted() {
+		fmt.Fprintf(&b, "payload  (%d bytes redacted)\n", len(p.payload))
+		if pad := 12 + len(p.payload); pad < len(data) {
+			field("pad", pad, len(data), "")
+		}
+		return b.String()
+	}
+	fmt.Fprintf(&b, "payload  %d bytes %s\n", len(p.payload), p.preview())
+	b.WriteString(hex.Dump(data[12:]))
+	return b.String()
+}
+
+// Type name, told apart by direction when it is known
+func (p *Packet) typeName() string {
+	if name, _ := p.GetMetadata(); name != "unknown" {
+		return name + " " + p.method
+	}
+	return p.requestType.String()
+}
+
+func (p *Packet) redacted() bool {
+	return p.method == "request" && p.requestType == TypeLoginRequest
+}
+
+// Quoted start of the payload
+func (p *Packet) preview() string {
+	if p.redacted() {
+		return "(redacted)"
+	}
+	if len(p.payload) > previewMax {
+		return strconv.Quote(p.payload[:previewMax]) + "..."
+	}
+	return strconv.Quote(p.payload)
+}
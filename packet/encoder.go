@@ -0,0 +1,52 @@
+package packet
+
+import (
+	"io"
+)
+
+// Writes packets to a stream, each with a single Write from a buffer
+// reused between packets
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Write p, which is not verified
+func (e *Encoder) Encode(p *Packet) error {
+	e.buf = Append(e.buf[:0], p.requestId, p.requestType, p.payload)
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// Request to encode, unverified and without an encoded form. It needn't
+// escape to the heap, so encoding one allocates nothing.
+func NewRequest(id int32, code PacketType, body string) Packet {
+	return Packet{
+		length:      LengthMin + int32(len(body)),
+		requestId:   id,
+		requestType: code,
+		payload:     body,
+		method:      "request",
+	}
+}
+
+// Write p with a single Write, implementing io.WriterTo. Without an
+// encoded form p is encoded into a pooled buffer.
+func (p *Packet) WriteTo(w io.Writer) (int64, error) {
+	if p.encoded != nil {
+		n, err := w.Write(p.encoded)
+		return int64(n), err
+	}
+
+	pooled := bufferPool.Get().(*[]byte)
+	data := Append((*pooled)[:0], p.requestId, p.requestType, p.payload)
+	n, err := w.Write(data)
+	if cap(data) == cap(*pooled) {
+		bufferPool.Put(pooled)
+	}
+	return int64(n), err
+}
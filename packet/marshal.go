@@ -0,0 +1,33 @@
+package packet
+
+import (
+	"encoding"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Packet)(nil)
+	_ encoding.BinaryUnmarshaler = (*Packet)(nil)
+)
+
+// The packet's wire encoding, a copy
+func (p *Packet) MarshalBinary() ([]byte, error) {
+	if p.encoded != nil {
+		return append([]byte(nil), p.encoded...), nil
+	}
+	return Append(nil, p.requestId, p.requestType, p.payload), nil
+}
+
+// Decode exactly one packet from data, which is copied. The packet's
+// direction, which tells login responses from command requests, is kept.
+func (p *Packet) UnmarshalBinary(data []byte) error {
+	if len(data) < 4+LengthMin {
+		return ErrorMinLength
+	}
+	decoded := Packet{method: p.method}
+	decoded.decode(append([]byte(nil), data...))
+	if 4+int(decoded.length) != len(data) {
+		return ErrorMismatchedPayloadLength
+	}
+	*p = decoded
+	return nil
+}
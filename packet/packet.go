@@ -0,0 +1,336 @@
+// Package packet encodes and decodes RCON packets, the codec used by conn
+// and by tools built on the protocol such as proxies and test servers.
+package packet
+
+import (
+	"encoding/binary"   // translation between numbers and byte sequences
+	"errors"						// manipulate errors
+	"strings"						// trimming lenient payloads
+	"time"							// for measuring and displaying time
+	// "log"
+)
+
+// From https://wiki.vg/RCON
+// ######## PACKET ########
+//
+// Format:
+// ----	NAME								TYPE					SIZE (bytes)
+// ---- Length							int32					4			
+// ---- Request ID					int32					4
+// ----	Type								int32					4
+// ---- Payload						  []byte				>=1 (null terminated)
+// ---- Pad									byte					1
+//
+// Length is the the total size of the packet not including the length itself.
+//
+// Request ID is client generated.
+// 
+// Type:
+// ----	NAME								REQUEST				RESPONSE
+// ---- Login								3							2
+// ---- Command							2							0
+//
+// Payload:
+// ---- NAME								MAX SIZE (bytes)
+// ---- Request							1446/1024 (see note) 
+// ---- Response						4096															
+//
+// Pad is null and added during encode
+//
+// NB: Little endian integers
+// NB: Request payload max size is unreliable at 1446. Should be reliable at 1024
+// NB: Packet length (without "length" itself) minimum is 10 (4 + 4 + 1 + 1)
+// NB: Packet length maximum is the payload max plus the packet minimum (4106)
+//
+// ######## RESPONSE ########
+// 
+// Response Request ID:
+// ----	DESCRIPTION												REQUEST ID
+// ---- Authorised/Password correct				As request
+// ---- Unauthorised/Password incorrect		-1
+//
+// With fragmentation, the final packet can be determined by:
+// ---- Packet length < 4096
+// ---- Wait x seconds
+// ---- Send request with different Request ID, wait for same Request ID
+// ---- Send request with different Request ID and invalid type (x), wait for response with payload: 'Unknown request x'
+//
+
+const (	
+	// Two Int32 (requestId and type) plus two bytes (payload terminator and pad)
+	LengthMin							= 10 
+	// Payload max plus packet length minimum
+	LengthMax							= 4106 
+	// Packet length max plus "length" Int32
+	SizeMax								= 4110
+
+	IdInvalid							=	-1
+
+	PayloadRequestMax			= 1024
+	PayloadResponseMax  	= 4096	
+)
+
+type Packet struct {
+	length			int32		// size of packet (less length itself)
+	requestId		int32   // unique id
+	requestType PacketType // type named requestType
+	payload			string 	// parsed to []byte at encode
+	method			string  // for differentiating requestType codes
+	encoded			[]byte  // entire packet encoded to binary
+}
+
+var ( 
+	ErrorMaxLength 								= errors.New("packet: length too large")
+	ErrorMinLength 								= errors.New("packet: length too small")
+	ErrorMismatchType							= errors.New("packet: type mismatch")
+	ErrorInvalidId								= errors.New("packet: unauthorised/incorrect password")
+	ErrorMismatchedPayloadLength 	= errors.New("packet: payload length mismatch")
+	ErrorUnknown 									= errors.New("packet: unknown type")	
+)
+
+func CreateLoginRequest(password string) (*Packet, error) {
+	return CreateRequest(0, TypeLoginRequest, password, time.Now())
+}
+
+func CreateCommandRequest(id int32, body string) (*Packet, error) {
+	return CreateRequest(id, TypeCommandRequest, body, time.Now())
+}
+
+func CreateLoginResponse(payload []byte) (*Packet, error) {
+	return createResponse(TypeLoginResponse, payload)
+}
+
+func CreateCommandResponse(payload []byte) (*Packet, error) {
+	return createResponse(TypeCommandResponse, payload)
+}
+
+// Packet of any type, bypassing the login and command helpers, for vendor
+// extensions or the invalid type request that marks the end of a
+// fragmented response. It is encoded but not verified.
+func New(id int32, typ PacketType, payload []byte) *Packet {
+	p := &Packet{
+		length: LengthMin + int32(len(payload)),
+		requestId: id,
+		requestType: typ,
+		payload: string(payload),
+	}
+	p.encode()
+	return p
+}
+
+// Decode a response without verifying it. The encoded bytes alias data, see
+// Copy.
+func DecodeResponse(data []byte) Packet {
+	p := Packet{method: "response"}
+	p.decode(data)
+	return p
+}
+
+// Decode a request without verifying it, as DecodeResponse
+func DecodeRequest(data []byte) Packet {
+	p := Packet{method: "request"}
+	p.decode(data)
+	return p
+}
+
+// Copy of p owning its encoded bytes
+func (p *Packet) Copy() *Packet {
+	c := *p
+	c.encoded = append([]byte(nil), p.encoded...)
+	return &c
+}
+
+func (p *Packet) GetMetadata() (name string, payloadMax int32) {
+	name = "unknown"
+	payloadMax = 0
+	switch p.method {
+	case "request": 
+		if p.requestType == TypeLoginRequest {
+			name = "login"
+		}
+		if p.requestType == TypeCommandRequest {
+			name = "command"
+		}
+		payloadMax = PayloadRequestMax		
+	case "response":
+		if p.requestType == TypeLoginResponse {
+			name = "login"
+		}
+		if p.requestType == TypeCommandResponse {
+			name = "command"
+		}
+		payloadMax = PayloadResponseMax	
+	}
+	return
+}
+
+func (p *Packet) GetLength() (int32) {
+	return p.length
+}
+
+func (p *Packet) GetId() (int32) {
+	return p.requestId
+}
+
+func (p *Packet) GetType() (PacketType) {
+	return p.requestType
+}
+
+func (p *Packet) GetMethod() (string) {
+	return p.method
+}
+
+func (p *Packet) GetPayload() (string) {
+	return p.payload
+}
+
+// Payload as bytes, aliasing the encoded packet when there is one
+func (p *Packet) PayloadBytes() ([]byte) {
+	if len(p.encoded) >= 12 + len(p.payload) {
+		return p.encoded[12 : 12 + len(p.payload)]
+	}
+	return []byte(p.payload)
+}
+
+func (p *Packet) GetEncoded() ([]byte) {
+	return p.encoded
+}
+
+// Check the packet is well formed and of type code
+func (p *Packet) Verify(code PacketType) (error) {
+	_, payloadMax := p.GetMetadata()
+	return p.VerifyLimit(code, int(payloadMax))
+}
+
+// Verify with a payload limit other than the protocol's
+func (p *Packet) VerifyLimit(code PacketType, payloadMax int) (error) {
+	return p.VerifyWith(code, payloadMax, Strict)
+}
+
+// Verify in a validation mode. Lenient accepts any type and payload length
+// within the limits, and trims extra padding off the payload.
+func (p *Packet) VerifyWith(code PacketType, payloadMax int, v Validation) (error) {
+	if p.length < LengthMin {	
+		return ErrorMinLength
+	}
+	
+	if int(p.length) > payloadMax + LengthMin {
+		return ErrorMaxLength
+	}
+
+	if p.requestId == IdInvalid {
+		return ErrorInvalidId
+	}
+
+	if v == Lenient {
+		p.payload = strings.TrimRight(p.payload, "\x00")
+		return nil
+	}
+
+	if p.requestType != code {
+		return ErrorMismatchType
+	}
+	
+	if len(p.payload) != int(p.length) - LengthMin {	
+		return ErrorMismatchedPayloadLength
+	}
+
+	return nil
+}
+
+func (p *Packet) encode() (error) {
+	p.encoded = Append(make([]byte, 0, p.length + 4), p.requestId, p.requestType, p.payload)
+	return nil
+}
+
+// Append the encoded packet to dst, which callers may reuse between packets
+func Append(dst []byte, id int32, code PacketType, body string) []byte {
+	var header [12]byte
+	binary.LittleEndian.PutUint32(header[0:], uint32(LengthMin + len(body))) // packet size
+	binary.LittleEndian.PutUint32(header[4:], uint32(id))
+	binary.LittleEndian.PutUint32(header[8:], uint32(code))
+
+	dst = append(dst, header[:]...)
+	dst = append(dst, body...)
+	return append(dst, 0, 0) // null terminator and pad
+}
+
+// Decode without copying data, which encoded aliases
+func (p *Packet) decode(data []byte) (error) {
+	if len(data) >= 4 {
+		p.length = int32(binary.LittleEndian.Uint32(data[0:]))
+	}
+	if len(data) >= 8 {
+		p.requestId = int32(binary.LittleEndian.Uint32(data[4:]))
+	}
+	if len(data) >= 12 {
+		p.requestType = PacketType(binary.LittleEndian.Uint32(data[8:]))
+	}
+
+	end := 4 + int(p.length) // include length
+	complete := end <= len(data) && end >= 0
+	if !complete {
+		end = len(data)
+	}
+	p.encoded = data[:end]
+
+	// The payload is the declared length less the terminator and pad, so
+	// embedded nulls and non UTF-8 bytes survive. A truncated packet keeps
+	// what arrived, failing verification.
+	var payload []byte
+	if end > 12 {
+		payload = data[12:end]
+		if complete && len(payload) >= 2 {
+			payload = payload[:len(payload)-2]
+		}
+	}
+	p.payload = string(payload)
+
+	return nil
+}
+
+// Request following id, now seeding the request id when a new sequence is
+// started (id 0)
+func CreateRequest(id int32, code PacketType, body string, now time.Time) (*Packet, error) {
+	p := &Packet{
+		length: LengthMin + int32(len(body)),
+		requestId: createRequestId(id, now),
+		requestType: code,
+		payload: body,
+		method: "request", 
+	}
+
+	if err := p.encode(); err != nil{
+		return nil, err
+	}
+
+	if err := p.Verify(code); err != nil {
+		return nil, err
+	}	
+
+	return p, nil
+}
+
+func createResponse(code PacketType, data []byte) (*Packet, error) {		
+	p := &Packet{
+		method: "response",
+	}
+
+	if err := p.decode(data); err != nil{
+		return nil, err
+	}	
+
+	if err := p.Verify(code); err != nil {
+		return nil, err
+	} 	
+
+	return p, nil
+} 
+
+func createRequestId(id int32, now time.Time) (int32) {
+	// prevent max int overflow
+	if id <= 0 || id != id & 0x7fffffff { 
+		return int32((now.UnixNano() / 100000) % 100000)
+	}
+	return id + 1	
+}
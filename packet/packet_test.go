@@ -0,0 +1,217 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		id   int32
+		typ  PacketType
+		body string
+	}{
+		{"empty", 1, TypeCommandResponse, ""},
+		{"odd length", 2, TypeCommandResponse, "abc"},
+		{"even length", 3, TypeCommandResponse, "abcd"},
+		{"embedded null", 4, TypeCommandResponse, "a\x00b"},
+		{"not utf-8", 5, TypeCommandResponse, "\xff\xfe§"},
+		{"largest", 6, TypeCommandResponse, strings.Repeat("x", PayloadResponseMax)},
+		{"invalid id", IdInvalid, TypeLoginResponse, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := NewRequest(tt.id, tt.typ, tt.body)
+			if err := NewEncoder(&buf).Encode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if buf.Len() != 4+LengthMin+len(tt.body) {
+				t.Fatalf("encoded %d bytes", buf.Len())
+			}
+
+			got, err := NewDecoder(&buf).Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.GetId() != tt.id || got.GetType() != tt.typ || got.GetPayload() != tt.body {
+				t.Errorf("decoded %d %v %q", got.GetId(), got.GetType(), got.GetPayload())
+			}
+			if got.GetLength() != int32(LengthMin+len(tt.body)) {
+				t.Errorf("length %d", got.GetLength())
+			}
+		})
+	}
+}
+
+// However the stream is split, the decoder finds the same packets
+func TestDecoderReads(t *testing.T) {
+	var stream []byte
+	bodies := []string{"first", "", "third, of odd length", strings.Repeat("y", PayloadResponseMax)}
+	for i, body := range bodies {
+		stream = Append(stream, int32(i+1), TypeCommandResponse, body)
+	}
+
+	readers := map[string]func() io.Reader{
+		"coalesced":  func() io.Reader { return bytes.NewReader(stream) },
+		"one byte":   func() io.Reader { return iotest.OneByteReader(bytes.NewReader(stream)) },
+		"half":       func() io.Reader { return iotest.HalfReader(bytes.NewReader(stream)) },
+		"data error": func() io.Reader { return iotest.DataErrReader(bytes.NewReader(stream)) },
+	}
+	for name, reader := range readers {
+		t.Run(name, func(t *testing.T) {
+			d := NewDecoder(reader())
+			for i, body := range bodies {
+				p, err := d.Decode()
+				if err != nil {
+					t.Fatalf("packet %d: %v", i, err)
+				}
+				if p.GetId() != int32(i+1) || p.GetPayload() != body {
+					t.Fatalf("packet %d: %d %.20q", i, p.GetId(), p.GetPayload())
+				}
+			}
+			if _, err := d.Decode(); err != io.EOF {
+				t.Fatalf("after the last packet: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecoderShortRead(t *testing.T) {
+	encoded := Append(nil, 7, TypeCommandResponse, "truncated")
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"nothing", nil, io.EOF},
+		{"partial length", encoded[:2], io.ErrUnexpectedEOF},
+		{"header only", encoded[:12], io.ErrUnexpectedEOF},
+		{"missing pad", encoded[:len(encoded)-1], io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDecoder(bytes.NewReader(tt.data)).Decode()
+			if err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoderOversize(t *testing.T) {
+	var stream []byte
+	stream = Append(stream, 1, TypeCommandRequest, strings.Repeat("z", PayloadRequestMax+1))
+	stream = Append(stream, 2, TypeCommandRequest, "next")
+
+	d := NewRequestDecoder(iotest.HalfReader(bytes.NewReader(stream)))
+	p, err := d.Decode()
+	if err != ErrorMaxLength {
+		t.Fatalf("got %v, want ErrorMaxLength", err)
+	}
+	if p.GetId() != 1 {
+		t.Errorf("skipped packet's id %d", p.GetId())
+	}
+
+	// The oversize packet was skipped whole, so the next one is read
+	p, err = d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetId() != 2 || p.GetPayload() != "next" {
+		t.Errorf("decoded %d %q", p.GetId(), p.GetPayload())
+	}
+}
+
+func TestDecoderBadLength(t *testing.T) {
+	for _, length := range []int32{0, LengthMin - 1, -1} {
+		var head [4]byte
+		binary.LittleEndian.PutUint32(head[:], uint32(length))
+		_, err := NewDecoder(bytes.NewReader(head[:])).Decode()
+		if err != ErrorMinLength {
+			t.Errorf("length %d: got %v, want ErrorMinLength", length, err)
+		}
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for _, body := range []string{"", "a", "ab", "list uuids", strings.Repeat("q", PayloadRequestMax)} {
+		p, err := CreateCommandRequest(41, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, p.GetEncoded()) {
+			t.Fatalf("%q: marshalled % x, encoded % x", body, data, p.GetEncoded())
+		}
+		// A copy, not the packet's own bytes
+		data[4]++
+		if bytes.Equal(data, p.GetEncoded()) {
+			t.Fatalf("%q: marshalled bytes alias the packet", body)
+		}
+		data[4]--
+
+		got := Packet{method: "request"}
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%q: %v", body, err)
+		}
+		if got.GetId() != p.GetId() || got.GetType() != TypeCommandRequest || got.GetPayload() != body {
+			t.Errorf("%q: unmarshalled %d %v %q", body, got.GetId(), got.GetType(), got.GetPayload())
+		}
+		if err := got.Verify(TypeCommandRequest); err != nil {
+			t.Errorf("%q: %v", body, err)
+		}
+	}
+}
+
+func TestUnmarshalBinaryLength(t *testing.T) {
+	encoded := Append(nil, 3, TypeCommandResponse, "odd")
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"complete", encoded, nil},
+		{"too short", encoded[:4+LengthMin-1], ErrorMinLength},
+		{"truncated", encoded[:len(encoded)-1], ErrorMismatchedPayloadLength},
+		{"trailing byte", append(append([]byte(nil), encoded...), 0), ErrorMismatchedPayloadLength},
+		{"two packets", append(append([]byte(nil), encoded...), encoded...), ErrorMismatchedPayloadLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Packet{method: "response"}
+			if err := p.UnmarshalBinary(tt.data); err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if tt.want != nil && p.GetLength() != 0 {
+				t.Errorf("packet changed on error: %d", p.GetLength())
+			}
+		})
+	}
+}
+
+// A packet decoded from a short buffer keeps what arrived, which fails
+// verification rather than passing as a shorter payload
+func TestVerifyTruncated(t *testing.T) {
+	encoded := Append(nil, 9, TypeCommandResponse, "cut short")
+	p := DecodeResponse(encoded[:len(encoded)-3])
+	if err := p.Verify(TypeCommandResponse); err != ErrorMismatchedPayloadLength {
+		t.Fatalf("got %v, want ErrorMismatchedPayloadLength", err)
+	}
+
+	p = DecodeResponse(encoded)
+	if err := p.Verify(TypeCommandResponse); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Verify(TypeLoginResponse); err != ErrorMismatchType {
+		t.Fatalf("got %v, want ErrorMismatchType", err)
+	}
+}
@@ -0,0 +1,41 @@
+package packet
+
+import (
+	"strconv"
+)
+
+// Type field of a packet. Login responses and command requests share a
+// value, the direction tells them apart.
+type PacketType int32
+
+const (
+	TypeCommandResponse PacketType = 0
+	TypeCommandRequest  PacketType = 2
+	TypeLoginResponse   PacketType = 2
+	TypeLoginRequest    PacketType = 3
+)
+
+// Name of the type, both names for the shared value
+func (t PacketType) String() string {
+	switch t {
+	case TypeCommandResponse:
+		return "command response"
+	case TypeCommandRequest:
+		return "command request/login response"
+	case TypeLoginRequest:
+		return "login request"
+	}
+	return "unknown(" + strconv.Itoa(int(t)) + ")"
+}
+
+// How closely packets must follow the protocol
+type Validation int
+
+const (
+	// As the protocol specifies
+	Strict Validation = iota
+	// For servers sending slightly off-spec packets, older Bukkit forks or
+	// Factorio say: any type, payload lengths within the limits and extra
+	// padding
+	Lenient
+)
@@ -0,0 +1,211 @@
+// Package proxy is an RCON bastion: it accepts RCON clients logging in with
+// acl tokens instead of the real server password and forwards their
+// commands to upstream servers, within their role's commands.
+//
+// A command starting with "@name " is forwarded to the upstream called
+// name, "@survival list" say; other commands go to the default upstream.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/StarForger/neb-mc-rcon/acl"
+	"github.com/StarForger/neb-mc-rcon/audit"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"github.com/StarForger/neb-mc-rcon/ratelimit"
+	"github.com/StarForger/neb-mc-rcon/rconserver"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Marks the upstream's name at the start of a command
+const routePrefix = "@"
+
+var (
+	ErrorUnknownServer = errors.New("proxy: unknown server")
+	ErrorNoServer      = errors.New("proxy: no server given and no default")
+	ErrorNoAudit       = errors.New("proxy: no audit log, commands are refused")
+	ErrorSessionLimit  = errors.New("proxy: too many sessions on the server")
+)
+
+type Proxy struct {
+	upstreams  map[string]conn.Client
+	authorizer *acl.Authorizer
+	// Upstream of commands without a route prefix, none when empty
+	Default string
+	// Records every command, forwarded or denied. Commands are refused
+	// while it is nil.
+	Audit *audit.Logger
+	// Caps each token's concurrent sessions, nil for no cap
+	Sessions *ratelimit.Sessions
+	// Receives diagnostics, none by default
+	Logger logging.Logger
+
+	lock   sync.Mutex
+	limits map[string]*limits            // by upstream
+	used   map[uint64]map[string]*limits // upstreams each connection holds a session on
+}
+
+// Limits of clients on an upstream
+type limits struct {
+	rate     *ratelimit.Limiter
+	sessions *ratelimit.Sessions
+}
+
+// Proxy to upstreams by name, for clients with a token of authorizer
+func New(upstreams map[string]conn.Client, authorizer *acl.Authorizer) *Proxy {
+	return &Proxy{
+		upstreams:  upstreams,
+		authorizer: authorizer,
+		Logger:     logging.Nop,
+		limits:     map[string]*limits{},
+		used:       map[uint64]map[string]*limits{},
+	}
+}
+
+// Limit each token's commands and sessions on the upstream server. A
+// session counts against it from its first command to the server until it
+// disconnects.
+func (p *Proxy) SetLimits(server string, cfg ratelimit.Config) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.limits[server] = &limits{
+		rate:     ratelimit.NewLimiter(cfg.Rate, cfg.Burst),
+		sessions: ratelimit.NewSessions(cfg.MaxSessions),
+	}
+}
+
+// RCON server answering with the proxy, see rconserver.Server.Serve
+func (p *Proxy) Server() *rconserver.Server {
+	s := rconserver.NewServer("", p.handle)
+	s.Authenticate = p.authenticate
+	s.Disconnect = p.disconnect
+	s.Logger = p.Logger
+	return s
+}
+
+// A client logs in with its token, which identifies it for Authorize,
+// unless the token has as many sessions as allowed
+func (p *Proxy) authenticate(password string) (string, bool) {
+	if _, ok := p.authorizer.Role(password); !ok {
+		return password, false
+	}
+	if p.Sessions != nil && !p.Sessions.Acquire(password) {
+		logging.Or(p.Logger).Info("proxy: session limit reached")
+		return password, false
+	}
+	return password, true
+}
+
+// End the session of a client that logged in
+func (p *Proxy) disconnect(ctx context.Context) {
+	token := rconserver.Identity(ctx)
+	if p.Sessions != nil {
+		p.Sessions.Release(token)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	id := rconserver.ConnID(ctx)
+	for _, l := range p.used[id] {
+		l.sessions.Release(token)
+	}
+	delete(p.used, id)
+}
+
+// Check the upstream's limits for a command of the client on ctx
+func (p *Proxy) limit(ctx context.Context, token string, server string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	l, ok := p.limits[server]
+	if !ok {
+		return nil
+	}
+
+	id := rconserver.ConnID(ctx)
+	if _, held := p.used[id][server]; !held {
+		if !l.sessions.Acquire(token) {
+			return ErrorSessionLimit
+		}
+		if p.used[id] == nil {
+			p.used[id] = map[string]*limits{}
+		}
+		p.used[id][server] = l
+	}
+	if !l.rate.Allow(token) {
+		return acl.ErrorRateLimited
+	}
+	return nil
+}
+
+// Forward cmd for the client logged in on ctx. RCON has no way to report
+// errors, so a denied or failed command is answered with the error's text.
+func (p *Proxy) handle(ctx context.Context, cmd string) string {
+	start := time.Now()
+	token := rconserver.Identity(ctx)
+	record := audit.Record{Time: start, Command: cmd}
+	if addr := rconserver.ClientAddr(ctx); addr != nil {
+		record.Client = addr.String()
+	}
+
+	// Nothing runs that would not be recorded
+	if p.Audit == nil {
+		logging.Or(p.Logger).Error("proxy: command refused", "error", ErrorNoAudit)
+		return ErrorNoAudit.Error()
+	}
+
+	response, err := p.forward(ctx, token, cmd, &record)
+	record.Duration = time.Since(start)
+	if err != nil {
+		record.Error = err.Error()
+		response = err.Error()
+	} else {
+		record.Response = response
+	}
+	if err := p.Audit.Log(record); err != nil {
+		logging.Or(p.Logger).Error("proxy: audit failed", "error", err)
+	}
+	return response
+}
+
+func (p *Proxy) forward(ctx context.Context, token string, cmd string, record *audit.Record) (string, error) {
+	server, cmd := p.route(cmd)
+	record.Server, record.Command = server, cmd
+	if server == "" {
+		record.Denied = true
+		return "", ErrorNoServer
+	}
+
+	role, err := p.authorizer.Authorize(token, server, cmd)
+	record.Role = role
+	if err != nil {
+		record.Denied = true
+		return "", err
+	}
+
+	upstream, ok := p.upstreams[server]
+	if !ok {
+		record.Denied = true
+		return "", fmt.Errorf("%w %q", ErrorUnknownServer, server)
+	}
+	if err := p.limit(ctx, token, server); err != nil {
+		record.Denied = true
+		return "", err
+	}
+	return upstream.ExecuteContext(ctx, cmd)
+}
+
+// Upstream and command of a command line, after any route prefix
+func (p *Proxy) route(cmd string) (server string, rest string) {
+	if !strings.HasPrefix(cmd, routePrefix) {
+		return p.Default, cmd
+	}
+	server = strings.TrimPrefix(cmd, routePrefix)
+	if i := strings.IndexByte(server, ' '); i >= 0 {
+		return server[:i], strings.TrimSpace(server[i+1:])
+	}
+	return server, ""
+}
@@ -0,0 +1,310 @@
+package proxy_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StarForger/neb-mc-rcon/acl"
+	"github.com/StarForger/neb-mc-rcon/audit"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/packet"
+	"github.com/StarForger/neb-mc-rcon/proxy"
+	"github.com/StarForger/neb-mc-rcon/ratelimit"
+	"github.com/StarForger/neb-mc-rcon/rconserver"
+	"github.com/StarForger/neb-mc-rcon/rcontest"
+)
+
+// Proxy in front of "lobby" and "survival", each answering "<name> ran
+// <command>", for the tokens "admin" and "mod" whose role may run list and
+// say only on the lobby
+func newProxy(t *testing.T) (*proxy.Proxy, *bytes.Buffer) {
+	t.Helper()
+	authorizer, err := acl.NewAuthorizer(acl.Policy{
+		Roles: map[string]acl.Role{
+			"admin":     {Servers: []string{"*"}, Commands: []string{"*"}},
+			"moderator": {Servers: []string{"lobby"}, Commands: []string{"list", "say *"}},
+		},
+		Tokens: map[string]string{"admin": "admin", "mod": "moderator"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstreams := map[string]conn.Client{}
+	for _, name := range []string{"lobby", "survival"} {
+		name := name
+		upstreams[name] = rcontest.NewServer(t, rcontest.Options{
+			Handler: func(cmd string) string { return name + " ran " + cmd },
+		}).Dial()
+	}
+	p := proxy.New(upstreams, authorizer)
+	p.Default = "lobby"
+	var log bytes.Buffer
+	p.Audit = audit.NewLogger(&log)
+	return p, &log
+}
+
+// Client of the proxy over net.Pipe, speaking RCON packet by packet
+type client struct {
+	t    *testing.T
+	c    net.Conn
+	d    *packet.Decoder
+	next int32
+}
+
+func dial(t *testing.T, s *rconserver.Server) *client {
+	t.Helper()
+	c, server := net.Pipe()
+	go s.ServeConn(server)
+	t.Cleanup(func() { c.Close() })
+	return &client{t: t, c: c, d: packet.NewDecoder(bufio.NewReader(c))}
+}
+
+func (c *client) send(typ packet.PacketType, body string) *packet.Packet {
+	c.t.Helper()
+	c.next++
+	c.c.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.c.Write(packet.Append(nil, c.next, typ, body)); err != nil {
+		c.t.Fatal(err)
+	}
+	p, err := c.d.Decode()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+// Whether the token was accepted
+func (c *client) login(token string) bool {
+	c.t.Helper()
+	return c.send(packet.TypeLoginRequest, token).GetId() != packet.IdInvalid
+}
+
+func (c *client) execute(cmd string) string {
+	c.t.Helper()
+	return c.send(packet.TypeCommandRequest, cmd).GetPayload()
+}
+
+func records(t *testing.T, log *bytes.Buffer) []audit.Record {
+	t.Helper()
+	var rs []audit.Record
+	for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+		var r audit.Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// Wait for a disconnect to be handled, which follows the connection
+// closing
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	p, log := newProxy(t)
+	c := dial(t, p.Server())
+	if !c.login("admin") {
+		t.Fatal("login refused")
+	}
+
+	tests := []struct {
+		cmd      string
+		response string
+	}{
+		{"list", "lobby ran list"},
+		{"@survival list", "survival ran list"},
+		{"@survival   time set day", "survival ran time set day"},
+		{"@lobby say hi", "lobby ran say hi"},
+		{"@creative list", proxy.ErrorUnknownServer.Error() + ` "creative"`},
+	}
+	for _, tt := range tests {
+		if got := c.execute(tt.cmd); got != tt.response {
+			t.Errorf("%s: got %q, want %q", tt.cmd, got, tt.response)
+		}
+	}
+
+	rs := records(t, log)
+	if len(rs) != len(tests) {
+		t.Fatalf("%d records", len(rs))
+	}
+	if r := rs[1]; r.Server != "survival" || r.Command != "list" || r.Role != "admin" || r.Response != "survival ran list" || r.Denied {
+		t.Errorf("routed record %+v", r)
+	}
+	if r := rs[4]; r.Server != "creative" || !r.Denied || r.Error == "" {
+		t.Errorf("unknown server record %+v", r)
+	}
+}
+
+func TestNoDefault(t *testing.T) {
+	p, _ := newProxy(t)
+	p.Default = ""
+	c := dial(t, p.Server())
+	c.login("admin")
+	if got := c.execute("list"); got != proxy.ErrorNoServer.Error() {
+		t.Errorf("got %q", got)
+	}
+	if got := c.execute("@lobby list"); got != "lobby ran list" {
+		t.Errorf("routed: got %q", got)
+	}
+}
+
+func TestDenied(t *testing.T) {
+	p, log := newProxy(t)
+	c := dial(t, p.Server())
+	if c.login("nobody") {
+		t.Fatal("unknown token accepted")
+	}
+
+	c = dial(t, p.Server())
+	if !c.login("mod") {
+		t.Fatal("login refused")
+	}
+	if got := c.execute("say hi"); got != "lobby ran say hi" {
+		t.Errorf("allowed command: %q", got)
+	}
+	if got := c.execute("stop"); got != acl.ErrorCommandDenied.Error() {
+		t.Errorf("denied command: %q", got)
+	}
+	if got := c.execute("@survival list"); got != acl.ErrorServerDenied.Error() {
+		t.Errorf("denied server: %q", got)
+	}
+
+	rs := records(t, log)
+	if len(rs) != 3 || rs[0].Denied || !rs[1].Denied || !rs[2].Denied || rs[1].Role != "moderator" {
+		t.Errorf("records %+v", rs)
+	}
+}
+
+// Without an audit log nothing is forwarded
+func TestAuditRefusal(t *testing.T) {
+	p, _ := newProxy(t)
+	p.Audit = nil
+	c := dial(t, p.Server())
+	c.login("admin")
+	if got := c.execute("list"); got != proxy.ErrorNoAudit.Error() {
+		t.Errorf("got %q", got)
+	}
+}
+
+// A token logs in as many times at once as Sessions allows, the sessions
+// freed as clients disconnect
+func TestSessionLimit(t *testing.T) {
+	p, _ := newProxy(t)
+	p.Sessions = ratelimit.NewSessions(1)
+	s := p.Server()
+
+	first := dial(t, s)
+	if !first.login("mod") {
+		t.Fatal("first login refused")
+	}
+	if dial(t, s).login("mod") {
+		t.Fatal("second session accepted")
+	}
+	if !dial(t, s).login("admin") {
+		t.Fatal("other token refused")
+	}
+
+	first.c.Close()
+	eventually(t, func() bool { return p.Sessions.Active("mod") == 0 })
+	if !dial(t, s).login("mod") {
+		t.Fatal("login refused after the first session ended")
+	}
+}
+
+// Logging in again ends the session the connection had, so it isn't
+// counted twice
+func TestRelogin(t *testing.T) {
+	p, _ := newProxy(t)
+	p.Sessions = ratelimit.NewSessions(1)
+	p.SetLimits("lobby", ratelimit.Config{MaxSessions: 1})
+	s := p.Server()
+
+	c := dial(t, s)
+	if !c.login("mod") {
+		t.Fatal("login refused")
+	}
+	c.execute("list")
+	if !c.login("mod") {
+		t.Fatal("login again refused")
+	}
+	if n := p.Sessions.Active("mod"); n != 1 {
+		t.Fatalf("%d sessions after logging in again, want 1", n)
+	}
+	if got := c.execute("list"); got != "lobby ran list" {
+		t.Fatalf("after logging in again: %q", got)
+	}
+
+	// Switching tokens moves the session
+	if !c.login("admin") {
+		t.Fatal("login as admin refused")
+	}
+	if p.Sessions.Active("mod") != 0 || p.Sessions.Active("admin") != 1 {
+		t.Fatalf("sessions mod %d, admin %d", p.Sessions.Active("mod"), p.Sessions.Active("admin"))
+	}
+	if other := dial(t, s); !other.login("mod") || other.execute("list") != "lobby ran list" {
+		t.Fatal("mod's sessions still held after switching tokens")
+	}
+}
+
+// Upstream limits count a session from its first command to the server
+// until the client disconnects
+func TestUpstreamLimits(t *testing.T) {
+	p, log := newProxy(t)
+	p.SetLimits("lobby", ratelimit.Config{MaxSessions: 1})
+	p.SetLimits("survival", ratelimit.Config{Rate: 0.001, Burst: 2})
+	s := p.Server()
+
+	first, second := dial(t, s), dial(t, s)
+	first.login("admin")
+	second.login("admin")
+
+	if got := first.execute("list"); got != "lobby ran list" {
+		t.Fatalf("first: %q", got)
+	}
+	if got := second.execute("list"); got != proxy.ErrorSessionLimit.Error() {
+		t.Fatalf("second: %q", got)
+	}
+	// Other servers are counted apart
+	if got := second.execute("@survival list"); got != "survival ran list" {
+		t.Fatalf("second on survival: %q", got)
+	}
+
+	first.c.Close()
+	eventually(t, func() bool { return second.execute("list") == "lobby ran list" })
+
+	// The rate is per token, across connections
+	if got := second.execute("@survival list"); got != "survival ran list" {
+		t.Fatalf("within the burst: %q", got)
+	}
+	third := dial(t, s)
+	third.login("admin")
+	if got := third.execute("@survival list"); got != acl.ErrorRateLimited.Error() {
+		t.Fatalf("past the burst: %q", got)
+	}
+
+	var denied int
+	for _, r := range records(t, log) {
+		if r.Denied {
+			denied++
+		}
+	}
+	if denied < 2 {
+		t.Errorf("%d denied records", denied)
+	}
+}
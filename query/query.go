@@ -0,0 +1,272 @@
+// Package query is a client for Minecraft's Query protocol, GameSpy4 over
+// UDP, enabled with enable-query in server.properties. Unlike RCON it needs
+// no password, and its full stat has the plugin list and map name.
+//
+//	c, err := query.Dial("localhost:25565")
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	stat, err := c.Full(ctx)
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timeout of a query whose context has no deadline
+const DefaultTimeout = 5 * time.Second
+
+// Packet types
+const (
+	typeStat      = 0x00
+	typeHandshake = 0x09
+)
+
+var magic = []byte{0xfe, 0xfd}
+
+var ErrorMalformed = errors.New("query: malformed response")
+
+// Basic stat, what the server list shows
+type BasicStat struct {
+	MOTD       string
+	GameType   string
+	Map        string
+	Players    int
+	MaxPlayers int
+	Port       int
+	Host       string
+}
+
+// Full stat. Values has every key the server sent, the known ones are also
+// parsed into fields.
+type FullStat struct {
+	BasicStat
+	GameId      string
+	Version     string
+	Software    string // server software and version, from the plugins key
+	Plugins     []string
+	PlayerNames []string
+	Values      map[string]string
+}
+
+type Client struct {
+	conn    net.Conn
+	session int32
+}
+
+func Dial(address string) (*Client, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	// Only the low four bits of each byte are used by the server
+	session := rand.Int31() & 0x0f0f0f0f
+	return &Client{conn: conn, session: session}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Basic stat, giving up when ctx is done
+func (c *Client) Basic(ctx context.Context) (*BasicStat, error) {
+	data, err := c.stat(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bytes.SplitN(data, []byte{0}, 6)
+	if len(fields) < 6 || len(fields[5]) < 2 {
+		return nil, ErrorMalformed
+	}
+	s := &BasicStat{
+		MOTD:     string(fields[0]),
+		GameType: string(fields[1]),
+		Map:      string(fields[2]),
+		Port:     int(binary.LittleEndian.Uint16(fields[5])),
+		Host:     string(bytes.TrimRight(fields[5][2:], "\x00")),
+	}
+	s.Players, _ = strconv.Atoi(string(fields[3]))
+	s.MaxPlayers, _ = strconv.Atoi(string(fields[4]))
+	return s, nil
+}
+
+// Full stat, giving up when ctx is done
+func (c *Client) Full(ctx context.Context) (*FullStat, error) {
+	data, err := c.stat(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Padding: "splitnum\0\x80\0"
+	if len(data) < 11 {
+		return nil, ErrorMalformed
+	}
+	data = data[11:]
+
+	s := &FullStat{Values: map[string]string{}}
+	for {
+		key, rest, ok := cstring(data)
+		if !ok {
+			return nil, ErrorMalformed
+		}
+		if key == "" {
+			data = rest
+			break
+		}
+		value, rest, ok := cstring(rest)
+		if !ok {
+			return nil, ErrorMalformed
+		}
+		s.Values[key] = value
+		data = rest
+	}
+
+	// Padding: "\x01player_\0\0"
+	if len(data) < 10 {
+		return nil, ErrorMalformed
+	}
+	data = data[10:]
+	for {
+		name, rest, ok := cstring(data)
+		if !ok || name == "" {
+			break
+		}
+		s.PlayerNames = append(s.PlayerNames, name)
+		data = rest
+	}
+
+	v := s.Values
+	s.MOTD, s.GameType, s.Map = v["hostname"], v["gametype"], v["map"]
+	s.GameId, s.Version, s.Host = v["game_id"], v["version"], v["hostip"]
+	s.Players, _ = strconv.Atoi(v["numplayers"])
+	s.MaxPlayers, _ = strconv.Atoi(v["maxplayers"])
+	s.Port, _ = strconv.Atoi(v["hostport"])
+	s.Software, s.Plugins = plugins(v["plugins"])
+	return s, nil
+}
+
+// Stat response after the header, following a handshake for its
+// challenge token, which the server expires every 30 seconds
+func (c *Client) stat(ctx context.Context, full bool) ([]byte, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultTimeout)
+	}
+	c.conn.SetDeadline(deadline)
+	if ctx.Done() != nil {
+		// Waited for, so a late cancellation can't cut the next query short
+		stop, done := make(chan struct{}), make(chan struct{})
+		defer func() {
+			close(stop)
+			<-done
+		}()
+		go func() {
+			defer close(done)
+			select {
+			case <-ctx.Done():
+				c.conn.SetDeadline(time.Now())
+			case <-stop:
+			}
+		}()
+	}
+
+	data, err := c.roundTrip(typeHandshake, nil)
+	if err != nil {
+		return nil, c.err(ctx, err)
+	}
+	token, _, ok := cstring(data)
+	if !ok {
+		return nil, ErrorMalformed
+	}
+	challenge, err := strconv.ParseInt(token, 10, 32)
+	if err != nil {
+		return nil, ErrorMalformed
+	}
+
+	payload := make([]byte, 4, 8)
+	binary.BigEndian.PutUint32(payload, uint32(challenge))
+	if full {
+		payload = append(payload, 0, 0, 0, 0)
+	}
+	data, err = c.roundTrip(typeStat, payload)
+	if err != nil {
+		return nil, c.err(ctx, err)
+	}
+	return data, nil
+}
+
+// ctx's error if it ended, which is why err happened
+func (c *Client) err(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	// The socket's deadline can pass a moment before ctx notices its own
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// Send a request and return its response's payload
+func (c *Client) roundTrip(typ byte, payload []byte) ([]byte, error) {
+	req := make([]byte, 0, 7+len(payload))
+	req = append(req, magic...)
+	req = append(req, typ)
+	req = append(req, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(req[3:], uint32(c.session))
+	req = append(req, payload...)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		resp := buf[:n]
+		if len(resp) < 5 {
+			return nil, ErrorMalformed
+		}
+		// A late response to an earlier query, say
+		if resp[0] != typ || int32(binary.BigEndian.Uint32(resp[1:])) != c.session {
+			continue
+		}
+		return resp[5:], nil
+	}
+}
+
+// Null terminated string at the start of data, and what follows
+func cstring(data []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(data[:i]), data[i+1:], true
+}
+
+// Split the plugins value, "Paper on 1.20.1: WorldEdit 7.2; Essentials"
+func plugins(value string) (software string, list []string) {
+	i := strings.Index(value, ": ")
+	if i < 0 {
+		return value, nil
+	}
+	for _, p := range strings.Split(value[i+2:], "; ") {
+		if p = strings.TrimSpace(p); p != "" {
+			list = append(list, p)
+		}
+	}
+	return value[:i], list
+}
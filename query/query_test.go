@@ -0,0 +1,282 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const challenge = 9513307
+
+// Query server on a local UDP socket, serving once dialled. before, when
+// set, is called ahead of each response and can send stray packets; respond
+// builds the stat response's payload.
+type fakeServer struct {
+	conn    net.PacketConn
+	before  func(conn net.PacketConn, addr net.Addr, typ byte, session []byte)
+	respond func(full bool) []byte
+}
+
+func newFakeServer(t *testing.T, respond func(full bool) []byte) *fakeServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &fakeServer{conn: conn, respond: respond}
+}
+
+func (s *fakeServer) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req := buf[:n]
+		if n < 7 || !bytes.Equal(req[:2], magic) {
+			continue
+		}
+		typ, session := req[2], append([]byte(nil), req[3:7]...)
+		if s.before != nil {
+			s.before(s.conn, addr, typ, session)
+		}
+
+		var payload []byte
+		switch typ {
+		case typeHandshake:
+			payload = []byte("9513307\x00")
+		case typeStat:
+			if n < 11 || binary.BigEndian.Uint32(req[7:]) != challenge {
+				continue
+			}
+			payload = s.respond(n == 15)
+		}
+		s.conn.WriteTo(append(append([]byte{typ}, session...), payload...), addr)
+	}
+}
+
+func (s *fakeServer) dial(t *testing.T) *Client {
+	t.Helper()
+	go s.serve()
+	c, err := Dial(s.conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func basicResponse() []byte {
+	b := []byte("A Minecraft Server\x00SMP\x00world\x002\x0020\x00")
+	b = append(b, 0xdd, 0x63) // 25565, little endian
+	return append(b, "127.0.0.1\x00"...)
+}
+
+func fullResponse() []byte {
+	b := []byte("splitnum\x00\x80\x00")
+	for _, kv := range [][2]string{
+		{"hostname", "A Minecraft Server"},
+		{"gametype", "SMP"},
+		{"game_id", "MINECRAFT"},
+		{"version", "1.20.4"},
+		{"plugins", "Paper on 1.20.4: WorldEdit 7.2.15; EssentialsX 2.20.1"},
+		{"map", "world"},
+		{"numplayers", "2"},
+		{"maxplayers", "20"},
+		{"hostport", "25565"},
+		{"hostip", "127.0.0.1"},
+	} {
+		b = append(b, kv[0]+"\x00"+kv[1]+"\x00"...)
+	}
+	b = append(b, 0)
+	b = append(b, "\x01player_\x00\x00"...)
+	return append(b, "Steve\x00Alex\x00\x00"...)
+}
+
+func respondWith(basic []byte, full []byte) func(bool) []byte {
+	return func(isFull bool) []byte {
+		if isFull {
+			return full
+		}
+		return basic
+	}
+}
+
+func TestBasic(t *testing.T) {
+	s := newFakeServer(t, respondWith(basicResponse(), nil))
+	stat, err := s.dial(t).Basic(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &BasicStat{
+		MOTD:       "A Minecraft Server",
+		GameType:   "SMP",
+		Map:        "world",
+		Players:    2,
+		MaxPlayers: 20,
+		Port:       25565,
+		Host:       "127.0.0.1",
+	}
+	if !reflect.DeepEqual(stat, want) {
+		t.Errorf("got %+v, want %+v", stat, want)
+	}
+}
+
+func TestFull(t *testing.T) {
+	s := newFakeServer(t, respondWith(nil, fullResponse()))
+	stat, err := s.dial(t).Full(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.MOTD != "A Minecraft Server" || stat.GameType != "SMP" || stat.Map != "world" || stat.GameId != "MINECRAFT" || stat.Version != "1.20.4" {
+		t.Errorf("got %+v", stat)
+	}
+	if stat.Players != 2 || stat.MaxPlayers != 20 || stat.Port != 25565 || stat.Host != "127.0.0.1" {
+		t.Errorf("got %+v", stat.BasicStat)
+	}
+	if stat.Software != "Paper on 1.20.4" || !reflect.DeepEqual(stat.Plugins, []string{"WorldEdit 7.2.15", "EssentialsX 2.20.1"}) {
+		t.Errorf("software %q, plugins %q", stat.Software, stat.Plugins)
+	}
+	if !reflect.DeepEqual(stat.PlayerNames, []string{"Steve", "Alex"}) {
+		t.Errorf("players %q", stat.PlayerNames)
+	}
+	if len(stat.Values) != 10 {
+		t.Errorf("%d values", len(stat.Values))
+	}
+}
+
+// The padding before the values and before the players is skipped whole,
+// whatever it holds
+func TestFullPadding(t *testing.T) {
+	full := fullResponse()
+	copy(full, "SPLITNUM\x00\x7f\x01")
+	i := bytes.Index(full, []byte("\x01player_"))
+	copy(full[i:], "\x02PLAYER_\x01\x01")
+
+	s := newFakeServer(t, respondWith(nil, full))
+	stat, err := s.dial(t).Full(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.MOTD != "A Minecraft Server" || !reflect.DeepEqual(stat.PlayerNames, []string{"Steve", "Alex"}) {
+		t.Errorf("got %+v", stat)
+	}
+}
+
+func TestFullNoPlayers(t *testing.T) {
+	full := fullResponse()
+	full = append(full[:bytes.Index(full, []byte("Steve"))], 0)
+
+	s := newFakeServer(t, respondWith(nil, full))
+	stat, err := s.dial(t).Full(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stat.PlayerNames) != 0 {
+		t.Errorf("players %q", stat.PlayerNames)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	full := fullResponse()
+	values := bytes.Index(full, []byte("\x00\x01player_"))
+	tests := []struct {
+		name  string
+		basic []byte
+		full  []byte
+	}{
+		{"basic missing fields", []byte("motd\x00SMP\x00world\x00"), nil},
+		{"basic short port", []byte("motd\x00SMP\x00world\x002\x0020\x00\xdd"), nil},
+		{"full shorter than padding", nil, []byte("splitnum\x00")},
+		{"full unterminated value", nil, full[:bytes.Index(full, []byte("SMP"))+2]},
+		{"full short player padding", nil, full[:values+5]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t, respondWith(tt.basic, tt.full))
+			c := s.dial(t)
+			var err error
+			if tt.full != nil {
+				_, err = c.Full(context.Background())
+			} else {
+				_, err = c.Basic(context.Background())
+			}
+			if err != ErrorMalformed {
+				t.Fatalf("got %v, want ErrorMalformed", err)
+			}
+		})
+	}
+}
+
+// Responses for another session, or of another type, such as late answers
+// to an earlier query, are skipped
+func TestStrayPackets(t *testing.T) {
+	s := newFakeServer(t, respondWith(basicResponse(), nil))
+	s.before = func(conn net.PacketConn, addr net.Addr, typ byte, session []byte) {
+		other := []byte{session[0] ^ 0x01, session[1], session[2], session[3]}
+		conn.WriteTo(append(append([]byte{typ}, other...), "stale\x00"...), addr)
+		if typ == typeStat {
+			conn.WriteTo(append(append([]byte{typeHandshake}, session...), "1\x00"...), addr)
+		}
+	}
+
+	stat, err := s.dial(t).Basic(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.MOTD != "A Minecraft Server" || stat.Port != 25565 {
+		t.Errorf("got %+v", stat)
+	}
+}
+
+func TestContext(t *testing.T) {
+	// Never answers
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c, err := Dial(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Basic(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := c.Full(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want Canceled", err)
+	}
+}
+
+func TestPlugins(t *testing.T) {
+	tests := []struct {
+		value    string
+		software string
+		list     []string
+	}{
+		{"", "", nil},
+		{"CraftBukkit on Bukkit 1.20.4", "CraftBukkit on Bukkit 1.20.4", nil},
+		{"Paper on 1.20.4: WorldEdit 7.2; Essentials", "Paper on 1.20.4", []string{"WorldEdit 7.2", "Essentials"}},
+		{"Paper on 1.20.4: ", "Paper on 1.20.4", nil},
+	}
+	for _, tt := range tests {
+		software, list := plugins(tt.value)
+		if software != tt.software || !reflect.DeepEqual(list, tt.list) {
+			t.Errorf("%q: got %q %q", tt.value, software, list)
+		}
+	}
+}
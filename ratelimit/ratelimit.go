@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"github.com/StarForger/neb-mc-rcon/clock"
+	"sync"
+	"time"
+)
+
+// Token bucket refilled at rate tokens per second, holding at most burst
+type Bucket struct {
+	Clock clock.Clock // for refills, set before first use
+
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time // of the last Allow, zero before the first
+	lock   sync.Mutex
+}
+
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// Take a token if one is available
+func (b *Bucket) Allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := clock.Or(b.Clock).Now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+	}
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Token buckets keyed by client, created on first use
+type Limiter struct {
+	Clock clock.Clock // for the buckets' refills, set before first use
+
+	rate    float64
+	burst   int
+	buckets map[string]*Bucket
+	lock    sync.Mutex
+}
+
+// A rate of zero or less means unlimited
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: map[string]*Bucket{},
+	}
+}
+
+func (l *Limiter) Allow(key string) bool {
+	if l == nil || l.rate <= 0 {
+		return true
+	}
+
+	l.lock.Lock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = NewBucket(l.rate, l.burst)
+		b.Clock = l.Clock
+		l.buckets[key] = b
+	}
+	l.lock.Unlock()
+
+	return b.Allow()
+}
+
+// Per client limits, as configured for an upstream server
+type Config struct {
+	Rate        float64 `mapstructure:"rate" json:"rate" yaml:"rate"` // commands per second, zero is unlimited
+	Burst       int     `mapstructure:"burst" json:"burst" yaml:"burst"`
+	MaxSessions int     `mapstructure:"max-sessions" json:"max-sessions" yaml:"max-sessions"` // zero is unlimited
+}
+
+// Caps the number of concurrent sessions per client
+type Sessions struct {
+	max    int
+	active map[string]int
+	lock   sync.Mutex
+}
+
+// A max of zero or less means unlimited
+func NewSessions(max int) *Sessions {
+	return &Sessions{
+		max:    max,
+		active: map[string]int{},
+	}
+}
+
+// Start a session for key, false when the cap is reached
+func (s *Sessions) Acquire(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.max > 0 && s.active[key] >= s.max {
+		return false
+	}
+	s.active[key]++
+	return true
+}
+
+func (s *Sessions) Release(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.active[key]--
+	if s.active[key] <= 0 {
+		delete(s.active, key)
+	}
+}
+
+func (s *Sessions) Active(key string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.active[key]
+}
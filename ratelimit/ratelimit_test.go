@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/StarForger/neb-mc-rcon/clock"
+)
+
+// Number of Allow calls that succeed in a row
+func allowed(b *Bucket) int {
+	n := 0
+	for b.Allow() {
+		n++
+	}
+	return n
+}
+
+func TestBucket(t *testing.T) {
+	mock := clock.NewMock(time.Unix(0, 0))
+	b := NewBucket(2, 3)
+	b.Clock = mock
+
+	if n := allowed(b); n != 3 {
+		t.Fatalf("%d allowed from a full bucket, want the burst of 3", n)
+	}
+
+	mock.Advance(499 * time.Millisecond)
+	if b.Allow() {
+		t.Fatal("allowed before a token was refilled")
+	}
+	mock.Advance(time.Millisecond)
+	if n := allowed(b); n != 1 {
+		t.Fatalf("%d allowed after half a second at 2/s, want 1", n)
+	}
+
+	// Refills stop at the burst
+	mock.Advance(time.Hour)
+	if n := allowed(b); n != 3 {
+		t.Fatalf("%d allowed after an hour, want the burst of 3", n)
+	}
+
+	// Partial tokens add up
+	mock.Advance(300 * time.Millisecond)
+	if b.Allow() {
+		t.Fatal("allowed with 0.6 tokens")
+	}
+	mock.Advance(200 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("refused with 1 token")
+	}
+}
+
+// The first Allow finds the bucket full, however long after it was made
+func TestBucketFirstUse(t *testing.T) {
+	mock := clock.NewMock(time.Unix(1000, 0))
+	b := NewBucket(1, 0)
+	b.Clock = mock
+	if n := allowed(b); n != 1 {
+		t.Fatalf("%d allowed, want a burst of at least 1", n)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	mock := clock.NewMock(time.Unix(0, 0))
+	l := NewLimiter(1, 2)
+	l.Clock = mock
+
+	for _, key := range []string{"alice", "bob"} {
+		if !l.Allow(key) || !l.Allow(key) {
+			t.Fatalf("%s: burst refused", key)
+		}
+		if l.Allow(key) {
+			t.Fatalf("%s: allowed past the burst", key)
+		}
+	}
+	mock.Advance(time.Second)
+	if !l.Allow("alice") || l.Allow("alice") {
+		t.Fatal("one token a second")
+	}
+
+	var unlimited *Limiter
+	for i := 0; i < 100; i++ {
+		if !unlimited.Allow("x") || !NewLimiter(0, 1).Allow("x") {
+			t.Fatal("unlimited limiter refused")
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	s := NewSessions(2)
+	if !s.Acquire("alice") || !s.Acquire("alice") {
+		t.Fatal("refused under the cap")
+	}
+	if s.Acquire("alice") {
+		t.Fatal("acquired past the cap")
+	}
+	if !s.Acquire("bob") {
+		t.Fatal("cap shared between keys")
+	}
+	if s.Active("alice") != 2 || s.Active("bob") != 1 {
+		t.Fatalf("active %d, %d", s.Active("alice"), s.Active("bob"))
+	}
+
+	s.Release("alice")
+	if s.Active("alice") != 1 || !s.Acquire("alice") {
+		t.Fatal("released session not freed")
+	}
+
+	s.Release("bob")
+	if s.Active("bob") != 0 {
+		t.Fatalf("bob active %d", s.Active("bob"))
+	}
+	if len(s.active) != 1 {
+		t.Errorf("%d keys kept, want released keys dropped", len(s.active))
+	}
+}
+
+// Releasing more than was acquired doesn't leave credit for sessions
+// past the cap
+func TestSessionsReleaseUnderflow(t *testing.T) {
+	s := NewSessions(1)
+	s.Release("alice")
+	s.Release("alice")
+	if s.Active("alice") != 0 {
+		t.Fatalf("active %d after releases alone", s.Active("alice"))
+	}
+	if !s.Acquire("alice") {
+		t.Fatal("refused under the cap")
+	}
+	if s.Acquire("alice") {
+		t.Fatal("acquired past the cap after extra releases")
+	}
+
+	s.Release("alice")
+	s.Release("alice")
+	if s.Active("alice") != 0 || len(s.active) != 0 {
+		t.Fatalf("active %d, %d keys", s.Active("alice"), len(s.active))
+	}
+}
+
+func TestSessionsUnlimited(t *testing.T) {
+	s := NewSessions(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !s.Acquire("alice") {
+				t.Error("unlimited sessions refused")
+			}
+		}()
+	}
+	wg.Wait()
+	if s.Active("alice") != 50 {
+		t.Fatalf("active %d", s.Active("alice"))
+	}
+}
@@ -0,0 +1,297 @@
+// Package rconserver is the server side of RCON: it accepts connections,
+// checks the password and hands each command to a Handler, for tests
+// without a Minecraft server and for RCON front-ends to other servers.
+//
+//	s, err := rconserver.Listen("127.0.0.1:25575", "password",
+//		func(ctx context.Context, cmd string) string {
+//			return "ran " + cmd
+//		})
+//	if err != nil {
+//		return err
+//	}
+//	defer s.Close()
+package rconserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/logging"
+	"github.com/StarForger/neb-mc-rcon/packet"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Answers a command. Commands on one connection are handled one at a time
+// and answered in order, as Minecraft does; ctx ends when the connection
+// or the server closes, and carries the client's Identity, ClientAddr and
+// ConnID.
+type Handler func(ctx context.Context, cmd string) string
+
+// Context keys
+type contextKey int
+
+const (
+	identityKey contextKey = iota
+	addrKey
+	connKey
+)
+
+// Identity the client logged in as, see Server.Authenticate
+func Identity(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey).(string)
+	return identity
+}
+
+// Remote address of the client
+func ClientAddr(ctx context.Context) net.Addr {
+	addr, _ := ctx.Value(addrKey).(net.Addr)
+	return addr
+}
+
+// Number of the connection, unique to the server
+func ConnID(ctx context.Context) uint64 {
+	id, _ := ctx.Value(connKey).(uint64)
+	return id
+}
+
+var ErrorServerClosed = errors.New("rconserver: server closed")
+
+type Server struct {
+	lastConn uint64 // accessed atomically, first to be 64-bit aligned
+	password string
+	handler  Handler
+	// Receives connection diagnostics, none by default
+	Logger logging.Logger
+	// Checks the password of a login in place of the server's password,
+	// returning who logged in. Set it before serving.
+	Authenticate func(password string) (identity string, ok bool)
+	// Called when a client that logged in disconnects or logs in again,
+	// with the context its commands had. Set it before serving.
+	Disconnect func(ctx context.Context)
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	lock      sync.Mutex // guards listeners, conns and closed
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+func NewServer(password string, handler Handler) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		password:  password,
+		handler:   handler,
+		Logger:    logging.Nop,
+		ctx:       ctx,
+		cancel:    cancel,
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[net.Conn]struct{}{},
+	}
+}
+
+// Listen on the TCP address addr and serve in the background until Close
+func Listen(addr string, password string, handler Handler) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := NewServer(password, handler)
+	s.track(l)
+	go s.Serve(l)
+	return s, nil
+}
+
+// Address of the first listener, nil if there is none
+func (s *Server) Addr() net.Addr {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for l := range s.listeners {
+		return l.Addr()
+	}
+	return nil
+}
+
+// Accept connections on l until it fails or the server closes, which
+// returns ErrorServerClosed
+func (s *Server) Serve(l net.Listener) error {
+	if !s.track(l) {
+		l.Close()
+		return ErrorServerClosed
+	}
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrorServerClosed
+			}
+			return err
+		}
+		go s.ServeConn(c)
+	}
+}
+
+// Serve one connection, one end of a net.Pipe say, until it closes
+func (s *Server) ServeConn(c net.Conn) {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		c.Close()
+		return
+	}
+	s.conns[c] = struct{}{}
+	s.wg.Add(1)
+	s.lock.Unlock()
+
+	defer func() {
+		c.Close()
+		s.lock.Lock()
+		delete(s.conns, c)
+		s.lock.Unlock()
+		s.wg.Done()
+	}()
+
+	logger := logging.Or(s.Logger)
+	if err := s.serve(c); err != nil && !s.isClosed() {
+		logger.Debug("rconserver: connection closed", "address", c.RemoteAddr(), "error", err)
+	}
+}
+
+func (s *Server) serve(c net.Conn) error {
+	done := make(chan struct{})
+	defer close(done)
+	var readErr error
+	requests := make(chan *packet.Packet, 16)
+	// Reading apart from writing keeps a client sending while a response is
+	// written, which over net.Pipe would otherwise block both ends
+	go func() {
+		defer close(requests)
+		d := packet.NewRequestDecoder(c)
+		for {
+			p, err := d.Decode()
+			if err != nil {
+				readErr = err
+				return
+			}
+			select {
+			case requests <- p.Copy():
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ctx := context.WithValue(s.ctx, addrKey, c.RemoteAddr())
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, connKey, atomic.AddUint64(&s.lastConn, 1)))
+	defer cancel()
+
+	w := bufio.NewWriter(c)
+	authenticated := false
+	defer func() {
+		if authenticated && s.Disconnect != nil {
+			s.Disconnect(ctx)
+		}
+	}()
+	for p := range requests {
+		id, typ, body := p.GetId(), p.GetType(), p.GetPayload()
+
+		switch {
+		case typ == packet.TypeLoginRequest:
+			if authenticated && s.Disconnect != nil {
+				s.Disconnect(ctx)
+			}
+			var identity string
+			identity, authenticated = s.authenticate(body)
+			if !authenticated {
+				id = packet.IdInvalid
+			}
+			ctx = context.WithValue(ctx, identityKey, identity)
+			w.Write(packet.Append(nil, id, packet.TypeLoginResponse, ""))
+
+		case !authenticated:
+			return errors.New("rconserver: request before login")
+
+		case typ == packet.TypeCommandRequest:
+			response := s.handler(ctx, body)
+			writeResponse(w, id, response)
+
+		default:
+			// As Minecraft, which clients rely on to find the end of a
+			// fragmented response
+			writeResponse(w, id, "Unknown request "+strconv.FormatInt(int64(typ), 16))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return readErr
+}
+
+func (s *Server) authenticate(password string) (string, bool) {
+	if s.Authenticate != nil {
+		return s.Authenticate(password)
+	}
+	return "", subtle.ConstantTimeCompare([]byte(password), []byte(s.password)) == 1
+}
+
+// Write response in packets of at most the protocol's payload size, split
+// between runes so that each packet holds valid UTF-8
+func writeResponse(w *bufio.Writer, id int32, response string) {
+	for len(response) > packet.PayloadResponseMax {
+		n := packet.PayloadResponseMax
+		// Invalid UTF-8 has no boundary to find, a rune is at most UTFMax
+		for n > packet.PayloadResponseMax-utf8.UTFMax && !utf8.RuneStart(response[n]) {
+			n--
+		}
+		w.Write(packet.Append(nil, id, packet.TypeCommandResponse, response[:n]))
+		response = response[n:]
+	}
+	w.Write(packet.Append(nil, id, packet.TypeCommandResponse, response))
+}
+
+// Stop listening, close every connection and wait for their handlers
+func (s *Server) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.cancel()
+	var err error
+	for l := range s.listeners {
+		if e := l.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// Add l to the listeners closed by Close, false if the server is closed
+func (s *Server) track(l net.Listener) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return false
+	}
+	s.listeners[l] = struct{}{}
+	return true
+}
+
+func (s *Server) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
@@ -0,0 +1,229 @@
+package rconserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/StarForger/neb-mc-rcon/packet"
+)
+
+func TestWriteResponse(t *testing.T) {
+	max := packet.PayloadResponseMax
+	tests := []struct {
+		name     string
+		response string
+		sizes    []int
+	}{
+		{"empty", "", []int{0}},
+		{"one packet", strings.Repeat("x", max), []int{max}},
+		{"ascii", strings.Repeat("x", max+1), []int{max, 1}},
+		// 2 byte runes, the last of the first packet would straddle it
+		{"split rune", "x" + strings.Repeat("é", max/2), []int{max - 1, 2}},
+		{"runes fit", strings.Repeat("é", max/2+1), []int{max, 2}},
+		{"4 byte runes", "xy" + strings.Repeat("😀", max/4), []int{max - 2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeResponse(w, 7, tt.response)
+			w.Flush()
+
+			var sizes []int
+			var joined strings.Builder
+			d := packet.NewDecoder(&buf)
+			for {
+				p, err := d.Decode()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if p.GetId() != 7 || p.GetType() != packet.TypeCommandResponse {
+					t.Fatalf("id %d, type %d", p.GetId(), p.GetType())
+				}
+				if !utf8.ValidString(p.GetPayload()) {
+					t.Errorf("packet %d isn't valid UTF-8", len(sizes))
+				}
+				sizes = append(sizes, len(p.GetPayload()))
+				joined.WriteString(p.GetPayload())
+			}
+			if joined.String() != tt.response {
+				t.Error("packets don't add up to the response")
+			}
+			if len(sizes) != len(tt.sizes) {
+				t.Fatalf("sizes %v, want %v", sizes, tt.sizes)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.sizes[i] {
+					t.Fatalf("sizes %v, want %v", sizes, tt.sizes)
+				}
+			}
+		})
+	}
+}
+
+// Invalid UTF-8 is split at the payload size rather than searched for a
+// boundary it doesn't have
+func TestWriteResponseInvalidUTF8(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeResponse(w, 1, strings.Repeat("\x80", packet.PayloadResponseMax+1))
+	w.Flush()
+	p, err := packet.NewDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(p.GetPayload()); n < packet.PayloadResponseMax-utf8.UTFMax {
+		t.Fatalf("first packet of %d bytes", n)
+	}
+}
+
+// Client of the server over net.Pipe, speaking RCON packet by packet
+type client struct {
+	t    *testing.T
+	c    net.Conn
+	d    *packet.Decoder
+	next int32
+}
+
+func dial(t *testing.T, s *Server) *client {
+	t.Helper()
+	c, server := net.Pipe()
+	go s.ServeConn(server)
+	t.Cleanup(func() { c.Close() })
+	return &client{t: t, c: c, d: packet.NewDecoder(bufio.NewReader(c))}
+}
+
+func (c *client) write(typ packet.PacketType, body string) {
+	c.t.Helper()
+	c.next++
+	c.c.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.c.Write(packet.Append(nil, c.next, typ, body)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) send(typ packet.PacketType, body string) *packet.Packet {
+	c.t.Helper()
+	c.write(typ, body)
+	p, err := c.d.Decode()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+func (c *client) login(password string) bool {
+	c.t.Helper()
+	return c.send(packet.TypeLoginRequest, password).GetId() != packet.IdInvalid
+}
+
+func (c *client) execute(cmd string) string {
+	c.t.Helper()
+	return c.send(packet.TypeCommandRequest, cmd).GetPayload()
+}
+
+// Server logging clients in as their password, except "wrong", answering
+// with the client's identity
+func newServer(t *testing.T) (*Server, func() []string) {
+	t.Helper()
+	s := NewServer("", func(ctx context.Context, cmd string) string {
+		return Identity(ctx) + " ran " + cmd
+	})
+	s.Authenticate = func(password string) (string, bool) {
+		return password, password != "wrong"
+	}
+	var lock sync.Mutex
+	var disconnected []string
+	s.Disconnect = func(ctx context.Context) {
+		lock.Lock()
+		disconnected = append(disconnected, Identity(ctx))
+		lock.Unlock()
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), disconnected...)
+	}
+}
+
+// Logging in again ends the session the connection had
+func TestRelogin(t *testing.T) {
+	s, disconnected := newServer(t)
+	c := dial(t, s)
+	if !c.login("alice") {
+		t.Fatal("login refused")
+	}
+	if got := c.execute("list"); got != "alice ran list" {
+		t.Fatalf("got %q", got)
+	}
+	if !c.login("bob") {
+		t.Fatal("login again refused")
+	}
+	if got := disconnected(); len(got) != 1 || got[0] != "alice" {
+		t.Fatalf("disconnected %q after logging in again, want alice", got)
+	}
+	if got := c.execute("list"); got != "bob ran list" {
+		t.Fatalf("after logging in again: %q", got)
+	}
+
+	// A failed login ends the session too, and leaves none to end later
+	if c.login("wrong") {
+		t.Fatal("wrong password accepted")
+	}
+	c.c.Close()
+	s.Close()
+	if got := disconnected(); len(got) != 2 || got[1] != "bob" {
+		t.Fatalf("disconnected %q, want alice and bob", got)
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	s, disconnected := newServer(t)
+	dial(t, s).login("wrong")
+	c := dial(t, s)
+	c.login("alice")
+	c.execute("list")
+	c.c.Close()
+	s.Close()
+	if got := disconnected(); len(got) != 1 || got[0] != "alice" {
+		t.Fatalf("disconnected %q, want alice alone", got)
+	}
+}
+
+// Commands before a login, or after a failed one, close the connection
+// unanswered
+func TestRequestBeforeLogin(t *testing.T) {
+	var ran []string
+	s := NewServer("password", func(ctx context.Context, cmd string) string {
+		ran = append(ran, cmd)
+		return ""
+	})
+	defer s.Close()
+
+	for _, password := range []string{"", "wrong"} {
+		c := dial(t, s)
+		if password != "" && c.login(password) {
+			t.Fatal("wrong password accepted")
+		}
+		c.write(packet.TypeCommandRequest, "stop")
+		if p, err := c.d.Decode(); err == nil {
+			t.Fatalf("login %q: answered %q", password, p.GetPayload())
+		}
+	}
+	s.Close()
+	if len(ran) != 0 {
+		t.Fatalf("ran %q", ran)
+	}
+}
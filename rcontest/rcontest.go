@@ -0,0 +1,200 @@
+// Package rcontest provides a scripted fake RCON server for tests, reached
+// over net.Pipe so no port is opened:
+//
+//	s := rcontest.NewServer(t, rcontest.Options{
+//		Responses: map[string]string{"list": "There are 0 of a max of 20 players online: "},
+//	})
+//	c := s.Dial()
+//	response, err := c.Execute("list")
+//
+// Options script the edge cases: fragmentation, slow replies and failed
+// logins.
+package rcontest
+
+import (
+	"bufio"
+	"context"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"github.com/StarForger/neb-mc-rcon/packet"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Password accepted when Options has none
+const DefaultPassword = "password"
+
+type Options struct {
+	Password string
+	// Canned responses by command
+	Responses map[string]string
+	// Answers commands without a canned response, which otherwise get an
+	// empty one
+	Handler func(cmd string) string
+	// Payload bytes per response packet, the protocol's 4096 by default.
+	// Clients only look for more fragments after a full 4096 byte packet.
+	FragmentSize int
+	// Wait before each response, and before login responses
+	Delay time.Duration
+	// Answer logins with the invalid id, as for a wrong password
+	RejectLogin bool
+	// Send an empty command response ahead of the login response, as some
+	// servers do
+	LoginQuirk bool
+}
+
+type Server struct {
+	t    testing.TB
+	opts Options
+
+	lock     sync.Mutex // guards commands, conns and closed
+	commands []string
+	conns    []net.Conn
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// Start a server, closed when the test ends
+func NewServer(t testing.TB, opts Options) *Server {
+	t.Helper()
+	if opts.Password == "" {
+		opts.Password = DefaultPassword
+	}
+	if opts.FragmentSize <= 0 {
+		opts.FragmentSize = packet.PayloadResponseMax
+	}
+	s := &Server{t: t, opts: opts}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Dial func connecting to the server, for conn.WithDialFunc
+func (s *Server) DialFunc() conn.DialFunc {
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.closed {
+			client.Close()
+			server.Close()
+			return nil, conn.ErrorClosed
+		}
+		s.conns = append(s.conns, server)
+		s.wg.Add(1)
+		go s.serve(server)
+		return client, nil
+	}
+}
+
+// Connect and log in with the server's password, failing the test on
+// error. The connection is closed when the test ends.
+func (s *Server) Dial(opts ...conn.Option) *conn.Connection {
+	s.t.Helper()
+	c, err := conn.Dial("rcontest:25575", s.opts.Password, append([]conn.Option{conn.WithDialFunc(s.DialFunc())}, opts...)...)
+	if err != nil {
+		s.t.Fatalf("rcontest: dial: %v", err)
+	}
+	s.t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// Commands received so far, in order
+func (s *Server) Commands() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Close every connection and wait for them to finish
+func (s *Server) Close() {
+	s.lock.Lock()
+	s.closed = true
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.lock.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer s.wg.Done()
+	defer c.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	requests := readRequests(c, done)
+
+	w := bufio.NewWriter(c)
+	for r := range requests {
+		id, typ, body := r.GetId(), r.GetType(), r.GetPayload()
+		time.Sleep(s.opts.Delay)
+
+		switch typ {
+		case packet.TypeLoginRequest:
+			if s.opts.LoginQuirk {
+				w.Write(packet.Append(nil, id, packet.TypeCommandResponse, ""))
+			}
+			if s.opts.RejectLogin || body != s.opts.Password {
+				id = packet.IdInvalid
+			}
+			w.Write(packet.Append(nil, id, packet.TypeLoginResponse, ""))
+
+		case packet.TypeCommandRequest:
+			s.lock.Lock()
+			s.commands = append(s.commands, body)
+			s.lock.Unlock()
+			s.respond(w, id, s.response(body))
+
+		default:
+			s.respond(w, id, "Unknown request "+strconv.FormatInt(int64(typ), 16))
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Requests read from c until it fails or done is closed. Reading apart
+// from writing keeps a client sending while a response is written, which
+// over net.Pipe would otherwise block both ends.
+func readRequests(c net.Conn, done <-chan struct{}) <-chan *packet.Packet {
+	requests := make(chan *packet.Packet, 16)
+	go func() {
+		defer close(requests)
+		d := packet.NewRequestDecoder(c)
+		for {
+			p, err := d.Decode()
+			if err != nil {
+				return
+			}
+			select {
+			case requests <- p.Copy():
+			case <-done:
+				return
+			}
+		}
+	}()
+	return requests
+}
+
+func (s *Server) response(cmd string) string {
+	if response, ok := s.opts.Responses[cmd]; ok {
+		return response
+	}
+	if s.opts.Handler != nil {
+		return s.opts.Handler(cmd)
+	}
+	return ""
+}
+
+// Write response in packets of FragmentSize
+func (s *Server) respond(w *bufio.Writer, id int32, response string) {
+	size := s.opts.FragmentSize
+	for len(response) > size {
+		w.Write(packet.Append(nil, id, packet.TypeCommandResponse, response[:size]))
+		response = response[size:]
+	}
+	w.Write(packet.Append(nil, id, packet.TypeCommandResponse, response))
+}
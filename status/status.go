@@ -0,0 +1,192 @@
+// Package status fetches a server's status with the Server List Ping, the
+// handshake the multiplayer screen uses: MOTD, version, players and
+// favicon, without RCON credentials.
+//
+//	s, err := status.Ping(ctx, "localhost:25565")
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(s.Description.PlainText(), s.Players.Online)
+package status
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/mcapi/text"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timeout of a ping whose context has no deadline
+const DefaultTimeout = 5 * time.Second
+
+// Protocol version sent in the handshake, -1 as the version is not known
+// before asking
+const protocolUnknown = -1
+
+// Largest packet read, the status JSON with a favicon fits easily
+const packetMax = 1 << 21
+
+var (
+	ErrorMalformed = errors.New("status: malformed response")
+	ErrorTooLarge  = errors.New("status: response too large")
+)
+
+type Version struct {
+	Name     string `json:"name"`
+	Protocol int    `json:"protocol"`
+}
+
+type Player struct {
+	Name string `json:"name"`
+	Id   string `json:"id"`
+}
+
+type Players struct {
+	Max    int      `json:"max"`
+	Online int      `json:"online"`
+	Sample []Player `json:"sample"` // some of the players online, if any
+}
+
+// Server status, as the server sent it, and the round trip of a ping
+// packet
+type Status struct {
+	Version     Version        `json:"version"`
+	Players     Players        `json:"players"`
+	Description text.Component `json:"description"` // the MOTD
+	Favicon     string         `json:"favicon"`     // data:image/png;base64 URI, if any
+	Latency     time.Duration  `json:"-"`
+}
+
+// Decoded favicon PNG, nil if the server has none
+func (s *Status) FaviconPNG() ([]byte, error) {
+	const prefix = "data:image/png;base64,"
+	if s.Favicon == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s.Favicon, prefix) {
+		return nil, ErrorMalformed
+	}
+	return base64.StdEncoding.DecodeString(s.Favicon[len(prefix):])
+}
+
+// Ping the server at address, giving up when ctx is done
+func Ping(ctx context.Context, address string) (*Status, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultTimeout)
+	}
+	conn.SetDeadline(deadline)
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.SetDeadline(time.Now())
+			case <-stop:
+			}
+		}()
+	}
+
+	s, err := ping(conn, host, uint16(port))
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	// The socket's deadline can pass a moment before ctx notices its own
+	if deadline, ok := ctx.Deadline(); err != nil && ok && !time.Now().Before(deadline) {
+		return nil, context.DeadlineExceeded
+	}
+	return s, err
+}
+
+func ping(conn net.Conn, host string, port uint16) (*Status, error) {
+	// Handshake, next state status, then the status request
+	var handshake []byte
+	handshake = appendVarInt(handshake, 0x00)
+	handshake = appendVarInt(handshake, protocolUnknown)
+	handshake = appendString(handshake, host)
+	handshake = append(handshake, byte(port>>8), byte(port))
+	handshake = appendVarInt(handshake, 1)
+
+	var out []byte
+	out = appendPacket(out, handshake)
+	out = appendPacket(out, []byte{0x00})
+	if _, err := conn.Write(out); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	id, body, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	if id != 0x00 {
+		return nil, ErrorMalformed
+	}
+	data, _, err := readString(body)
+	if err != nil {
+		return nil, err
+	}
+	s := &Status{}
+	if err := json.Unmarshal([]byte(data), s); err != nil {
+		return nil, err
+	}
+
+	// Ping, echoed back by the server; some close instead, which leaves
+	// the latency unknown
+	var payload [9]byte
+	payload[0] = 0x01
+	sent := time.Now()
+	binary.BigEndian.PutUint64(payload[1:], uint64(sent.UnixNano()))
+	if _, err := conn.Write(appendPacket(nil, payload[:])); err != nil {
+		return s, nil
+	}
+	if id, body, err := readPacket(r); err == nil && id == 0x01 && len(body) == 8 {
+		s.Latency = time.Since(sent)
+	}
+	return s, nil
+}
+
+// Packet id and body
+func readPacket(r *bufio.Reader) (int32, []byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if length < 1 || length > packetMax {
+		return 0, nil, ErrorTooLarge
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	id, n, err := varInt(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, data[n:], nil
+}
@@ -0,0 +1,278 @@
+package status
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVarInt(t *testing.T) {
+	tests := []struct {
+		v       int32
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{255, []byte{0xff, 0x01}},
+		{25565, []byte{0xdd, 0xc7, 0x01}},
+		{2097151, []byte{0xff, 0xff, 0x7f}},
+		{math.MaxInt32, []byte{0xff, 0xff, 0xff, 0xff, 0x07}},
+		{-1, []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+		{math.MinInt32, []byte{0x80, 0x80, 0x80, 0x80, 0x08}},
+	}
+	for _, tt := range tests {
+		if got := appendVarInt(nil, tt.v); !bytes.Equal(got, tt.encoded) {
+			t.Errorf("append %d: % x, want % x", tt.v, got, tt.encoded)
+		}
+
+		data := append(append([]byte(nil), tt.encoded...), 0xaa)
+		v, n, err := varInt(data)
+		if err != nil || v != tt.v || n != len(tt.encoded) {
+			t.Errorf("decode % x: %d, %d bytes, %v", tt.encoded, v, n, err)
+		}
+
+		r := bufio.NewReader(bytes.NewReader(tt.encoded))
+		if v, err := readVarInt(r); err != nil || v != tt.v {
+			t.Errorf("read % x: %d, %v", tt.encoded, v, err)
+		}
+	}
+}
+
+func TestVarIntMalformed(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		{0x80},
+		{0xff, 0xff},
+		{0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, // six bytes
+	} {
+		if _, _, err := varInt(data); err != ErrorMalformed {
+			t.Errorf("decode % x: %v", data, err)
+		}
+	}
+
+	if _, err := readVarInt(bytes.NewReader([]byte{0x80, 0x80, 0x80, 0x80, 0x80})); err != ErrorMalformed {
+		t.Errorf("read six bytes: %v", err)
+	}
+	if _, err := readVarInt(bytes.NewReader([]byte{0x80})); err != io.EOF {
+		t.Errorf("read truncated: %v", err)
+	}
+}
+
+func TestString(t *testing.T) {
+	for _, s := range []string{"", "localhost", strings.Repeat("é", 100)} {
+		data := appendString(nil, s)
+		got, rest, err := readString(append(data, 'x'))
+		if err != nil || got != s || string(rest) != "x" {
+			t.Errorf("%.10q: got %.10q, rest %q, %v", s, got, rest, err)
+		}
+	}
+	for _, data := range [][]byte{
+		{0x05, 'a', 'b'},               // shorter than its length
+		{0xff, 0xff, 0xff, 0xff, 0x0f}, // negative length
+		{0x80},                         // truncated length
+	} {
+		if _, _, err := readString(data); err != ErrorMalformed {
+			t.Errorf("% x: %v", data, err)
+		}
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	var stream []byte
+	stream = appendPacket(stream, []byte{0x00, 'h', 'i'})
+	stream = appendPacket(stream, append([]byte{0x80, 0x01}, "large id"...))
+	r := bufio.NewReader(bytes.NewReader(stream))
+
+	id, body, err := readPacket(r)
+	if err != nil || id != 0 || string(body) != "hi" {
+		t.Fatalf("first: %d %q %v", id, body, err)
+	}
+	id, body, err = readPacket(r)
+	if err != nil || id != 128 || string(body) != "large id" {
+		t.Fatalf("second: %d %q %v", id, body, err)
+	}
+	if _, _, err := readPacket(r); err != io.EOF {
+		t.Fatalf("after the last packet: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty packet", []byte{0x00}, ErrorTooLarge},
+		{"too large", appendVarInt(nil, packetMax+1), ErrorTooLarge},
+		{"truncated", []byte{0x05, 0x00, 'a'}, io.ErrUnexpectedEOF},
+		{"bad id", []byte{0x01, 0x80}, ErrorMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readPacket(bufio.NewReader(bytes.NewReader(tt.data)))
+			if err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Server end of a ping over net.Pipe: it checks the handshake and status
+// request, answers with response, then echoes the ping unless closeOnPing
+func fakeServer(t *testing.T, response string, closeOnPing bool) net.Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	errs := make(chan error, 1)
+	// Cleanups run last first, so the client is closed before the wait
+	t.Cleanup(func() {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+	t.Cleanup(func() { client.Close() })
+
+	go func() {
+		defer server.Close()
+		errs <- func() error {
+			r := bufio.NewReader(server)
+			id, body, err := readPacket(r)
+			if err != nil {
+				return err
+			}
+			var handshake []byte
+			handshake = appendVarInt(handshake, protocolUnknown)
+			handshake = appendString(handshake, "mc.example.com")
+			handshake = append(handshake, 0x63, 0xdd) // 25565
+			handshake = appendVarInt(handshake, 1)
+			if id != 0x00 || !bytes.Equal(body, handshake) {
+				return errors.New("bad handshake")
+			}
+			if id, body, err := readPacket(r); err != nil || id != 0x00 || len(body) != 0 {
+				return errors.New("bad status request")
+			}
+
+			if _, err := server.Write(appendPacket(nil, appendString([]byte{0x00}, response))); err != nil {
+				return err
+			}
+
+			id, body, err = readPacket(r)
+			if err == io.EOF {
+				return nil // the client gave up on the response
+			}
+			if err != nil || id != 0x01 || len(body) != 8 {
+				return errors.New("bad ping")
+			}
+			if closeOnPing {
+				return nil
+			}
+			_, err = server.Write(appendPacket(nil, append([]byte{0x01}, body...)))
+			return err
+		}()
+	}()
+	return client
+}
+
+const vanilla = `{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":2,"sample":[{"name":"Steve","id":"8667ba71-b85a-4004-af54-457a9734eed7"}]},"description":{"text":"A ","extra":[{"text":"Minecraft","bold":true},{"text":" Server"}]},"favicon":"data:image/png;base64,iVBORw0KGgo="}`
+
+func TestPing(t *testing.T) {
+	s, err := ping(fakeServer(t, vanilla, false), "mc.example.com", 25565)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version.Name != "1.20.4" || s.Version.Protocol != 765 {
+		t.Errorf("version %+v", s.Version)
+	}
+	if s.Players.Max != 20 || s.Players.Online != 2 || len(s.Players.Sample) != 1 || s.Players.Sample[0].Name != "Steve" {
+		t.Errorf("players %+v", s.Players)
+	}
+	if got := s.Description.PlainText(); got != "A Minecraft Server" {
+		t.Errorf("description %q", got)
+	}
+	if s.Latency <= 0 {
+		t.Errorf("latency %v", s.Latency)
+	}
+	png, err := s.FaviconPNG()
+	if err != nil || !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Errorf("favicon % x, %v", png, err)
+	}
+}
+
+// Servers before 1.20.3, and many proxies, send the description as a string
+func TestPingStringDescription(t *testing.T) {
+	response := `{"version":{"name":"1.8.9","protocol":47},"players":{"max":100,"online":0},"description":"§aA legacy server"}`
+	s, err := ping(fakeServer(t, response, false), "mc.example.com", 25565)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Description.PlainText(); got != "§aA legacy server" {
+		t.Errorf("description %q", got)
+	}
+	if png, err := s.FaviconPNG(); png != nil || err != nil {
+		t.Errorf("favicon %v, %v", png, err)
+	}
+}
+
+// A server closing instead of answering the ping still has its status
+// returned, without a latency
+func TestPingClosed(t *testing.T) {
+	s, err := ping(fakeServer(t, vanilla, true), "mc.example.com", 25565)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Players.Online != 2 || s.Latency != 0 {
+		t.Errorf("players %+v, latency %v", s.Players, s.Latency)
+	}
+}
+
+func TestPingBadJSON(t *testing.T) {
+	if _, err := ping(fakeServer(t, `{"version":`, true), "mc.example.com", 25565); err == nil {
+		t.Fatal("no error")
+	}
+}
+
+func TestFaviconPNG(t *testing.T) {
+	s := &Status{Favicon: "data:image/gif;base64," + base64.StdEncoding.EncodeToString([]byte("GIF89a"))}
+	if _, err := s.FaviconPNG(); err != ErrorMalformed {
+		t.Errorf("gif: %v", err)
+	}
+	s.Favicon = "data:image/png;base64,!!!"
+	if _, err := s.FaviconPNG(); err == nil {
+		t.Error("bad base64 decoded")
+	}
+}
+
+func TestPingContext(t *testing.T) {
+	// Accepts, never answers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Ping(ctx, l.Addr().String()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+
+	if _, err := Ping(context.Background(), "localhost"); err == nil {
+		t.Fatal("address without a port")
+	}
+}
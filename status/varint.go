@@ -0,0 +1,72 @@
+package status
+
+import (
+	"io"
+)
+
+// Minecraft's VarInt: an int32, seven bits per byte from the least
+// significant, negative numbers taking all five bytes
+
+func appendVarInt(dst []byte, v int32) []byte {
+	u := uint32(v)
+	for u >= 0x80 {
+		dst = append(dst, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(dst, byte(u))
+}
+
+// VarInt at the start of data and its length in bytes
+func varInt(data []byte) (int32, int, error) {
+	var u uint32
+	for i := 0; i < 5; i++ {
+		if i >= len(data) {
+			return 0, 0, ErrorMalformed
+		}
+		u |= uint32(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return int32(u), i + 1, nil
+		}
+	}
+	return 0, 0, ErrorMalformed
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var u uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		u |= uint32(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			return int32(u), nil
+		}
+	}
+	return 0, ErrorMalformed
+}
+
+// String prefixed with its length as a VarInt
+func appendString(dst []byte, s string) []byte {
+	dst = appendVarInt(dst, int32(len(s)))
+	return append(dst, s...)
+}
+
+// String at the start of data and what follows
+func readString(data []byte) (string, []byte, error) {
+	length, n, err := varInt(data)
+	if err != nil {
+		return "", nil, err
+	}
+	data = data[n:]
+	if length < 0 || int(length) > len(data) {
+		return "", nil, ErrorMalformed
+	}
+	return string(data[:length]), data[length:], nil
+}
+
+// Packet prefixed with its length as a VarInt
+func appendPacket(dst []byte, packet []byte) []byte {
+	dst = appendVarInt(dst, int32(len(packet)))
+	return append(dst, packet...)
+}
@@ -0,0 +1,166 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLS listeners for the serve modes. Certificates, keys and the client CA
+// bundle are re-read when their files change so rotated certificates are
+// picked up without a restart.
+
+const DefaultReloadInterval = 10 * time.Second
+
+var (
+	ErrorMissingKeyPair = errors.New("tlsutil: cert and key files must both be set")
+	ErrorNoClientCA     = errors.New("tlsutil: client cert verification requires a client CA file")
+	ErrorInvalidCA      = errors.New("tlsutil: no certificates found in client CA file")
+)
+
+type Config struct {
+	CertFile string `mapstructure:"cert" json:"cert" yaml:"cert"`
+	KeyFile  string `mapstructure:"key" json:"key" yaml:"key"`
+	// CA bundle used to verify client certificates (mutual TLS)
+	ClientCAFile string `mapstructure:"client-ca" json:"client-ca" yaml:"client-ca"`
+	// Reject clients without a valid certificate, otherwise verify if given
+	RequireClientCert bool `mapstructure:"require-client-cert" json:"require-client-cert" yaml:"require-client-cert"`
+	// How often files are checked for changes, defaults to DefaultReloadInterval
+	ReloadInterval time.Duration `mapstructure:"reload-interval" json:"reload-interval" yaml:"reload-interval"`
+}
+
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// Build a server side tls.Config that reloads its files on rotation
+func NewServerConfig(cfg Config) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, ErrorMissingKeyPair
+	}
+	if cfg.RequireClientCert && cfg.ClientCAFile == "" {
+		return nil, ErrorNoClientCA
+	}
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = DefaultReloadInterval
+	}
+
+	r := &reloader{cfg: cfg}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cert, pool := r.current()
+		c := base.Clone()
+		c.GetConfigForClient = nil
+		c.Certificates = []tls.Certificate{*cert}
+		if pool != nil {
+			c.ClientCAs = pool
+			c.ClientAuth = tls.VerifyClientCertIfGiven
+			if cfg.RequireClientCert {
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+		}
+		return c, nil
+	}
+	return base, nil
+}
+
+// Listen on addr, with TLS when cfg has a certificate
+func Listen(network string, addr string, cfg Config) (net.Listener, error) {
+	if !cfg.Enabled() {
+		return net.Listen(network, addr)
+	}
+	tlsConfig, err := NewServerConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen(network, addr, tlsConfig)
+}
+
+type reloader struct {
+	cfg     Config
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time // newest of the watched files
+	checked time.Time
+	lock    sync.Mutex
+}
+
+func (r *reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if time.Since(r.checked) >= r.cfg.ReloadInterval {
+		r.checked = time.Now()
+		if mod, err := r.newestModTime(); err == nil && mod.After(r.modTime) {
+			// Keep serving the previous certificate if the new one is broken
+			// or only half written
+			r.loadLocked()
+		}
+	}
+	return r.cert, r.pool
+}
+
+func (r *reloader) load() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.checked = time.Now()
+	return r.loadLocked()
+}
+
+func (r *reloader) loadLocked() error {
+	mod, err := r.newestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tlsutil: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("tlsutil: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return ErrorInvalidCA
+		}
+	}
+
+	r.cert = &cert
+	r.pool = pool
+	r.modTime = mod
+	return nil
+}
+
+func (r *reloader) newestModTime() (time.Time, error) {
+	var newest time.Time
+	for _, path := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("tlsutil: %w", err)
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest, nil
+}
@@ -0,0 +1,49 @@
+package webrcon
+
+import (
+	"context"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"io"
+	"net"
+)
+
+// Error of one of conn's kinds, with its cause, so errors.Is matches the
+// same kinds for either protocol
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+func wrap(kind error, err error) error {
+	return &kindError{kind: kind, err: err}
+}
+
+// Wrap err with its kind when it has one, otherwise return it as is
+func classify(err error) error {
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return wrap(conn.ErrorTimeout, err)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &opErr):
+		return wrap(conn.ErrorClosed, err)
+	case errors.Is(err, ErrorMessageTooLarge):
+		return wrap(conn.ErrorPayloadTooLarge, err)
+	}
+	return err
+}
@@ -0,0 +1,275 @@
+// Package webrcon is a client for Rust's WebRCON, RCON over a WebSocket
+// with JSON messages. Connection implements conn.Conn, so code written
+// against it works with either protocol.
+//
+//	c, err := webrcon.Dial("localhost:28016", "password")
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	response, err := c.Execute("status")
+package webrcon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/StarForger/neb-mc-rcon/conn"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults, see WithConnectTimeout and WithReadTimeout
+const (
+	connTimeout = 10 * time.Second
+	readTimeout = 1 * time.Minute
+)
+
+// Name the server shows for the client's commands
+const clientName = "WebRcon"
+
+type Connection struct {
+	socket      *socket
+	nextId      int32      // last identifier handed out
+	lock        sync.Mutex // guards pending, failed and closing
+	pending     map[int]chan Message
+	pong        chan struct{}
+	failed      error         // why the reader stopped
+	done        chan struct{} // closed when the reader stops
+	closing     bool
+	readTimeout time.Duration
+}
+
+var _ conn.Conn = (*Connection)(nil)
+
+// Message from the server. Replies carry the Identifier of their command,
+// console output broadcast to every client has 0 or less.
+type Message struct {
+	Identifier int    `json:"Identifier"`
+	Message    string `json:"Message"`
+	Type       string `json:"Type"`
+	Stacktrace string `json:"Stacktrace"`
+}
+
+type request struct {
+	Identifier int    `json:"Identifier"`
+	Message    string `json:"Message"`
+	Name       string `json:"Name"`
+}
+
+// Option configures a Connection at Dial
+type Option func(*options)
+
+type options struct {
+	dial        conn.DialFunc
+	readTimeout time.Duration
+}
+
+// Time allowed to establish the TCP connection and upgrade it
+func WithConnectTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.dial = (&net.Dialer{Timeout: d}).DialContext
+	}
+}
+
+// Time allowed for each reply, one minute by default
+func WithReadTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.readTimeout = d
+	}
+}
+
+// Open connections with dial
+func WithDialFunc(dial conn.DialFunc) Option {
+	return func(o *options) {
+		o.dial = dial
+	}
+}
+
+func Dial(hostUri string, password string, opts ...Option) (*Connection, error) {
+	return DialContext(context.Background(), hostUri, password, opts...)
+}
+
+// Connect and log in, giving up when ctx is done. The password is part of
+// the WebSocket's path, a server rejecting it refuses the upgrade, which
+// fails with an error matching conn.ErrorAuthFailed.
+func DialContext(ctx context.Context, hostUri string, password string, opts ...Option) (*Connection, error) {
+	o := options{
+		dial:        (&net.Dialer{Timeout: connTimeout}).DialContext,
+		readTimeout: readTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	nc, err := o.dial(ctx, "tcp", hostUri)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	} else {
+		nc.SetDeadline(time.Now().Add(o.readTimeout))
+	}
+	s, err := handshake(nc, hostUri, "/"+url.PathEscape(password))
+	if err != nil {
+		nc.Close()
+		if errors.Is(err, ErrorHandshake) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, wrap(conn.ErrorAuthFailed, err)
+		}
+		return nil, classify(err)
+	}
+	nc.SetDeadline(time.Time{})
+
+	c := &Connection{
+		socket:      s,
+		pending:     map[int]chan Message{},
+		pong:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		readTimeout: o.readTimeout,
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *Connection) Execute(cmd string) (string, error) {
+	return c.ExecuteContext(context.Background(), cmd)
+}
+
+// Execute command, giving up on the reply when ctx is done or, unless ctx
+// has a deadline, after the read timeout
+func (c *Connection) ExecuteContext(ctx context.Context, cmd string) (string, error) {
+	id := int(atomic.AddInt32(&c.nextId, 1) & 0x7fffffff)
+	reply := make(chan Message, 1)
+
+	c.lock.Lock()
+	if c.failed != nil {
+		err := c.failed
+		c.lock.Unlock()
+		return "", err
+	}
+	c.pending[id] = reply
+	c.lock.Unlock()
+
+	data, err := json.Marshal(request{id, cmd, clientName})
+	if err == nil {
+		err = c.socket.write(opText, data)
+	}
+	if err != nil {
+		c.unregister(id)
+		return "", classify(err)
+	}
+
+	select {
+	case m := <-reply:
+		return m.Message, nil
+	case <-c.done:
+		return "", c.err()
+	case <-c.expired(ctx):
+		c.unregister(id)
+		return "", wrap(conn.ErrorTimeout, os.ErrDeadlineExceeded)
+	case <-ctx.Done():
+		c.unregister(id)
+		return "", classify(ctx.Err())
+	}
+}
+
+// Send a WebSocket ping and wait for the pong
+func (c *Connection) Ping() error {
+	select {
+	case <-c.pong:
+	default:
+	}
+	if err := c.socket.write(opPing, nil); err != nil {
+		return classify(err)
+	}
+	timer := time.NewTimer(c.readTimeout)
+	defer timer.Stop()
+	select {
+	case <-c.pong:
+		return nil
+	case <-c.done:
+		return c.err()
+	case <-timer.C:
+		return wrap(conn.ErrorTimeout, os.ErrDeadlineExceeded)
+	}
+}
+
+func (c *Connection) Close() error {
+	c.lock.Lock()
+	c.closing = true
+	c.lock.Unlock()
+	c.socket.write(opClose, []byte{0x03, 0xe8}) // normal closure
+	return c.socket.conn.Close()
+}
+
+// Channel firing after the read timeout, nil when ctx has a deadline
+func (c *Connection) expired(ctx context.Context) <-chan time.Time {
+	if _, ok := ctx.Deadline(); ok {
+		return nil
+	}
+	return time.After(c.readTimeout)
+}
+
+func (c *Connection) unregister(id int) {
+	c.lock.Lock()
+	delete(c.pending, id)
+	c.lock.Unlock()
+}
+
+// Hand each reply to the command waiting for it until the connection
+// fails. Broadcasts and late replies are dropped.
+func (c *Connection) readLoop() {
+	pong := func() {
+		select {
+		case c.pong <- struct{}{}:
+		default:
+		}
+	}
+	for {
+		data, err := c.socket.read(pong)
+		if err != nil {
+			c.stop(err)
+			return
+		}
+		var m Message
+		if json.Unmarshal(data, &m) != nil || m.Identifier <= 0 {
+			continue
+		}
+		c.lock.Lock()
+		if reply, ok := c.pending[m.Identifier]; ok {
+			delete(c.pending, m.Identifier)
+			reply <- m
+		}
+		c.lock.Unlock()
+	}
+}
+
+// Fail every command and any made later
+func (c *Connection) stop(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closing {
+		c.failed = conn.ErrorClosed
+	} else {
+		c.failed = wrap(conn.ErrorClosed, err)
+	}
+	for id := range c.pending {
+		delete(c.pending, id)
+	}
+	close(c.done)
+}
+
+// Why the reader stopped
+func (c *Connection) err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.failed
+}
@@ -0,0 +1,338 @@
+package webrcon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/StarForger/neb-mc-rcon/conn"
+)
+
+const password = "hunter 2/x"
+
+// Server end of a WebSocket
+type peer struct {
+	t *testing.T
+	s *socket
+}
+
+// WebRCON server on an httptest server, running serve on each connection
+// upgraded with the password
+func fakeServer(t *testing.T, serve func(p *peer)) string {
+	t.Helper()
+	var wg sync.WaitGroup
+	t.Cleanup(wg.Wait)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wg.Add(1)
+		defer wg.Done()
+		if r.URL.Path != "/"+password {
+			http.Error(w, "wrong password", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Sec-WebSocket-Version") != "13" || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			http.Error(w, "not a websocket", http.StatusBadRequest)
+			return
+		}
+		nc, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer nc.Close()
+
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + acceptGuid))
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			base64.StdEncoding.EncodeToString(sum[:]))
+		if err := rw.Flush(); err != nil {
+			return
+		}
+		serve(&peer{t: t, s: &socket{conn: nc, r: rw.Reader}})
+	}))
+	// Closed before waiting, so every handler still running has hijacked
+	// its connection and been counted
+	t.Cleanup(ts.Close)
+	return ts.Listener.Addr().String()
+}
+
+func dial(t *testing.T, address string) *Connection {
+	t.Helper()
+	c, err := Dial(address, password, WithReadTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// Next frame from the client, which must be masked
+func (p *peer) read() (fin bool, op byte, payload []byte, err error) {
+	head, err := p.s.r.Peek(2)
+	if err != nil {
+		return
+	}
+	if head[1]&0x80 == 0 {
+		p.t.Errorf("unmasked frame, opcode %#x", head[0]&0x0f)
+	}
+	return p.s.readFrame()
+}
+
+// Next command, answering pings until it comes. False once the client
+// closes.
+func (p *peer) command() (request, bool) {
+	for {
+		_, op, payload, err := p.read()
+		if err != nil {
+			return request{}, false
+		}
+		switch op {
+		case opText:
+			var req request
+			if err := json.Unmarshal(payload, &req); err != nil {
+				p.t.Errorf("%q: %v", payload, err)
+			}
+			return req, true
+		case opPing:
+			p.write(true, opPong, payload)
+		case opClose:
+			return request{}, false
+		}
+	}
+}
+
+// Send an unmasked frame, as servers must
+func (p *peer) write(fin bool, op byte, payload []byte) {
+	head := op
+	if fin {
+		head |= 0x80
+	}
+	frame := []byte{head}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	p.s.conn.Write(append(frame, payload...))
+}
+
+func (p *peer) send(m Message) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	p.write(true, opText, data)
+}
+
+// Replies come in three fragments with a ping between the first two, after
+// console output and a reply to a command no one waits for
+func TestExecute(t *testing.T) {
+	address := fakeServer(t, func(p *peer) {
+		for {
+			req, ok := p.command()
+			if !ok {
+				return
+			}
+			if req.Name != clientName {
+				t.Errorf("name %q", req.Name)
+			}
+			p.send(Message{Identifier: 0, Message: "console", Type: "Generic"})
+			p.send(Message{Identifier: req.Identifier + 1000, Message: "late"})
+
+			response := "ran " + req.Message
+			if req.Message == "big" {
+				response = strings.Repeat("x", 70000)
+			}
+			data, _ := json.Marshal(Message{Identifier: req.Identifier, Message: response})
+			third := len(data) / 3
+			p.write(false, opText, data[:third])
+			p.write(true, opPing, []byte("beat"))
+			if _, op, payload, err := p.read(); err != nil || op != opPong || string(payload) != "beat" {
+				t.Errorf("answer to a ping: opcode %#x, %q, %v", op, payload, err)
+			}
+			p.write(false, opContinuation, data[third:2*third])
+			p.write(true, opContinuation, data[2*third:])
+		}
+	})
+	c := dial(t, address)
+	defer c.Close()
+
+	for _, cmd := range []string{"status", strings.Repeat("say hi ", 40), "big"} {
+		want := "ran " + cmd
+		if cmd == "big" {
+			want = strings.Repeat("x", 70000)
+		}
+		if got, err := c.Execute(cmd); err != nil || got != want {
+			t.Fatalf("%.20s: got %.20q (%d bytes), %v", cmd, got, len(got), err)
+		}
+	}
+}
+
+func TestDialAuthFailed(t *testing.T) {
+	address := fakeServer(t, func(p *peer) {})
+	_, err := Dial(address, "wrong")
+	var handshakeErr *HandshakeError
+	if !errors.Is(err, conn.ErrorAuthFailed) || !errors.As(err, &handshakeErr) || handshakeErr.Status != http.StatusUnauthorized {
+		t.Fatalf("got %v", err)
+	}
+	if !errors.Is(err, ErrorHandshake) {
+		t.Errorf("%v doesn't match ErrorHandshake", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	address := fakeServer(t, func(p *peer) { p.command() })
+	c := dial(t, address)
+	defer c.Close()
+	for i := 0; i < 2; i++ {
+		if err := c.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Closing sends a normal closure before dropping the connection
+func TestClose(t *testing.T) {
+	closed := make(chan []byte, 1)
+	address := fakeServer(t, func(p *peer) {
+		for {
+			_, op, payload, err := p.read()
+			if err != nil {
+				close(closed)
+				return
+			}
+			if op == opClose {
+				closed <- payload
+				return
+			}
+		}
+	})
+	c := dial(t, address)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if payload := <-closed; !bytes.Equal(payload, []byte{0x03, 0xe8}) {
+		t.Errorf("close payload %x", payload)
+	}
+	if _, err := c.Execute("list"); !errors.Is(err, conn.ErrorClosed) {
+		t.Errorf("after closing: %v", err)
+	}
+}
+
+// A close from the server is echoed and fails the waiting command
+func TestServerClose(t *testing.T) {
+	echo := make(chan []byte, 1)
+	address := fakeServer(t, func(p *peer) {
+		p.command()
+		p.write(true, opClose, []byte{0x03, 0xe9})
+		_, op, payload, err := p.read()
+		if err != nil || op != opClose {
+			t.Errorf("opcode %#x, %v", op, err)
+		}
+		echo <- payload
+	})
+	c := dial(t, address)
+	defer c.Close()
+
+	if _, err := c.Execute("list"); !errors.Is(err, conn.ErrorClosed) {
+		t.Fatalf("got %v", err)
+	}
+	if payload := <-echo; !bytes.Equal(payload, []byte{0x03, 0xe9}) {
+		t.Errorf("echoed %x", payload)
+	}
+	if _, err := c.Execute("list"); !errors.Is(err, conn.ErrorClosed) {
+		t.Errorf("after the server closed: %v", err)
+	}
+}
+
+// Broken framing from the server ends the connection
+func TestProtocolErrors(t *testing.T) {
+	tooLarge := []byte{0x80 | opText, 127, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint64(tooLarge[2:], messageMax+1)
+
+	tests := []struct {
+		name   string
+		frames func(p *peer)
+		want   error
+	}{
+		{"continuation first", func(p *peer) {
+			p.write(true, opContinuation, []byte("{}"))
+		}, ErrorProtocol},
+		{"message inside a message", func(p *peer) {
+			p.write(false, opText, []byte("{"))
+			p.write(true, opText, []byte("}"))
+		}, ErrorProtocol},
+		{"unknown opcode", func(p *peer) {
+			p.write(true, 0x3, nil)
+		}, ErrorProtocol},
+		{"too large", func(p *peer) {
+			p.s.conn.Write(tooLarge)
+		}, ErrorMessageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := fakeServer(t, func(p *peer) {
+				p.command()
+				tt.frames(p)
+				p.command()
+			})
+			c := dial(t, address)
+			defer c.Close()
+			if _, err := c.Execute("list"); !errors.Is(err, conn.ErrorClosed) || !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	address := fakeServer(t, func(p *peer) {
+		for _, ok := p.command(); ok; _, ok = p.command() {
+		}
+	})
+	c := dial(t, address)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.ExecuteContext(ctx, "list"); !errors.Is(err, conn.ErrorTimeout) {
+		t.Fatalf("deadline: got %v", err)
+	}
+	c.readTimeout = 50 * time.Millisecond
+	if _, err := c.Execute("list"); !errors.Is(err, conn.ErrorTimeout) {
+		t.Fatalf("read timeout: got %v", err)
+	}
+}
+
+// Fragments of a message add up to the limit
+func TestReadMessageTooLarge(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	p := &peer{t: t, s: &socket{conn: server}}
+	go func() {
+		defer server.Close()
+		chunk := make([]byte, messageMax/2)
+		p.write(false, opText, chunk)
+		p.write(false, opContinuation, chunk)
+		p.write(true, opContinuation, []byte("x"))
+	}()
+
+	s := &socket{conn: client, r: bufio.NewReader(client)}
+	if _, err := s.read(func() {}); err != ErrorMessageTooLarge {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package webrcon
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The client side of RFC 6455, as much as WebRCON needs: text messages,
+// pings and closing
+
+// Opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Largest message read, fragments included
+const messageMax = 16 << 20
+
+// Appended to the handshake key, see RFC 6455 section 1.3
+const acceptGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	ErrorHandshake       = errors.New("webrcon: websocket handshake failed")
+	ErrorMessageTooLarge = errors.New("webrcon: message too large")
+	ErrorProtocol        = errors.New("webrcon: websocket protocol error")
+)
+
+type socket struct {
+	conn      net.Conn
+	r         *bufio.Reader
+	writeLock sync.Mutex
+}
+
+// Upgrade conn to a WebSocket for path. A response other than 101 Switching
+// Protocols is a *HandshakeError.
+func handshake(conn net.Conn, host string, path string) (*socket, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	req, err := http.NewRequest("GET", "http://"+host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, &HandshakeError{resp.StatusCode}
+	}
+	sum := sha1.Sum([]byte(key + acceptGuid))
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return nil, ErrorHandshake
+	}
+	return &socket{conn: conn, r: r}, nil
+}
+
+// Server refused the upgrade. Rust servers refuse a wrong password, which
+// is in the path, by closing or with an error status.
+type HandshakeError struct {
+	Status int
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("webrcon: websocket handshake failed with status %d", e.Status)
+}
+
+func (e *HandshakeError) Is(target error) bool {
+	return target == ErrorHandshake
+}
+
+// Send one masked frame, as clients must
+func (s *socket) write(op byte, payload []byte) error {
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|op) // final
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	default:
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(n))
+		frame = append(frame, 0x80|127)
+		frame = append(frame, size[:]...)
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	_, err := s.conn.Write(frame)
+	return err
+}
+
+// Read the next data message, answering pings and reporting pongs to pong.
+// A close from the server is io.EOF.
+func (s *socket) read(pong func()) ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		fin, op, payload, err := s.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := s.write(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			pong()
+			continue
+		case opClose:
+			s.write(opClose, payload)
+			return nil, io.EOF
+		case opText, opBinary:
+			if started {
+				return nil, ErrorProtocol
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, ErrorProtocol
+			}
+		default:
+			return nil, ErrorProtocol
+		}
+		if len(message)+len(payload) > messageMax {
+			return nil, ErrorMessageTooLarge
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (s *socket) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(s.r, head[:]); err != nil {
+		return
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0f
+	masked := head[1]&0x80 != 0
+
+	size := uint64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(s.r, ext[:]); err != nil {
+			return
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(s.r, ext[:]); err != nil {
+			return
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > messageMax {
+		err = ErrorMessageTooLarge
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(s.r, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, size)
+	if _, err = io.ReadFull(s.r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}